@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+)
+
+// createAPICommand creates the api command
+// Exits with code 1 when breaking changes are found, to use it in CI
+//
+// 创建 api 命令
+// 发现破坏性更改时以退出码 1 退出，便于在 CI 中使用
+func createAPICommand(projectRoot string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "api",
+		Short: "Report exported API changes of the staged Go packages",
+		Long:  "Compare the exported API of each changed package between HEAD and the staged tree with go/types, \"!!\" marks breaking changes",
+		Run: func(cmd *cobra.Command, args []string) {
+			report := rese.P1(commitmate.CheckAPIChanges(projectRoot))
+			if len(report.Changes) == 0 {
+				fmt.Println("no exported API changes")
+				return
+			}
+			fmt.Println(report.String())
+			if breaking := report.BreakingChanges(); len(breaking) > 0 {
+				fmt.Printf("%d breaking change(s), commit with \"type!: ...\" or a \"BREAKING CHANGE:\" footer\n", len(breaking))
+				os.Exit(1)
+			}
+		},
+	}
+}
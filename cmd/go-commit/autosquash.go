@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/zaplog"
+)
+
+// createAutosquashCommand creates the autosquash command
+// Uses the persistent --force flag, the same rule as amending pushed commits
+//
+// 创建 autosquash 命令
+// 使用持久的 --force 标志，与 amend 已推送提交的规则相同
+func createAutosquashCommand(projectRoot string, commitFlags *commitmate.CommitFlags) *cobra.Command {
+	options := &commitmate.AutosquashOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "autosquash",
+		Short: "Fold fixup! and squash! commits into their targets",
+		Long:  "Fold fixup! and squash! commits of the current branch into their targets without an editor, like git rebase -i --autosquash",
+		Run: func(cmd *cobra.Command, args []string) {
+			options.Force = commitFlags.IsForce
+
+			report, err := commitmate.Autosquash(projectRoot, options)
+			if report != nil {
+				for _, step := range report.Steps {
+					fmt.Println(step.String())
+				}
+				for _, step := range report.Unmatched {
+					fmt.Printf("no target: %s (targets among pushed commits need --force)\n", step.String())
+				}
+			}
+			if err != nil {
+				zaplog.SUG.Panicln(err)
+			}
+
+			switch {
+			case report.FoldCount() == 0:
+				fmt.Println("nothing to fold")
+			case options.DryRun:
+				fmt.Printf("%d commit(s) to fold, run without --dry-run to rewrite\n", report.FoldCount())
+			default:
+				fmt.Printf("%d commit(s) folded, new head %s, previous head kept in ORIG_HEAD\n", report.FoldCount(), report.NewHead.String()[:7])
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "show the rearranged steps without rewriting")
+	return cmd
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/must"
+	"github.com/yyle88/rese"
+	"github.com/yyle88/zaplog"
+)
+
+// ChangelogFlags holds the flags of the changelog command
+// ChangelogFlags 保存 changelog 命令的标志
+type ChangelogFlags struct {
+	Format   string // Output format, markdown or json // 输出格式，markdown 或 json
+	Template string // Path of a text/template file to render Markdown // 用于渲染 Markdown 的 text/template 文件路径
+	Update   bool   // Update the changelog file and stage it // 更新变更日志文件并暂存
+	File     string // Changelog file to update // 要更新的变更日志文件
+}
+
+// createChangelogCommand creates the changelog command
+// Uses the config when given to name authors by signature
+//
+// 创建 changelog 命令
+// 提供配置时按签名命名作者
+func createChangelogCommand(projectRoot string, appConfig *AppConfig) *cobra.Command {
+	flags := &ChangelogFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "changelog [from..to]",
+		Short: "Generate a changelog from conventional commits",
+		Long:  "Group conventional commits of the range (default: latest tag..HEAD) by type and scope, render Markdown or JSON, and optionally update CHANGELOG.md under \"Unreleased\"",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			options := &commitmate.ChangelogOptions{}
+			if len(args) > 0 {
+				options.Range = args[0]
+			}
+			if appConfig.ConfigPath != "" {
+				options.Config = commitmate.LoadConfig(appConfig.ConfigPath)
+			}
+			changelog := rese.P1(commitmate.BuildChangelog(projectRoot, options))
+
+			if flags.Format == "json" {
+				fmt.Println(string(rese.V1(changelog.RenderJSON())))
+				return
+			}
+			if flags.Format != "markdown" {
+				zaplog.SUG.Panicln("unknown format:", flags.Format, "use markdown or json")
+			}
+			var text string
+			if flags.Template != "" {
+				text = string(rese.V1(os.ReadFile(flags.Template)))
+			}
+			markdown := rese.V1(changelog.RenderMarkdown(text))
+			if !flags.Update {
+				fmt.Print(markdown)
+				return
+			}
+			must.Done(commitmate.UpdateChangelogFile(projectRoot, flags.File, markdown))
+			fmt.Printf("%s updated and staged, it goes into the next commit\n", flags.File)
+		},
+	}
+	cmd.Flags().StringVar(&flags.Format, "format", "markdown", "output format: markdown or json")
+	cmd.Flags().StringVar(&flags.Template, "template", "", "text/template file to render markdown")
+	cmd.Flags().BoolVar(&flags.Update, "update", false, "update the changelog file under \"Unreleased\" and stage it")
+	cmd.Flags().StringVar(&flags.File, "file", commitmate.DefaultChangelogFile, "changelog file to update")
+	return cmd
+}
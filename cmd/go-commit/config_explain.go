@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// createConfigExplainCommand creates the config explain subcommand
+// Prints each pattern with its score, the remote used, and why the winner won
+//
+// 创建 config explain 子命令
+// 输出每个模式及其分数、使用的远程以及胜出者胜出的原因
+func createConfigExplainCommand(projectRoot string, appConfig *AppConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [remote-url]",
+		Short: "Explain which signature is resolved and why",
+		Long:  "Explain signature resolution: print every pattern of every signature with its score, the remote used, and why the winner won",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if appConfig.ConfigPath == "" {
+				zaplog.SUG.Panicln("missing config path. use -c flag")
+			}
+			config := commitmate.LoadConfig(appConfig.ConfigPath)
+
+			// Use the remote URL argument when given, else resolve from the project remotes
+			// Conditions are evaluated against the project checkout in both cases
+			//
+			// 如果给定了远程 URL 参数则使用它，否则从项目远程解析
+			// 两种情况下条件都针对项目检出进行评估
+			var match *commitmate.SignatureMatch
+			if len(args) > 0 {
+				fmt.Printf("remote: %s (from argument)\n", args[0])
+				match = config.MatchSignatureDetailedInContext(args[0], commitmate.NewMatchContext(projectRoot))
+			} else {
+				resolution := config.ResolveSignatureDetailed(projectRoot)
+				printRemoteCandidates(resolution)
+				if resolution.Match == nil {
+					fmt.Printf("remote: none (%s)\n", resolution.RemoteNote)
+					return
+				}
+				if resolution.RemoteName == "" {
+					fmt.Printf("remote: none (%s)\n", resolution.RemoteNote)
+				} else {
+					fmt.Printf("remote: %s %s (%s)\n", resolution.RemoteName, resolution.Match.RemoteURL, resolution.RemoteNote)
+				}
+				for _, candidate := range resolution.Candidates {
+					if candidate.RemoteName == resolution.RemoteName && candidate.ConfiguredURL != resolution.Match.RemoteURL {
+						fmt.Printf("rewritten from: %s (insteadOf)\n", candidate.ConfiguredURL)
+					}
+				}
+				match = resolution.Match
+			}
+			printSignatureMatch(match)
+		},
+	}
+}
+
+// printSignatureMatch prints the pattern table and the verdict of the match
+// printSignatureMatch 输出模式表格以及匹配结论
+func printSignatureMatch(match *commitmate.SignatureMatch) {
+	if match.Canonical != "" {
+		fmt.Printf("normalized: %s\n", match.Canonical)
+	}
+	for _, patternMatch := range match.Patterns {
+		mark := "  "
+		if patternMatch == match.Winner {
+			mark = "=>"
+		}
+		verdict := "no match"
+		if patternMatch.Matched() {
+			verdict = fmt.Sprintf("score %d", patternMatch.Score)
+		}
+		if patternMatch.Excluded != "" {
+			verdict += " (excluded: " + patternMatch.Excluded + ")"
+		}
+		pattern := patternMatch.Pattern
+		if patternMatch.Target != nil {
+			pattern = "target " + pattern
+		}
+		fmt.Printf("%s signatures[%d] %-20s %-40s %s\n", mark, patternMatch.SignatureIdx, patternMatch.Signature.Name, pattern, verdict)
+	}
+	if match.Signature != nil {
+		fmt.Printf("signature: %s (%s <%s>)\n", match.Signature.Name, match.Signature.Username, zerotern.VV(match.Signature.Mailbox, match.Signature.Eddress))
+	} else {
+		fmt.Println("signature: none")
+	}
+	if match.Condition != "" {
+		fmt.Printf("condition: %s\n", match.Condition)
+	}
+	fmt.Printf("reason: %s\n", match.Reason)
+}
+
+// printRemoteCandidates prints the winning score of each remote when several remotes were matched
+// printRemoteCandidates 在匹配了多个远程时输出每个远程的胜出分数
+func printRemoteCandidates(resolution *commitmate.SignatureResolution) {
+	if len(resolution.Candidates) < 2 {
+		return
+	}
+	for _, candidate := range resolution.Candidates {
+		mark := "  "
+		if candidate.RemoteName == resolution.RemoteName {
+			mark = "=>"
+		}
+		verdict := "no match"
+		if candidate.Match.Winner != nil {
+			verdict = fmt.Sprintf("score %d (%s)", candidate.Match.Winner.Score, candidate.Match.Signature.Name)
+		} else if candidate.Match.Signature != nil {
+			verdict = fmt.Sprintf("by condition (%s)", candidate.Match.Signature.Name)
+		}
+		fmt.Printf("%s remote %-12s %-40s %s\n", mark, candidate.RemoteName, candidate.Match.RemoteURL, verdict)
+	}
+}
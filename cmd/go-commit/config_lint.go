@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+	"github.com/yyle88/zaplog"
+)
+
+// createConfigLintCommand creates the config lint subcommand
+// Exits with 0 when clean, 1 when just warnings exist, 2 on errors or invalid config
+//
+// 创建 config lint 子命令
+// 无问题时退出码为 0，仅有警告时为 1，存在错误或配置无效时为 2
+func createConfigLintCommand(appConfig *AppConfig) *cobra.Command {
+	var samplesPath string
+	var sampleRemotes []string
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Detect ambiguous and unreachable remote patterns",
+		Long:  "Analyze remote patterns of the go-commit configuration, report equal-score overlaps, patterns that can never win, and patterns matching none of the sample remotes",
+		Run: func(cmd *cobra.Command, args []string) {
+			if appConfig.ConfigPath == "" {
+				zaplog.SUG.Panicln("missing config path. use -c flag")
+			}
+
+			// Collect sample remotes from the file and the flags
+			// 从文件和标志收集样本远程
+			if samplesPath != "" {
+				sampleRemotes = append(sampleRemotes, readSampleRemotes(samplesPath)...)
+			}
+
+			report, err := commitmate.LintConfigFile(appConfig.ConfigPath, sampleRemotes)
+			if err != nil {
+				var configError *commitmate.ConfigError
+				if !errors.As(err, &configError) {
+					zaplog.SUG.Panicln(err)
+				}
+				for _, problem := range configError.Problems {
+					fmt.Println("error", problem.String())
+				}
+				os.Exit(2)
+			}
+
+			for _, finding := range report.Findings {
+				fmt.Println(finding.String())
+			}
+			fmt.Printf("%d finding(s), %d sample remote(s)\n", len(report.Findings), report.SampleCount)
+			os.Exit(report.ExitCode())
+		},
+	}
+	cmd.Flags().StringVar(&samplesPath, "samples", "", "file with sample remote URLs, one URL each line")
+	cmd.Flags().StringArrayVar(&sampleRemotes, "remote", nil, "sample remote URL (repeatable)")
+	return cmd
+}
+
+// readSampleRemotes reads remote URLs from the file, skipping blank lines and "#" comments
+// readSampleRemotes 从文件读取远程 URL，跳过空行和 "#" 注释
+func readSampleRemotes(path string) []string {
+	file := rese.P1(os.Open(path))
+	defer rese.F0(file.Close)
+
+	var remotes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		remotes = append(remotes, line)
+	}
+	rese.V0(scanner.Err())
+	return remotes
+}
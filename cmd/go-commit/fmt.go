@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/go-xlan/gogit"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+)
+
+// createFmtCommand creates the fmt command
+// Uses the persistent --format-scope and --jobs flags, --check exits with code 1 to use it in CI
+// Formats the changed files by default, every tracked file with --all or the branch changes with --since
+//
+// 创建 fmt 命令
+// 使用持久的 --format-scope 和 --jobs 标志，--check 以退出码 1 退出，便于在 CI 中使用
+// 默认格式化已改变的文件，--all 格式化所有已跟踪的文件，--since 格式化分支上的更改
+func createFmtCommand(projectRoot string, commitFlags *commitmate.CommitFlags) *cobra.Command {
+	var check bool
+	var all bool
+	var since string
+
+	cmd := &cobra.Command{
+		Use:   "fmt",
+		Short: "Format the changed Go files without committing, or check them with --check",
+		Long:  "Format the changed Go files with the same rules as --format-go (DefaultAllowFormat exclusions included), --all formats every tracked Go file and --since <ref> the files changed since the merge base with ref, --check prints unified diffs of unformatted files without writing or staging",
+		Run: func(cmd *cobra.Command, args []string) {
+			client := rese.P1(gogit.New(projectRoot))
+			options := commitFlags.GetFormatOptions()
+			options.All = all
+			options.Since = since
+			if !check {
+				options.Progress = func(path string, changed bool) {
+					state := "unchanged"
+					if changed {
+						state = "formatted"
+					}
+					fmt.Printf("%-9s %s\n", state, rese.V1(filepath.Rel(projectRoot, path)))
+				}
+				summary := rese.P1(commitmate.FormatGoFiles(projectRoot, client, options))
+				fmt.Println(summary.String())
+				return
+			}
+
+			checks := rese.V1(commitmate.CheckChangedGoFiles(projectRoot, client, options))
+			if len(checks) == 0 {
+				fmt.Println("all selected go files are formatted")
+				return
+			}
+			for _, check := range checks {
+				fmt.Print(check.Diff)
+			}
+			fmt.Printf("%d file(s) not formatted, run: go-commit fmt\n", len(checks))
+			os.Exit(1)
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "print diffs of unformatted changed go files and exit 1 without writing")
+	cmd.Flags().BoolVar(&all, "all", false, "select every tracked go file")
+	cmd.Flags().StringVar(&since, "since", "", "select the go files changed since the merge base with the ref, like origin/main")
+	cmd.MarkFlagsMutuallyExclusive("all", "since")
+	return cmd
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+	"github.com/yyle88/zaplog"
+)
+
+// createIdentityCommand creates the identity command and its subcommands
+// 创建 identity 命令及其子命令
+func createIdentityCommand(projectRoot string, appConfig *AppConfig) *cobra.Command {
+	identityCmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Manage the git identity stored in repo config",
+		Long:  "Manage the git identity stored in repo config, so plain git commands use the signature resolved by go-commit",
+	}
+	identityCmd.AddCommand(createIdentityApplyCommand(projectRoot, appConfig))
+	return identityCmd
+}
+
+// createIdentityApplyCommand creates the identity apply subcommand
+// Exits with 1 in check mode when some repo drifts from its resolved signature, or when some repo fails to apply
+//
+// 创建 identity apply 子命令
+// 检查模式下当有仓库偏离其解析出的签名时，或有仓库应用失败时，退出码为 1
+func createIdentityApplyCommand(projectRoot string, appConfig *AppConfig) *cobra.Command {
+	var applyAll bool
+	var checkOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Write the resolved user.name, user.email and signing key into .git/config",
+		Long:  "Write the resolved user.name, user.email and user.signingkey into the local .git/config, or report drift with --check",
+		Run: func(cmd *cobra.Command, args []string) {
+			if appConfig.ConfigPath == "" {
+				zaplog.SUG.Panicln("missing config path. use -c flag")
+			}
+			config := commitmate.LoadConfig(appConfig.ConfigPath)
+
+			// Cover each repo under the current DIR with --all, else just the current project
+			// 使用 --all 时覆盖当前 DIR 下的每个仓库，否则仅处理当前项目
+			projectRoots := []string{projectRoot}
+			if applyAll {
+				projectRoots = rese.V1(commitmate.FindProjectRoots(projectRoot))
+			}
+
+			var driftCount, appliedCount, failedCount int
+			for _, root := range projectRoots {
+				var report *commitmate.IdentityReport
+				if checkOnly {
+					report = config.CheckIdentity(root)
+				} else {
+					// Keep going with the other repos when one fails, since earlier ones are written already
+					// 某个仓库失败时继续处理其它仓库，因为之前的仓库已经写入
+					var err error
+					if report, err = config.ApplyIdentity(root); err != nil {
+						zaplog.SUG.Errorln("cannot apply identity to", root, ":", err)
+						failedCount++
+						continue
+					}
+				}
+				if report.Drifted() {
+					driftCount++
+				}
+				if report.Applied {
+					appliedCount++
+				}
+				fmt.Println(report.String())
+			}
+
+			if checkOnly {
+				fmt.Printf("%d repo(s), %d drifted\n", len(projectRoots), driftCount)
+				if driftCount > 0 {
+					os.Exit(1)
+				}
+			} else {
+				fmt.Printf("%d repo(s), %d applied, %d failed\n", len(projectRoots), appliedCount, failedCount)
+				if failedCount > 0 {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&applyAll, "all", false, "apply to each repo under the current DIR")
+	cmd.Flags().BoolVar(&checkOnly, "check", false, "report drift without writing, exit 1 on drift")
+	return cmd
+}
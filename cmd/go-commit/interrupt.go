@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yyle88/zaplog"
+)
+
+// watchInterrupt returns a context canceled by the first interrupt, so the commit stops at the next step
+// A second interrupt exits at once without rollback, the returned function stops watching
+//
+// watchInterrupt 返回一个在第一次中断时取消的上下文，使提交在下一个步骤停止
+// 第二次中断立即退出且不回滚，返回的函数停止监听
+func watchInterrupt() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if ctx.Err() != nil {
+					zaplog.SUG.Warnln("interrupted again, exit without rollback")
+					os.Exit(130)
+				}
+				zaplog.SUG.Warnln("interrupted, rolling back at the next step, interrupt again to exit at once")
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+)
+
+// createLogCommand creates the log command
+// Lists the journaled operations newest first, --limit caps the count
+//
+// 创建 log 命令
+// 按从新到旧列出日志中的操作，--limit 限制数量
+func createLogCommand(projectRoot string) *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "List past go-commit operations",
+		Long:  "List the operations journaled under .git/go-commit/ newest first, with the previous and new HEAD and the count of files rewritten, undone operations are marked",
+		Run: func(cmd *cobra.Command, args []string) {
+			entries := rese.V1(commitmate.ListJournal(projectRoot))
+			if len(entries) == 0 {
+				fmt.Println("no operations journaled")
+				return
+			}
+			for idx := len(entries) - 1; idx >= 0; idx-- {
+				if limit > 0 && len(entries)-idx > limit {
+					break
+				}
+				fmt.Println(entries[idx].String())
+			}
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "show at most n operations (default: all)")
+	return cmd
+}
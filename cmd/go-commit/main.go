@@ -8,6 +8,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/go-mate/go-commit/commitmate"
@@ -47,9 +48,52 @@ func main() {
 	// 添加配置命令及其子命令
 	configCmd := createConfigCommand(projectRoot, commitFlags, appConfig)
 	configCmd.AddCommand(createConfigExampleCommand(projectRoot))
+	configCmd.AddCommand(createConfigSchemaCommand())
+	configCmd.AddCommand(createConfigLintCommand(appConfig))
+	configCmd.AddCommand(createConfigExplainCommand(projectRoot, appConfig))
 
 	rootCmd.AddCommand(configCmd)
 
+	// Add identity command to write the resolved identity into repo config
+	// 添加 identity 命令，将解析出的身份写入仓库配置
+	rootCmd.AddCommand(createIdentityCommand(projectRoot, appConfig))
+
+	// Add reauthor command to rewrite commits made with the wrong identity
+	// 添加 reauthor 命令，重写使用错误身份的提交
+	rootCmd.AddCommand(createReauthorCommand(projectRoot, commitFlags, appConfig))
+
+	// Add autosquash command to fold fixup commits into their targets
+	// 添加 autosquash 命令，将 fixup 提交合入其目标
+	rootCmd.AddCommand(createAutosquashCommand(projectRoot, commitFlags))
+
+	// Add suggest command to propose a message from the staged changes
+	// 添加 suggest 命令，根据已暂存的更改建议提交消息
+	rootCmd.AddCommand(createSuggestCommand(projectRoot, commitFlags))
+
+	// Add api command to report exported API changes of the staged changes
+	// 添加 api 命令，报告已暂存更改中的导出 API 更改
+	rootCmd.AddCommand(createAPICommand(projectRoot))
+
+	// Add changelog command to render conventional commits as a changelog
+	// 添加 changelog 命令，将约定式提交渲染为变更日志
+	rootCmd.AddCommand(createChangelogCommand(projectRoot, appConfig))
+
+	// Add tag command to tag the next semver of each module
+	// 添加 tag 命令，为每个模块打上下一个语义版本标签
+	rootCmd.AddCommand(createTagCommand(projectRoot, commitFlags, appConfig))
+
+	// Add fmt command to format or check the changed Go files
+	// 添加 fmt 命令，格式化或检查已改变的 Go 文件
+	rootCmd.AddCommand(createFmtCommand(projectRoot, commitFlags))
+
+	// Add undo command to revert the last journaled operation
+	// 添加 undo 命令，撤销日志中最近一次操作
+	rootCmd.AddCommand(createUndoCommand(projectRoot))
+
+	// Add log command to list the journaled operations
+	// 添加 log 命令，列出日志中的操作
+	rootCmd.AddCommand(createLogCommand(projectRoot))
+
 	// Add independent config-example command (same features as config example)
 	// 添加独立的 config-example 命令（与 config example 功能相同）
 	configExampleIndependentCmd := createConfigExampleIndependentCommand(projectRoot)
@@ -83,7 +127,11 @@ func createRootCommand(projectRoot string, commitFlags *commitmate.CommitFlags,
 				}
 			}
 
-			must.Done(commitmate.GitCommit(projectRoot, commitFlags))
+			// The first interrupt rolls back at the next step, the second one exits at once
+			// 第一次中断在下一个步骤回滚，第二次中断立即退出
+			ctx, stopWatch := watchInterrupt()
+			defer stopWatch()
+			must.Done(commitmate.GitCommitContext(ctx, projectRoot, commitFlags))
 		},
 	}
 
@@ -98,6 +146,17 @@ func createRootCommand(projectRoot string, commitFlags *commitmate.CommitFlags,
 	rootCmd.PersistentFlags().BoolVar(&commitFlags.NoCommit, "no-commit", false, "stage changes without committing")
 	rootCmd.PersistentFlags().BoolVar(&commitFlags.FormatGo, "format-go", false, "format changed go files")
 	rootCmd.PersistentFlags().BoolVar(&commitFlags.AutoSign, "auto-sign", false, "auto-use git config signing info when unset")
+	rootCmd.PersistentFlags().StringVar(&commitFlags.Fixup, "fixup", "", "commit as a fixup! of the revision")
+	rootCmd.PersistentFlags().StringVar(&commitFlags.Squash, "squash", "", "commit as a squash! of the revision, message becomes the body")
+	rootCmd.PersistentFlags().BoolVar(&commitFlags.Suggest, "suggest", false, "suggest the commit message from the staged go changes when -m is empty")
+	rootCmd.PersistentFlags().BoolVar(&commitFlags.Conventional, "conventional", false, "require a conventional commit message, marked breaking when the exported api breaks")
+	rootCmd.PersistentFlags().StringVar(&commitFlags.SplitBy, "split-by", "", "split changes into one commit per package, module or dir")
+	rootCmd.PersistentFlags().StringVar(&commitFlags.SplitMsg, "split-message", commitmate.DefaultSplitMessage, "message template of split commits with {group}, {message} and {count}")
+	rootCmd.PersistentFlags().BoolVar(&commitFlags.StagedOnly, "staged-only", false, "commit the index as staged, format the staged go files with --format-go")
+	rootCmd.PersistentFlags().StringVar(&commitFlags.FormatScope, "format-scope", "", "format whole files (file) or just the changed lines and their declarations (hunks)")
+	rootCmd.PersistentFlags().IntVar(&commitFlags.Jobs, "jobs", 0, "go files formatted at once (default: the cpu count)")
+	rootCmd.PersistentFlags().BoolVar(&commitFlags.NormalizeText, "normalize-text", false, "normalize trailing whitespace, final newline, line endings and bom of changed text files")
+	rootCmd.PersistentFlags().BoolVar(&commitFlags.NoRollback, "no-rollback", false, "keep the formatted and staged changes when the commit fails or is interrupted")
 	rootCmd.PersistentFlags().StringVarP(&appConfig.ConfigPath, "config", "c", "", "path to go-commit configuration file")
 
 	return rootCmd
@@ -119,9 +178,18 @@ func createConfigCommand(projectRoot string, commitFlags *commitmate.CommitFlags
 
 			zaplog.SUG.Debugln("config path:", appConfig.ConfigPath)
 
-			// Load and use configuration
-			// 加载并使用配置
-			config := commitmate.LoadConfig(appConfig.ConfigPath)
+			// Load configuration and list each problem when validation fails
+			// 加载配置，验证失败时列出每个问题
+			config, err := commitmate.LoadConfigE(appConfig.ConfigPath)
+			if err != nil {
+				var configError *commitmate.ConfigError
+				if errors.As(err, &configError) {
+					for _, problem := range configError.Problems {
+						zaplog.SUG.Errorln(problem.String())
+					}
+				}
+				zaplog.SUG.Panicln(err)
+			}
 			zaplog.SUG.Debugln("config items:", neatjsons.S(config))
 
 			commitFlags.ApplyProjectConfig(projectRoot, config)
@@ -143,6 +211,19 @@ func createConfigExampleCommand(projectRoot string) *cobra.Command {
 	}
 }
 
+// createConfigSchemaCommand creates the config schema subcommand
+// 创建 config schema 子命令
+func createConfigSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of go-commit configuration",
+		Long:  "Print the JSON Schema of go-commit configuration, save it and reference it with the \"$schema\" field",
+		Run: func(cmd *cobra.Command, args []string) {
+			rese.C1(os.Stdout.Write(commitmate.ConfigSchema()))
+		},
+	}
+}
+
 // createConfigExampleIndependentCommand creates the independent config-example command
 // 创建独立的 config-example 命令
 func createConfigExampleIndependentCommand(projectRoot string) *cobra.Command {
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// createReauthorCommand creates the reauthor command
+// Uses the persistent --force flag, the same rule as amending pushed commits
+//
+// 创建 reauthor 命令
+// 使用持久的 --force 标志，与 amend 已推送提交的规则相同
+func createReauthorCommand(projectRoot string, commitFlags *commitmate.CommitFlags, appConfig *AppConfig) *cobra.Command {
+	options := &commitmate.ReauthorOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "reauthor",
+		Short: "Rewrite unpushed commits made with the wrong identity",
+		Long:  "Find unpushed commits on the current branch whose author or committer differs from the resolved signature, and rewrite them keeping messages, dates and trees",
+		Run: func(cmd *cobra.Command, args []string) {
+			if appConfig.ConfigPath == "" {
+				zaplog.SUG.Panicln("missing config path. use -c flag")
+			}
+			config := commitmate.LoadConfig(appConfig.ConfigPath)
+			options.Force = commitFlags.IsForce
+
+			report, err := config.Reauthor(projectRoot, options)
+			if report != nil {
+				signature := report.Signature
+				fmt.Printf("branch: %s, signature: %s (%s <%s>)\n", report.Branch, signature.Name, signature.Username, zerotern.VV(signature.Mailbox, signature.Eddress))
+				for _, commit := range report.Commits {
+					fmt.Println(commit.String())
+				}
+			}
+			if err != nil {
+				zaplog.SUG.Panicln(err)
+			}
+
+			wrongCount := len(report.WrongCommits())
+			switch {
+			case wrongCount == 0:
+				fmt.Printf("%d commit(s) audited, none with the wrong identity\n", len(report.Commits))
+			case options.DryRun:
+				fmt.Printf("%d commit(s) audited, %d with the wrong identity, run without --dry-run to rewrite\n", len(report.Commits), wrongCount)
+			default:
+				fmt.Printf("%d commit(s) with the wrong identity rewritten, new head %s, previous head kept in ORIG_HEAD\n", wrongCount, report.NewHead.String()[:7])
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "show the commits without rewriting")
+	cmd.Flags().IntVar(&options.Limit, "limit", 0, "audit this many commits from HEAD, pushed ones included (default: each unpushed commit)")
+	return cmd
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+)
+
+// createSuggestCommand creates the suggest command
+// Prints the suggested message of the staged changes, ready to pipe into "git commit -e -F -"
+// With --conventional the message gets a conventional header
+//
+// 创建 suggest 命令
+// 输出已暂存更改的建议消息，可直接通过管道传给 "git commit -e -F -"
+// 使用 --conventional 时消息带有约定式标题
+func createSuggestCommand(projectRoot string, commitFlags *commitmate.CommitFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest a commit message from the staged Go changes",
+		Long:  "Suggest a commit message offline from the staged changes: exported API changes, new packages, test-only changes and go.mod dependency bumps",
+		Run: func(cmd *cobra.Command, args []string) {
+			var message string
+			if commitFlags.Conventional {
+				report := rese.P1(commitmate.CheckAPIChanges(projectRoot))
+				message = rese.V1(commitmate.SuggestConventionalMessage(projectRoot, report))
+			} else {
+				message = rese.V1(commitmate.SuggestMessage(projectRoot))
+			}
+			if message != "" {
+				fmt.Println(message)
+			}
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// createTagCommand creates the tag command
+// Uses the persistent -u/--mailbox/-m flags and the config signature as tagger
+//
+// 创建 tag 命令
+// 使用持久的 -u/--mailbox/-m 标志和配置中的签名作为标签创建者
+func createTagCommand(projectRoot string, commitFlags *commitmate.CommitFlags, appConfig *AppConfig) *cobra.Command {
+	options := &commitmate.TagOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Tag the next semver of each module from conventional commits",
+		Long:  "Compute the next semver of each Go module since its latest tag (nested modules use \"dir/vX.Y.Z\" tags) and create annotated tags on HEAD",
+		Run: func(cmd *cobra.Command, args []string) {
+			if appConfig.ConfigPath != "" {
+				config := commitmate.LoadConfig(appConfig.ConfigPath)
+				signature := config.ResolveSignature(projectRoot)
+				commitFlags.ApplySignature(signature)
+				if signature != nil {
+					options.SigningKey = zerotern.VV(options.SigningKey, signature.SigningKey)
+				}
+			}
+			options.Username = commitFlags.Username
+			options.Mailbox = zerotern.VV(commitFlags.Mailbox, commitFlags.Eddress)
+			options.Message = commitFlags.Message
+
+			tags, err := commitmate.TagModules(projectRoot, options)
+			for _, tag := range tags {
+				fmt.Println(tag.String())
+			}
+			if err != nil {
+				zaplog.SUG.Panicln(err)
+			}
+
+			var count int
+			for _, tag := range tags {
+				if tag.Next != "" {
+					count++
+				}
+			}
+			switch {
+			case count == 0:
+				fmt.Println("nothing to tag")
+			case options.DryRun:
+				fmt.Printf("%d tag(s) computed, run without --dry-run to create them\n", count)
+			default:
+				fmt.Printf("%d tag(s) created, push them with: git push --tags\n", count)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "print the computed versions without creating tags")
+	cmd.Flags().BoolVar(&options.Sign, "sign", false, "create signed tags with git tag -s")
+	cmd.Flags().StringVar(&options.SigningKey, "signing-key", "", "key of the signed tags (default: signingKey of the config signature)")
+	return cmd
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-mate/go-commit/commitmate"
+	"github.com/spf13/cobra"
+	"github.com/yyle88/rese"
+)
+
+// createUndoCommand creates the undo command
+// Reverts the last journaled operation, --restore-files brings back the contents from before formatting
+//
+// 创建 undo 命令
+// 撤销日志中最近一次操作，--restore-files 恢复格式化之前的内容
+func createUndoCommand(projectRoot string) *cobra.Command {
+	options := &commitmate.UndoOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last go-commit operation",
+		Long:  "Reset the branch and the index to before the last operation journaled under .git/go-commit/ (the previous HEAD is kept as ORIG_HEAD), refusing when the branch or the index moved since, --restore-files also writes back the files rewritten by formatting unless edited since",
+		Run: func(cmd *cobra.Command, args []string) {
+			result := rese.P1(commitmate.UndoLastOperation(projectRoot, options))
+			fmt.Println("undone:", result.Entry.String())
+			for _, path := range result.Restored {
+				fmt.Println("restored:", path)
+			}
+			for _, path := range result.Skipped {
+				fmt.Println("skipped, edited since:", path)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&options.RestoreFiles, "restore-files", false, "restore the file contents from before formatting")
+	return cmd
+}
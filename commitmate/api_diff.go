@@ -0,0 +1,608 @@
+// Package commitmate compares the exported API of changed Go packages between HEAD and the index
+// Type-checks both snapshots with go/types and classifies each change as compatible or breaking
+//
+// commitmate 包比较 HEAD 与索引之间已更改 Go 包的导出 API
+// 使用 go/types 对两个快照进行类型检查，并将每个更改分类为兼容或破坏性
+package commitmate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// APIChange is one change of an exported declaration
+// APIChange 是一个导出声明的更改
+type APIChange struct {
+	Package  string // Import path of the package // 包的导入路径
+	Item     string // Declaration such as "func Open" or "field Config.Name" // 声明，例如 "func Open" 或 "field Config.Name"
+	Action   string // "added", "removed", "changed" or "unknown" when types are unresolved // "added"、"removed"、"changed"，类型无法解析时为 "unknown"
+	Before   string // Type before the change // 更改前的类型
+	After    string // Type after the change // 更改后的类型
+	Breaking bool   // Whether importers may fail to build // 导入方是否可能无法构建
+}
+
+// String returns the change as a report line, "!!" marks breaking changes
+// String 将更改返回为报告行，"!!" 标记破坏性更改
+func (c *APIChange) String() string {
+	mark := "  "
+	if c.Breaking {
+		mark = "!!"
+	}
+	switch c.Action {
+	case "changed":
+		return fmt.Sprintf("%s %s: changed %s from %s to %s", mark, c.Package, c.Item, c.Before, c.After)
+	case "removed":
+		return fmt.Sprintf("%s %s: removed %s", mark, c.Package, c.Item)
+	case "unknown":
+		return fmt.Sprintf("?? %s: unknown %s, unresolved types in %s", c.Package, c.Item, c.After)
+	default:
+		return fmt.Sprintf("%s %s: added %s", mark, c.Package, c.Item)
+	}
+}
+
+// APIReport holds the exported API changes of the staged changes
+// APIReport 保存已暂存更改中的导出 API 更改
+type APIReport struct {
+	Changes    []*APIChange // Changes sorted by package and item // 按包和声明排序的更改
+	MajorBumps []string     // Suggested module path bumps as "old -> new" // 建议的模块路径升级，格式为 "old -> new"
+}
+
+// BreakingChanges returns the changes that may break importers
+// BreakingChanges 返回可能破坏导入方的更改
+func (r *APIReport) BreakingChanges() []*APIChange {
+	var changes []*APIChange
+	for _, change := range r.Changes {
+		if change.Breaking {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// String returns one line per change followed by the suggested module path bumps
+// String 每个更改一行，随后是建议的模块路径升级
+func (r *APIReport) String() string {
+	var lines []string
+	for _, change := range r.Changes {
+		lines = append(lines, change.String())
+	}
+	for _, bump := range r.MajorBumps {
+		lines = append(lines, "major version: "+bump)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckAPIChanges compares the exported API of each changed package between HEAD and the index
+// Packages main and internal packages are skipped since no other module imports them
+// Imports from the repo are type-checked from the same snapshot, standard ones use export data,
+// others resolve to stub packages declaring each selected name as a type, so "dep.A" and "dep.B" differ
+// Declarations still holding invalid types on both sides are reported as unknown, not as unchanged
+//
+// CheckAPIChanges 比较 HEAD 与索引之间每个已更改包的导出 API
+// 跳过 main 包和 internal 包，因为其他模块不会导入它们
+// 仓库内的导入从同一快照进行类型检查，标准库使用导出数据，
+// 其他导入解析为桩包，桩包将每个被选择的名称声明为类型，因此 "dep.A" 与 "dep.B" 不同
+// 两侧仍包含无效类型的声明报告为 unknown，而不是未更改
+func CheckAPIChanges(projectRoot string) (*APIReport, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedFiles, err := indexFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	var dirs []string
+	for _, name := range listStagedChanges(headFiles, stagedFiles) {
+		if path.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") {
+			dirs = appendUnique(dirs, path.Dir(name))
+		}
+	}
+	report := &APIReport{}
+	if len(dirs) == 0 {
+		return report, nil
+	}
+
+	stdImporter := importer.ForCompiler(token.NewFileSet(), "gc", nil)
+	before, err := newSnapshotImporter(repo, headFiles, stdImporter)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	after, err := newSnapshotImporter(repo, stagedFiles, stdImporter)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	breakingModules := map[string]string{}
+	for _, dir := range dirs {
+		moduleDIR, modulePath := after.moduleOf(dir)
+		if modulePath == "" {
+			moduleDIR, modulePath = before.moduleOf(dir)
+		}
+		importPath := joinImportPath(modulePath, moduleDIR, dir)
+		if isInternalPath(importPath) {
+			continue
+		}
+		beforeAPI, err := before.packageAPI(dir, importPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		afterAPI, err := after.packageAPI(dir, importPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		changes := diffPackageAPI(importPath, beforeAPI, afterAPI)
+		for _, change := range changes {
+			if change.Breaking && modulePath != "" {
+				breakingModules[modulePath] = moduleDIR
+			}
+		}
+		report.Changes = append(report.Changes, changes...)
+	}
+
+	modulePaths := make([]string, 0, len(breakingModules))
+	for modulePath := range breakingModules {
+		modulePaths = append(modulePaths, modulePath)
+	}
+	sort.Strings(modulePaths)
+	for _, modulePath := range modulePaths {
+		if newPath := suggestMajorModulePath(repo, modulePath, breakingModules[modulePath]); newPath != "" {
+			report.MajorBumps = append(report.MajorBumps, modulePath+" -> "+newPath)
+		}
+	}
+	return report, nil
+}
+
+// joinImportPath returns the import path of the DIR within the module, the DIR itself without a module
+// joinImportPath 返回模块内 DIR 的导入路径，没有模块时返回 DIR 本身
+func joinImportPath(modulePath string, moduleDIR string, dir string) string {
+	if modulePath == "" {
+		return dir
+	}
+	if dir == moduleDIR {
+		return modulePath
+	}
+	return modulePath + "/" + strings.TrimPrefix(dir, moduleDIR+"/")
+}
+
+// isInternalPath reports whether the import path has an "internal" element
+// isInternalPath 判断导入路径中是否包含 "internal" 元素
+func isInternalPath(importPath string) bool {
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestMajorModulePath returns the next major module path when the module is v1 or above
+// A "/vN" path gives "/vN+1", else a release tag of v1 or above gives "/v2", v0 modules give blank
+//
+// suggestMajorModulePath 当模块为 v1 及以上时返回下一个主版本的模块路径
+// "/vN" 路径返回 "/vN+1"，否则存在 v1 及以上的发布标签时返回 "/v2"，v0 模块返回空
+func suggestMajorModulePath(repo *git.Repository, modulePath string, moduleDIR string) string {
+	prefix, major, ok := module.SplitPathVersion(modulePath)
+	if ok && major != "" && strings.HasPrefix(major, "/v") {
+		number, err := strconv.Atoi(strings.TrimPrefix(major, "/v"))
+		if err == nil {
+			return fmt.Sprintf("%s/v%d", prefix, number+1)
+		}
+	}
+	if latest := latestModuleVersion(repo, moduleDIR); latest != "" && semver.Major(latest) != "v0" {
+		return modulePath + "/v2"
+	}
+	return ""
+}
+
+// latestModuleVersion returns the highest semver tag of the module DIR, "dir/vX.Y.Z" for nested modules
+// latestModuleVersion 返回模块 DIR 的最高语义版本标签，嵌套模块使用 "dir/vX.Y.Z"
+func latestModuleVersion(repo *git.Repository, moduleDIR string) string {
+	tagPrefix := ""
+	if moduleDIR != "." {
+		tagPrefix = moduleDIR + "/"
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		return ""
+	}
+	latest := ""
+	_ = tags.ForEach(func(reference *plumbing.Reference) error {
+		version, found := strings.CutPrefix(reference.Name().Short(), tagPrefix)
+		if found && semver.IsValid(version) && semver.Compare(version, latest) > 0 {
+			latest = version
+		}
+		return nil
+	})
+	return latest
+}
+
+// apiItem is one exported declaration with its type description
+// apiItem 是一个导出声明及其类型描述
+type apiItem struct {
+	desc      string // Type description compared across snapshots // 跨快照比较的类型描述
+	parent    string // Owning type name of fields and methods // 字段和方法所属的类型名称
+	addBreaks bool   // Adding breaks importers, as with interface methods // 新增会破坏导入方，例如接口方法
+}
+
+// diffPackageAPI returns the changes between the two API maps, a nil map means the package is missing
+// Fields and methods of added or removed types are folded into the type change
+//
+// diffPackageAPI 返回两个 API 映射之间的更改，nil 映射表示包不存在
+// 新增或删除类型的字段和方法合并到该类型的更改中
+func diffPackageAPI(importPath string, before, after map[string]*apiItem) []*APIChange {
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		return []*APIChange{{Package: importPath, Item: "package", Action: "added"}}
+	case after == nil:
+		return []*APIChange{{Package: importPath, Item: "package", Action: "removed", Breaking: true}}
+	}
+
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, exists := before[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []*APIChange
+	for _, name := range names {
+		oldItem, newItem := before[name], after[name]
+		switch {
+		case oldItem == nil:
+			if _, exists := before["type "+newItem.parent]; newItem.parent != "" && !exists {
+				continue
+			}
+			changes = append(changes, &APIChange{Package: importPath, Item: name, Action: "added", After: newItem.desc, Breaking: newItem.addBreaks})
+		case newItem == nil:
+			if _, exists := after["type "+oldItem.parent]; oldItem.parent != "" && !exists {
+				continue
+			}
+			changes = append(changes, &APIChange{Package: importPath, Item: name, Action: "removed", Before: oldItem.desc, Breaking: true})
+		case oldItem.desc != newItem.desc:
+			changes = append(changes, &APIChange{Package: importPath, Item: name, Action: "changed", Before: oldItem.desc, After: newItem.desc, Breaking: true})
+		case strings.Contains(newItem.desc, invalidTypeDesc):
+			changes = append(changes, &APIChange{Package: importPath, Item: name, Action: "unknown", Before: oldItem.desc, After: newItem.desc})
+		}
+	}
+	return changes
+}
+
+// invalidTypeDesc is how go/types describes a type it could not resolve
+// invalidTypeDesc 是 go/types 对无法解析的类型的描述
+const invalidTypeDesc = "invalid type"
+
+// snapshotImporter type-checks packages of one tree snapshot and resolves imports between them
+// snapshotImporter 对一个文件树快照中的包进行类型检查，并解析它们之间的导入
+type snapshotImporter struct {
+	repo     *git.Repository           // Repo holding the blobs // 保存数据对象的仓库
+	files    map[string]treeFile       // Files of the snapshot // 快照中的文件
+	modules  map[string]string         // Module DIR to module path // 模块 DIR 到模块路径
+	std      types.Importer            // Importer of other packages // 其他包的导入器
+	fset     *token.FileSet            // File set of parsed files // 已解析文件的文件集
+	packages map[string]*types.Package // Checked packages by import path // 按导入路径缓存的已检查包
+	stubs    map[string]bool           // Import paths resolved to stub packages // 解析为桩包的导入路径
+}
+
+// newSnapshotImporter reads the go.mod files of the snapshot
+// newSnapshotImporter 读取快照中的 go.mod 文件
+func newSnapshotImporter(repo *git.Repository, files map[string]treeFile, std types.Importer) (*snapshotImporter, error) {
+	modules := map[string]string{}
+	for name, file := range files {
+		if path.Base(name) != "go.mod" {
+			continue
+		}
+		content, err := readBlob(repo, file.hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if modulePath := modfile.ModulePath([]byte(content)); modulePath != "" {
+			modules[path.Dir(name)] = modulePath
+		}
+	}
+	return &snapshotImporter{
+		repo:     repo,
+		files:    files,
+		modules:  modules,
+		std:      std,
+		fset:     token.NewFileSet(),
+		packages: map[string]*types.Package{},
+		stubs:    map[string]bool{},
+	}, nil
+}
+
+// moduleOf returns the DIR and path of the nearest module at or above the DIR
+// moduleOf 返回 DIR 及其上级中最近模块的 DIR 和路径
+func (s *snapshotImporter) moduleOf(dir string) (string, string) {
+	for {
+		if modulePath, exists := s.modules[dir]; exists {
+			return dir, modulePath
+		}
+		if dir == "." {
+			return "", ""
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// dirOf returns the DIR of the import path within the modules of the snapshot
+// dirOf 返回导入路径在快照模块中的 DIR
+func (s *snapshotImporter) dirOf(importPath string) (string, bool) {
+	bestDIR, bestPath := "", ""
+	for moduleDIR, modulePath := range s.modules {
+		if (importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")) && len(modulePath) > len(bestPath) {
+			bestDIR, bestPath = moduleDIR, modulePath
+		}
+	}
+	if bestPath == "" {
+		return "", false
+	}
+	return path.Join(bestDIR, strings.TrimPrefix(importPath, bestPath)), true
+}
+
+// Import resolves the import path from the snapshot, then the standard importer, else gives a stub package
+// Import 先从快照解析导入路径，然后使用标准导入器，否则返回桩包
+func (s *snapshotImporter) Import(importPath string) (*types.Package, error) {
+	if pkg, exists := s.packages[importPath]; exists {
+		return pkg, nil
+	}
+	if dir, ok := s.dirOf(importPath); ok && hasPackageFiles(s.files, dir) {
+		return s.checkPackage(dir, importPath)
+	}
+	if pkg, err := s.std.Import(importPath); err == nil {
+		s.packages[importPath] = pkg
+		return pkg, nil
+	}
+	prefix, _, _ := module.SplitPathVersion(importPath)
+	pkg := types.NewPackage(importPath, path.Base(prefix))
+	pkg.MarkComplete()
+	s.packages[importPath], s.stubs[importPath] = pkg, true
+	return pkg, nil
+}
+
+// declareStubNames declares the names the files select from stub packages as named types
+// Dependencies outside the snapshot then compare by qualified type names instead of all being invalid
+//
+// declareStubNames 将文件从桩包中选择的名称声明为命名类型
+// 快照之外的依赖因此按限定类型名称比较，而不是全部作为无效类型
+func (s *snapshotImporter) declareStubNames(astFiles []*ast.File) {
+	for _, astFile := range astFiles {
+		stubNames := map[string]*types.Package{}
+		for _, spec := range astFile.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			pkg, _ := s.Import(importPath)
+			if !s.stubs[importPath] {
+				continue
+			}
+			name := pkg.Name()
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			stubNames[name] = pkg
+		}
+		if len(stubNames) == 0 {
+			continue
+		}
+		ast.Inspect(astFile, func(node ast.Node) bool {
+			selector, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := selector.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if pkg, ok := stubNames[ident.Name]; ok && pkg.Scope().Lookup(selector.Sel.Name) == nil {
+				typeName := types.NewTypeName(token.NoPos, pkg, selector.Sel.Name, nil)
+				types.NewNamed(typeName, types.NewStruct(nil, nil), nil)
+				pkg.Scope().Insert(typeName)
+			}
+			return true
+		})
+	}
+}
+
+// checkPackage parses the non-test Go files of the DIR and type-checks them, type errors are tolerated
+// checkPackage 解析 DIR 中的非测试 Go 文件并进行类型检查，容忍类型错误
+func (s *snapshotImporter) checkPackage(dir string, importPath string) (*types.Package, error) {
+	// Register a placeholder first so that import cycles end
+	// 先注册占位包以终止导入循环
+	s.packages[importPath] = types.NewPackage(importPath, path.Base(dir))
+
+	var names []string
+	for name := range s.files {
+		if path.Dir(name) == dir && path.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var astFiles []*ast.File
+	for _, name := range names {
+		content, err := readBlob(s.repo, s.files[name].hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		astFile, err := parser.ParseFile(s.fset, name, content, parser.SkipObjectResolution|parser.ParseComments)
+		if astFile == nil || !matchBuildConstraint(astFile) {
+			continue
+		}
+		astFiles = append(astFiles, astFile)
+	}
+	s.declareStubNames(astFiles)
+	config := &types.Config{Importer: s, Error: func(error) {}}
+	pkg, _ := config.Check(importPath, s.fset, astFiles, nil)
+	s.packages[importPath] = pkg
+	return pkg, nil
+}
+
+// packageAPI returns the exported API of the package in the DIR, nil when the DIR has no package
+// Package main gives nil since no one imports it
+//
+// packageAPI 返回 DIR 中包的导出 API，DIR 中没有包时返回 nil
+// main 包返回 nil，因为没有人导入它
+func (s *snapshotImporter) packageAPI(dir string, importPath string) (map[string]*apiItem, error) {
+	if !hasPackageFiles(s.files, dir) {
+		return nil, nil
+	}
+	pkg, exists := s.packages[importPath]
+	if !exists || !pkg.Complete() {
+		var err error
+		if pkg, err = s.checkPackage(dir, importPath); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	if pkg == nil || pkg.Name() == "main" {
+		return nil, nil
+	}
+	return collectPackageAPI(pkg), nil
+}
+
+// matchBuildConstraint reports whether the "//go:build" line of the file holds on this platform
+// matchBuildConstraint 判断文件的 "//go:build" 行在当前平台上是否成立
+func matchBuildConstraint(astFile *ast.File) bool {
+	for _, group := range astFile.Comments {
+		if group.Pos() > astFile.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				return true
+			}
+			return expr.Eval(func(tag string) bool {
+				return tag == runtime.GOOS || tag == runtime.GOARCH || tag == "gc" || strings.HasPrefix(tag, "go1.") || (tag == "unix" && runtime.GOOS != "windows")
+			})
+		}
+	}
+	return true
+}
+
+// collectPackageAPI describes each exported const, var, func, type, field and method of the package
+// collectPackageAPI 描述包中每个导出的常量、变量、函数、类型、字段和方法
+func collectPackageAPI(pkg *types.Package) map[string]*apiItem {
+	qualifier := func(other *types.Package) string {
+		if other.Path() == pkg.Path() {
+			return ""
+		}
+		return other.Path()
+	}
+	describe := func(typ types.Type) string {
+		return types.TypeString(typ, qualifier)
+	}
+
+	items := map[string]*apiItem{}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		object := scope.Lookup(name)
+		if !object.Exported() {
+			continue
+		}
+		switch object := object.(type) {
+		case *types.Const:
+			items["const "+name] = &apiItem{desc: describe(object.Type())}
+		case *types.Var:
+			items["var "+name] = &apiItem{desc: describe(object.Type())}
+		case *types.Func:
+			items["func "+name] = &apiItem{desc: describe(object.Type())}
+		case *types.TypeName:
+			collectTypeAPI(items, object, describe)
+		}
+	}
+	return items
+}
+
+// collectTypeAPI describes the type with its exported fields, interface methods and methods
+// collectTypeAPI 描述类型及其导出字段、接口方法和方法
+func collectTypeAPI(items map[string]*apiItem, object *types.TypeName, describe func(types.Type) string) {
+	name := object.Name()
+	if object.IsAlias() {
+		items["type "+name] = &apiItem{desc: "= " + describe(object.Type())}
+		return
+	}
+	named, ok := object.Type().(*types.Named)
+	if !ok {
+		items["type "+name] = &apiItem{desc: describe(object.Type())}
+		return
+	}
+
+	var typeParams []string
+	for idx := 0; idx < named.TypeParams().Len(); idx++ {
+		typeParam := named.TypeParams().At(idx)
+		typeParams = append(typeParams, typeParam.Obj().Name()+" "+describe(typeParam.Constraint()))
+	}
+	desc := ""
+	if len(typeParams) > 0 {
+		desc = "[" + strings.Join(typeParams, ", ") + "] "
+	}
+
+	switch underlying := named.Underlying().(type) {
+	case *types.Struct:
+		items["type "+name] = &apiItem{desc: desc + "struct"}
+		for idx := 0; idx < underlying.NumFields(); idx++ {
+			if field := underlying.Field(idx); field.Exported() {
+				items["field "+name+"."+field.Name()] = &apiItem{desc: describe(field.Type()), parent: name}
+			}
+		}
+	case *types.Interface:
+		items["type "+name] = &apiItem{desc: desc + "interface"}
+		sealed := false
+		for idx := 0; idx < underlying.NumMethods(); idx++ {
+			sealed = sealed || !underlying.Method(idx).Exported()
+		}
+		for idx := 0; idx < underlying.NumMethods(); idx++ {
+			if method := underlying.Method(idx); method.Exported() {
+				items["method "+name+"."+method.Name()] = &apiItem{desc: describe(method.Type()), parent: name, addBreaks: !sealed}
+			}
+		}
+	default:
+		items["type "+name] = &apiItem{desc: desc + describe(underlying)}
+	}
+
+	for idx := 0; idx < named.NumMethods(); idx++ {
+		method := named.Method(idx)
+		if !method.Exported() {
+			continue
+		}
+		receiver := name
+		if _, isPointer := method.Type().(*types.Signature).Recv().Type().(*types.Pointer); isPointer {
+			receiver = "(*" + name + ")"
+		}
+		items["method "+receiver+"."+method.Name()] = &apiItem{desc: describe(method.Type()), parent: name}
+	}
+}
@@ -0,0 +1,115 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// setupAPITestRepo commits a module with a client package and a package depending on it
+// setupAPITestRepo 提交一个包含 client 包以及依赖它的包的模块
+func setupAPITestRepo(t *testing.T, modulePath string) string {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"go.mod":           "module " + modulePath + "\n\ngo 1.22\n",
+		"client/client.go": "package client\n\nimport \"io\"\n\ntype Reader interface {\n\tRead() error\n}\n\ntype Config struct {\n\tName string\n}\n\ntype Client struct{}\n\nfunc Open(cfg Config) (*Client, error) { return nil, nil }\n\nfunc (c *Client) Close() error { return nil }\n\nfunc (c *Client) Writer() io.Writer { return nil }\n",
+		"store/store.go":   "package store\n\nimport \"" + modulePath + "/client\"\n\nfunc Use(c *client.Client) {}\n",
+		"cmd/main.go":      "package main\n\nfunc Main() {}\n\nfunc main() {}\n",
+	})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add module"}))
+	return tempDIR
+}
+
+// TestCheckAPIChanges validates classifying compatible and breaking changes
+// Tests added fields and methods, interface methods, changed signatures and package main skipping
+//
+// TestCheckAPIChanges 验证将更改分类为兼容或破坏性
+// 测试新增字段和方法、接口方法、签名更改以及跳过 main 包
+func TestCheckAPIChanges(t *testing.T) {
+	tempDIR := setupAPITestRepo(t, "example.com/demo")
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"client/client.go": "package client\n\nimport \"io\"\n\ntype Reader interface {\n\tRead() error\n}\n\ntype Config struct {\n\tName string\n\tDebug bool\n}\n\ntype Client struct{}\n\nfunc Open(cfg Config) (*Client, error) { return nil, nil }\n\nfunc (c *Client) Close() error { return nil }\n\nfunc (c *Client) Writer() io.Writer { return nil }\n\nfunc (c *Client) Ping() error { return nil }\n",
+		"cmd/main.go":      "package main\n\nfunc main() {}\n",
+	})
+	report, err := CheckAPIChanges(tempDIR)
+	require.NoError(t, err)
+	require.Empty(t, report.BreakingChanges())
+	require.Equal(t, "   example.com/demo/client: added field Config.Debug\n   example.com/demo/client: added method (*Client).Ping", report.String())
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"client/client.go": "package client\n\nimport \"io\"\n\ntype Reader interface {\n\tRead() error\n\tReset()\n}\n\ntype Config struct {\n\tName string\n}\n\ntype Client struct{}\n\nfunc Open(cfg *Config) (*Client, error) { return nil, nil }\n\nfunc (c *Client) Close() error { return nil }\n\nfunc (c *Client) Writer() io.WriteCloser { return nil }\n",
+	})
+	report, err = CheckAPIChanges(tempDIR)
+	require.NoError(t, err)
+	var items []string
+	for _, change := range report.BreakingChanges() {
+		items = append(items, change.Action+" "+change.Item)
+	}
+	require.Equal(t, []string{"changed func Open", "changed method (*Client).Writer", "added method Reader.Reset"}, items)
+	require.Equal(t, "!! example.com/demo/client: changed func Open from func(cfg Config) (*Client, error) to func(cfg *Config) (*Client, error)", report.BreakingChanges()[0].String())
+	require.Empty(t, report.MajorBumps)
+}
+
+// TestCheckAPIChanges_Dependency validates types from module dependencies outside the snapshot
+// Tests that swapping dependency types is breaking and that unresolved types are reported as unknown
+//
+// TestCheckAPIChanges_Dependency 验证来自快照之外模块依赖的类型
+// 测试替换依赖类型属于破坏性更改，且无法解析的类型报告为 unknown
+func TestCheckAPIChanges_Dependency(t *testing.T) {
+	tempDIR := setupAPITestRepo(t, "example.com/demo")
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"codec/codec.go": "package codec\n\nimport (\n\t\"github.com/other/dep\"\n\tyaml \"github.com/other/yaml/v3\"\n)\n\nfunc Encode(x dep.A) yaml.Node { return yaml.Node{} }\n\nfunc Batch(x dep.List[int]) {}\n",
+	})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add codec"}))
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"codec/codec.go": "package codec\n\nimport (\n\t\"github.com/other/dep\"\n\tyaml \"github.com/other/yaml/v3\"\n)\n\nfunc Encode(x dep.B) yaml.Node { return yaml.Node{} }\n\nfunc Batch(x dep.List[int]) {}\n",
+	})
+	report, err := CheckAPIChanges(tempDIR)
+	require.NoError(t, err)
+	require.Len(t, report.BreakingChanges(), 1)
+	require.Equal(t, "!! example.com/demo/codec: changed func Encode from func(x github.com/other/dep.A) github.com/other/yaml/v3.Node to func(x github.com/other/dep.B) github.com/other/yaml/v3.Node", report.BreakingChanges()[0].String())
+	require.Len(t, report.Changes, 2)
+	require.Equal(t, "unknown", report.Changes[0].Action)
+	require.Equal(t, "?? example.com/demo/codec: unknown func Batch, unresolved types in func(x invalid type)", report.Changes[0].String())
+}
+
+func TestCheckConventionalCommit(t *testing.T) {
+	tempDIR := setupAPITestRepo(t, "example.com/demo/v2")
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store.go": "package store\n",
+	})
+	_, err := CheckConventionalCommit(tempDIR, "refactor(store): drop Use")
+	require.ErrorContains(t, err, "BREAKING CHANGE")
+	require.ErrorContains(t, err, "example.com/demo/v2 -> example.com/demo/v3")
+
+	report, err := CheckConventionalCommit(tempDIR, "refactor(store)!: drop Use")
+	require.NoError(t, err)
+	require.Len(t, report.BreakingChanges(), 1)
+
+	require.Error(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "drop Use", Conventional: true}))
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "refactor: drop Use\n\nBREAKING CHANGE: Use is gone", Conventional: true}))
+}
+
+func TestSuggestMajorModulePath(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+	repo := rese.P1(git.PlainOpen(tempDIR))
+
+	require.Equal(t, "example.com/demo/v3", suggestMajorModulePath(repo, "example.com/demo/v2", "."))
+	require.Empty(t, suggestMajorModulePath(repo, "example.com/demo", "."))
+
+	head := rese.P1(repo.Head()).Hash()
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("sub/v1.2.0"), head)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v0.9.0"), head)))
+	require.Equal(t, "example.com/demo/sub/v2", suggestMajorModulePath(repo, "example.com/demo/sub", "sub"))
+	require.Empty(t, suggestMajorModulePath(repo, "example.com/demo", "."))
+}
@@ -0,0 +1,397 @@
+// Package commitmate provides fixup commits and non-interactive autosquash
+// Creates "fixup!" and "squash!" commits, then folds them into their targets using go-git plumbing
+//
+// commitmate 包提供 fixup 提交和非交互式 autosquash
+// 创建 "fixup!" 和 "squash!" 提交，然后使用 go-git 底层接口将其合入目标提交
+package commitmate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// Subject prefixes of commits folded by autosquash
+// autosquash 合入的提交的标题前缀
+const (
+	FixupPrefix  = "fixup! "  // Fold the change and drop the message // 合入更改并丢弃消息
+	SquashPrefix = "squash! " // Fold the change and append the message // 合入更改并追加消息
+)
+
+// BuildFixupMessage builds the message of a fixup or squash commit targeting the revision
+// A squash commit appends the given message as its body
+//
+// BuildFixupMessage 构建以指定修订为目标的 fixup 或 squash 提交消息
+// squash 提交会将给定消息追加为正文
+func BuildFixupMessage(repo *git.Repository, prefix string, revision string, message string) (string, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	target, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	result := prefix + commitSubject(target.Message)
+	if prefix == SquashPrefix && strings.TrimSpace(message) != "" {
+		result += "\n\n" + strings.TrimSpace(message)
+	}
+	return result, nil
+}
+
+// AutosquashOptions configures the autosquash operation
+// AutosquashOptions 配置 autosquash 操作
+type AutosquashOptions struct {
+	Force  bool // Allow targets among pushed commits, the same rule as AmendConfig.ForceAmend // 允许目标位于已推送的提交中，与 AmendConfig.ForceAmend 规则相同
+	DryRun bool // Report the plan without rewriting // 仅报告计划而不重写
+}
+
+// AutosquashStep is one step of the rearranged history, oldest first
+// AutosquashStep 是重新排列后历史中的一步，最早的在前
+type AutosquashStep struct {
+	Action  string        // "pick", "fixup" or "squash" // "pick"、"fixup" 或 "squash"
+	Hash    plumbing.Hash // Original commit hash // 原始提交哈希
+	Subject string        // First line of the message // 消息的第一行
+	Target  plumbing.Hash // Target of fixup and squash steps // fixup 和 squash 步骤的目标
+}
+
+// String formats the step like a rebase todo line
+// String 将步骤格式化为类似 rebase 待办行的形式
+func (s *AutosquashStep) String() string {
+	return fmt.Sprintf("%-6s %s %s", s.Action, s.Hash.String()[:7], s.Subject)
+}
+
+// AutosquashReport is the result of folding fixup and squash commits
+// AutosquashReport 是合入 fixup 和 squash 提交的结果
+type AutosquashReport struct {
+	Branch    string            // Current branch // 当前分支
+	Steps     []*AutosquashStep // Rearranged steps from the first target, oldest first // 从第一个目标开始重新排列的步骤，最早的在前
+	Unmatched []*AutosquashStep // Fixup and squash commits without a target, kept as picks // 没有目标的 fixup 和 squash 提交，保留为 pick
+	NewHead   plumbing.Hash     // Branch tip after folding, zero when nothing was folded // 合入后的分支顶端，未合入时为零值
+}
+
+// FoldCount returns how many commits are folded into their targets
+// FoldCount 返回合入到目标中的提交数
+func (r *AutosquashReport) FoldCount() int {
+	var count int
+	for _, step := range r.Steps {
+		if step.Action != "pick" {
+			count++
+		}
+	}
+	return count
+}
+
+// Autosquash folds "fixup!" and "squash!" commits of the current branch into their targets
+// Works like "git rebase -i --autosquash" without an editor, on the first-parent history
+// The worktree and index stay as is since the folded history ends at the same tree
+//
+// Autosquash 将当前分支上的 "fixup!" 和 "squash!" 提交合入其目标
+// 类似无编辑器的 "git rebase -i --autosquash"，作用于第一父提交历史
+// 由于合入后的历史以相同的文件树结束，工作区和索引保持不变
+func Autosquash(projectRoot string, options *AutosquashOptions) (*AutosquashReport, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headReference, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if headReference.Type() != plumbing.SymbolicReference {
+		return nil, erero.New("HEAD is detached, checkout a branch first")
+	}
+	branchReference, err := repo.Reference(headReference.Target(), true)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	report := &AutosquashReport{Branch: headReference.Target().Short()}
+
+	commits, err := listAutosquashRange(repo, branchReference.Hash(), options.Force)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	steps, firstIdx := arrangeAutosquashSteps(commits, report)
+	if firstIdx < 0 {
+		return report, nil
+	}
+	report.Steps = steps[firstIdx:]
+	if options.DryRun {
+		return report, nil
+	}
+
+	newHead, err := replayAutosquashSteps(repo, commits, report.Steps)
+	if err != nil {
+		return report, erero.Wro(err)
+	}
+	headCommit, err := repo.CommitObject(branchReference.Hash())
+	if err != nil {
+		return report, erero.Wro(err)
+	}
+	newCommit, err := repo.CommitObject(newHead)
+	if err != nil {
+		return report, erero.Wro(err)
+	}
+	if newCommit.TreeHash != headCommit.TreeHash {
+		return report, erero.New("folding changes the final tree, run git rebase -i --autosquash instead")
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName("ORIG_HEAD"), branchReference.Hash())); err != nil {
+		return report, erero.Wro(err)
+	}
+	if err := repo.Storer.CheckAndSetReference(plumbing.NewHashReference(branchReference.Name(), newHead), branchReference); err != nil {
+		return report, erero.Wro(err)
+	}
+	report.NewHead = newHead
+	zaplog.SUG.Debugln("autosquash folded", report.FoldCount(), "commits on", report.Branch, "new head:", newHead)
+	return report, nil
+}
+
+// listAutosquashRange returns the unpushed first-parent commits, oldest first
+// With force the range extends past pushed commits until each fixup target is found
+//
+// listAutosquashRange 返回未推送的第一父提交，最早的在前
+// 使用 force 时范围会越过已推送的提交，直到找到每个 fixup 目标
+func listAutosquashRange(repo *git.Repository, head plumbing.Hash, force bool) ([]*object.Commit, error) {
+	pushedHashes, err := listPushedHashes(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	var commits []*object.Commit
+	for hash := head; !hash.IsZero(); {
+		if pushedHashes[hash] && (!force || !hasUnmatchedFixup(commits)) {
+			break
+		}
+		commitObject, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if len(commitObject.ParentHashes) > 1 {
+			return nil, erero.Errorf("merge commit %s in range, autosquash works on linear history", hash.String()[:7])
+		}
+		commits = append(commits, commitObject)
+		hash = plumbing.ZeroHash
+		if len(commitObject.ParentHashes) > 0 {
+			hash = commitObject.ParentHashes[0]
+		}
+	}
+
+	// Reverse to oldest first
+	// 反转为最早的在前
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// hasUnmatchedFixup reports whether some fixup in the newest-first commits has no target among them
+// hasUnmatchedFixup 判断最新在前的提交中是否有 fixup 在其中找不到目标
+func hasUnmatchedFixup(newestFirst []*object.Commit) bool {
+	for idx, commitObject := range newestFirst {
+		subject := commitSubject(commitObject.Message)
+		if _, fold := splitFixupSubject(subject); !fold {
+			continue
+		}
+		if findFixupTarget(subject, newestFirst[idx+1:], true) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFixupSubject strips the outermost fixup or squash prefix, reporting whether one was found
+// splitFixupSubject 去除最外层的 fixup 或 squash 前缀，并报告是否找到前缀
+func splitFixupSubject(subject string) (string, bool) {
+	for _, prefix := range []string{FixupPrefix, SquashPrefix} {
+		if rest, found := strings.CutPrefix(subject, prefix); found {
+			return rest, true
+		}
+	}
+	return subject, false
+}
+
+// fixupAction returns "fixup" or "squash" by the subject prefix, "pick" otherwise
+// fixupAction 根据标题前缀返回 "fixup" 或 "squash"，否则返回 "pick"
+func fixupAction(subject string) string {
+	switch {
+	case strings.HasPrefix(subject, FixupPrefix):
+		return "fixup"
+	case strings.HasPrefix(subject, SquashPrefix):
+		return "squash"
+	default:
+		return "pick"
+	}
+}
+
+// findFixupTarget finds the candidate the fixup subject refers to, -1 when none
+// Matches the exact subject first, then a subject prefix, then a hash prefix, like git does
+// With reversed candidates (newest first) the last match is taken to keep the earliest commit
+//
+// findFixupTarget 查找 fixup 标题所指向的候选提交，没有时返回 -1
+// 与 git 一致，先匹配完整标题，再匹配标题前缀，最后匹配哈希前缀
+// 候选提交反序（最新在前）时取最后一个匹配，以保留最早的提交
+func findFixupTarget(subject string, candidates []*object.Commit, newestFirst bool) int {
+	rest, _ := splitFixupSubject(subject)
+	for _, match := range []func(*object.Commit) bool{
+		func(candidate *object.Commit) bool { return commitSubject(candidate.Message) == rest },
+		func(candidate *object.Commit) bool { return strings.HasPrefix(commitSubject(candidate.Message), rest) },
+		func(candidate *object.Commit) bool {
+			return len(rest) >= 4 && strings.HasPrefix(candidate.Hash.String(), rest)
+		},
+	} {
+		found := -1
+		for idx, candidate := range candidates {
+			if match(candidate) {
+				found = idx
+				if !newestFirst {
+					break
+				}
+			}
+		}
+		if found >= 0 {
+			return found
+		}
+	}
+	return -1
+}
+
+// arrangeAutosquashSteps moves each fixup and squash right after its target and the fixups before it
+// Returns the steps and the index of the first target, -1 when nothing is folded
+//
+// arrangeAutosquashSteps 将每个 fixup 和 squash 移到其目标以及之前的 fixup 之后
+// 返回步骤以及第一个目标的索引，没有合入时返回 -1
+func arrangeAutosquashSteps(commits []*object.Commit, report *AutosquashReport) ([]*AutosquashStep, int) {
+	followers := map[int][]int{}
+	targets := make([]int, len(commits))
+	for idx, commitObject := range commits {
+		targets[idx] = -1
+		subject := commitSubject(commitObject.Message)
+		if fixupAction(subject) == "pick" {
+			continue
+		}
+		target := findFixupTarget(subject, commits[:idx], false)
+		if target < 0 {
+			report.Unmatched = append(report.Unmatched, &AutosquashStep{Action: fixupAction(subject), Hash: commitObject.Hash, Subject: subject})
+			continue
+		}
+
+		// A fixup of a fixup folds into the final target
+		// fixup 的 fixup 合入最终目标
+		for targets[target] >= 0 {
+			target = targets[target]
+		}
+		targets[idx] = target
+		followers[target] = append(followers[target], idx)
+	}
+
+	var steps []*AutosquashStep
+	firstIdx := -1
+	for idx, commitObject := range commits {
+		if targets[idx] >= 0 {
+			continue
+		}
+		if len(followers[idx]) > 0 && firstIdx < 0 {
+			firstIdx = len(steps)
+		}
+		steps = append(steps, &AutosquashStep{Action: "pick", Hash: commitObject.Hash, Subject: commitSubject(commitObject.Message)})
+		for _, followerIdx := range followers[idx] {
+			subject := commitSubject(commits[followerIdx].Message)
+			steps = append(steps, &AutosquashStep{Action: fixupAction(subject), Hash: commits[followerIdx].Hash, Subject: subject, Target: commitObject.Hash})
+		}
+	}
+	return steps, firstIdx
+}
+
+// replayAutosquashSteps writes the rearranged commits and returns the new tip
+// Fixup steps fold their change into the pending commit, squash steps also append their message
+//
+// replayAutosquashSteps 写入重新排列后的提交并返回新的顶端
+// fixup 步骤将其更改合入待写入的提交，squash 步骤同时追加其消息
+func replayAutosquashSteps(repo *git.Repository, commits []*object.Commit, steps []*AutosquashStep) (plumbing.Hash, error) {
+	commitMap := map[plumbing.Hash]*object.Commit{}
+	for _, commitObject := range commits {
+		commitMap[commitObject.Hash] = commitObject
+	}
+	parentOf := func(commitObject *object.Commit) (*object.Commit, error) {
+		if len(commitObject.ParentHashes) == 0 {
+			return nil, nil
+		}
+		return repo.CommitObject(commitObject.ParentHashes[0])
+	}
+
+	// Start from the parent of the first target, which keeps its hash
+	// 从第一个目标的父提交开始，该父提交保持哈希不变
+	base, err := parentOf(commitMap[steps[0].Hash])
+	if err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	files, err := commitFiles(base)
+	if err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	var parentHashes []plumbing.Hash
+	if base != nil {
+		parentHashes = []plumbing.Hash{base.Hash}
+	}
+
+	var pending *object.Commit
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		treeHash, err := writeTree(repo, files)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		pending.TreeHash = treeHash
+		newHash, err := writeCommit(repo, pending)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		parentHashes = []plumbing.Hash{newHash}
+		pending = nil
+		return nil
+	}
+
+	for _, step := range steps {
+		commitObject := commitMap[step.Hash]
+		parent, err := parentOf(commitObject)
+		if err != nil {
+			return plumbing.ZeroHash, erero.Wro(err)
+		}
+		if step.Action == "pick" {
+			if err := flush(); err != nil {
+				return plumbing.ZeroHash, erero.Wro(err)
+			}
+			pending = &object.Commit{
+				Author:       commitObject.Author,
+				Committer:    commitObject.Committer,
+				Message:      commitObject.Message,
+				ParentHashes: parentHashes,
+				Encoding:     commitObject.Encoding,
+			}
+		} else if step.Action == "squash" {
+			if _, body, _ := strings.Cut(strings.TrimSpace(commitObject.Message), "\n"); strings.TrimSpace(body) != "" {
+				pending.Message = strings.TrimRight(pending.Message, "\n") + "\n\n" + strings.TrimSpace(body) + "\n"
+			}
+		}
+		conflictPath, err := applyCommitChange(repo, parent, commitObject, files)
+		if err != nil {
+			return plumbing.ZeroHash, erero.Wro(err)
+		}
+		if conflictPath != "" {
+			return plumbing.ZeroHash, erero.Errorf("conflict in %s when replaying %s, run git rebase -i --autosquash instead", conflictPath, step.Hash.String()[:7])
+		}
+	}
+	if err := flush(); err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	return parentHashes[0], nil
+}
@@ -0,0 +1,117 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// commitTestFile writes the file and commits it through GitCommit with the flags
+// commitTestFile 写入文件并通过 GitCommit 使用给定标志提交
+func commitTestFile(t *testing.T, projectRoot string, name string, content string, commitFlags *CommitFlags) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(projectRoot, name)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectRoot, name), []byte(content), 0644))
+	commitFlags.Username = "Test Username"
+	commitFlags.Mailbox = "test@example.com"
+	require.NoError(t, GitCommit(projectRoot, commitFlags))
+}
+
+// readTestFile reads the file content in the tree of the commit
+// readTestFile 读取提交文件树中的文件内容
+func readTestFile(t *testing.T, commitObject *object.Commit, name string) string {
+	file := rese.P1(commitObject.File(name))
+	return rese.V1(file.Contents())
+}
+
+// TestAutosquash validates folding fixup and squash commits created with the flags
+// Tests that intermediate trees include the fixup and the final tree stays the same
+//
+// TestAutosquash 验证合入通过标志创建的 fixup 和 squash 提交
+// 测试中间文件树包含 fixup 的更改，且最终文件树保持不变
+func TestAutosquash(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "a.txt", "line 1\nline 2\nline 3\n", &CommitFlags{Message: "add a"})
+	commitTestFile(t, tempDIR, "b.txt", "b\n", &CommitFlags{Message: "add b"})
+	commitTestFile(t, tempDIR, "a.txt", "line 1\nline 2 fixed\nline 3\n", &CommitFlags{Fixup: "HEAD~1"})
+	commitTestFile(t, tempDIR, "b.txt", "b\nmore b\n", &CommitFlags{Squash: "HEAD~1", Message: "explain more b"})
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	oldHead := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "squash! add b\n\nexplain more b", oldHead.Message)
+
+	report, err := Autosquash(tempDIR, &AutosquashOptions{DryRun: true})
+	require.NoError(t, err)
+	var actions []string
+	for _, step := range report.Steps {
+		actions = append(actions, step.Action+" "+step.Subject)
+	}
+	require.Equal(t, []string{"pick add a", "fixup fixup! add a", "pick add b", "squash squash! add b"}, actions)
+	require.Equal(t, 2, report.FoldCount())
+	require.True(t, report.NewHead.IsZero())
+
+	report, err = Autosquash(tempDIR, &AutosquashOptions{})
+	require.NoError(t, err)
+	newHead := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, report.NewHead, newHead.Hash)
+	require.Equal(t, oldHead.TreeHash, newHead.TreeHash)
+	require.Equal(t, "add b\n\nexplain more b\n", newHead.Message)
+
+	addA := rese.P1(newHead.Parent(0))
+	require.Equal(t, "add a", commitSubject(addA.Message))
+	require.Equal(t, "line 1\nline 2 fixed\nline 3\n", readTestFile(t, addA, "a.txt"))
+	require.Equal(t, "Initial commit", commitSubject(rese.P1(addA.Parent(0)).Message))
+
+	report, err = Autosquash(tempDIR, &AutosquashOptions{})
+	require.NoError(t, err)
+	require.Zero(t, report.FoldCount())
+}
+
+// TestAutosquash_ReplaysAcrossLaterChanges tests a fixup merged into a file changed again after its target
+// TestAutosquash_ReplaysAcrossLaterChanges 测试 fixup 合入到目标之后又被修改的文件中
+func TestAutosquash_ReplaysAcrossLaterChanges(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "a.txt", "alpha\nbeta\ngamma\ndelta\nepsilon\nzeta\neta\ntheta\n", &CommitFlags{Message: "add a"})
+	commitTestFile(t, tempDIR, "a.txt", "alpha\nbeta\ngamma\ndelta\nepsilon\nzeta\neta\ntheta\niota\n", &CommitFlags{Message: "append iota"})
+	commitTestFile(t, tempDIR, "a.txt", "ALPHA\nbeta\ngamma\ndelta\nepsilon\nzeta\neta\ntheta\niota\n", &CommitFlags{Fixup: "HEAD~1"})
+
+	report, err := Autosquash(tempDIR, &AutosquashOptions{})
+	require.NoError(t, err)
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	appendIota := rese.P1(repo.CommitObject(report.NewHead))
+	addA := rese.P1(appendIota.Parent(0))
+	require.Equal(t, "ALPHA\nbeta\ngamma\ndelta\nepsilon\nzeta\neta\ntheta\n", readTestFile(t, addA, "a.txt"))
+	require.Equal(t, "append iota", commitSubject(appendIota.Message))
+}
+
+func TestAutosquash_UnmatchedFixup(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "a.txt", "a\n", &CommitFlags{Message: "fixup! missing target"})
+	report, err := Autosquash(tempDIR, &AutosquashOptions{})
+	require.NoError(t, err)
+	require.Zero(t, report.FoldCount())
+	require.Len(t, report.Unmatched, 1)
+}
+
+func TestFindFixupTarget(t *testing.T) {
+	commits := []*object.Commit{
+		{Message: "add parser\n"},
+		{Message: "add parser tests\n"},
+		{Message: "fixup! add parser\n"},
+	}
+	require.Equal(t, 0, findFixupTarget("fixup! add parser", commits, false))
+	require.Equal(t, 1, findFixupTarget("squash! add parser te", commits, false))
+	require.Equal(t, 2, findFixupTarget("fixup! fixup! add parser", commits, false))
+	require.Equal(t, -1, findFixupTarget("fixup! remove parser", commits, false))
+}
@@ -0,0 +1,429 @@
+// Package commitmate builds changelogs from conventional commits in the history
+// Groups entries by type and scope, names authors with signature config names, and renders Markdown or JSON
+//
+// commitmate 包根据历史中的约定式提交构建变更日志
+// 按类型和范围分组条目，使用签名配置名称标识作者，并渲染为 Markdown 或 JSON
+package commitmate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/tern/zerotern"
+	"golang.org/x/mod/semver"
+)
+
+// DefaultChangelogFile is the changelog file updated under the "Unreleased" heading
+// DefaultChangelogFile 是在 "Unreleased" 标题下更新的变更日志文件
+const DefaultChangelogFile = "CHANGELOG.md"
+
+// DefaultChangelogTemplate renders the changelog sections as Markdown with text/template
+// DefaultChangelogTemplate 使用 text/template 将变更日志各节渲染为 Markdown
+const DefaultChangelogTemplate = `{{if .Breaking}}### Breaking Changes
+
+{{range .Breaking}}- {{if .Scope}}**{{.Scope}}:** {{end}}{{.Description}} ({{.ShortHash}}{{if .Author}}, {{.Author}}{{end}})
+{{end}}
+{{end}}{{range .Groups}}### {{.Title}}
+
+{{range .Entries}}- {{if .Scope}}**{{.Scope}}:** {{end}}{{.Description}} ({{.ShortHash}}{{if .Author}}, {{.Author}}{{end}})
+{{end}}
+{{end}}`
+
+// changelogTypeTitles gives the section title and order of known commit types
+// changelogTypeTitles 给出已知提交类型的章节标题及顺序
+var changelogTypeTitles = []struct{ Type, Title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"style", "Style"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"chore", "Chores"},
+}
+
+// unreleasedHeadingRegexp matches "## Unreleased" and "## [Unreleased]" headings of any level
+// unreleasedHeadingRegexp 匹配任意级别的 "## Unreleased" 和 "## [Unreleased]" 标题
+var unreleasedHeadingRegexp = regexp.MustCompile(`(?i)^(#+)\s*\[?unreleased\]?\s*$`)
+
+// ChangelogEntry is one conventional commit of the changelog
+// ChangelogEntry 是变更日志中的一个约定式提交
+type ChangelogEntry struct {
+	Hash        string                `json:"hash"`              // Commit hash // 提交哈希
+	ShortHash   string                `json:"shortHash"`         // Abbreviated commit hash // 缩写的提交哈希
+	Type        string                `json:"type"`              // Commit type // 提交类型
+	Scope       string                `json:"scope,omitempty"`   // Commit scope // 提交范围
+	Description string                `json:"description"`       // Header description // 标题描述
+	Body        string                `json:"body,omitempty"`    // Commit body // 提交正文
+	Breaking    bool                  `json:"breaking"`          // Marked breaking with "!" or a footer // 通过 "!" 或脚注标记为破坏性
+	Footers     []*ConventionalFooter `json:"footers,omitempty"` // Trailers such as "Refs" // 尾注，例如 "Refs"
+	Author      string                `json:"author"`            // Signature name, else author name // 签名名称，否则为作者名称
+	Mailbox     string                `json:"mailbox"`           // Author mailbox // 作者邮箱
+	When        time.Time             `json:"when"`              // Author date // 作者日期
+}
+
+// ChangelogGroup holds the entries of one commit type, sorted by scope
+// ChangelogGroup 保存一种提交类型的条目，按范围排序
+type ChangelogGroup struct {
+	Type    string            `json:"type"`    // Commit type // 提交类型
+	Title   string            `json:"title"`   // Section title // 章节标题
+	Entries []*ChangelogEntry `json:"entries"` // Entries, newest first within a scope // 条目，同一范围内最新的在前
+}
+
+// Changelog is the grouped conventional commits of a revision range
+// Changelog 是某个修订范围内分组后的约定式提交
+type Changelog struct {
+	From     string            `json:"from,omitempty"` // Excluded start revision // 排除的起始修订
+	To       string            `json:"to"`             // Included end revision // 包含的结束修订
+	Groups   []*ChangelogGroup `json:"groups"`         // Groups in type order // 按类型顺序排列的分组
+	Breaking []*ChangelogEntry `json:"breaking"`       // Breaking entries of each group // 各分组中的破坏性条目
+	Skipped  int               `json:"skipped"`        // Merge and non-conventional commits // 合并提交和非约定式提交
+}
+
+// ChangelogOptions configures BuildChangelog
+// ChangelogOptions 配置 BuildChangelog
+type ChangelogOptions struct {
+	Range  string        // "from..to", blank means from the latest tag to HEAD // "from..to"，为空表示从最新标签到 HEAD
+	Config *CommitConfig // Optional config naming authors by signature // 可选配置，按签名命名作者
+}
+
+// BuildChangelog walks the commits of the range and groups the conventional ones by type and scope
+// A blank "from" means the latest semver tag reachable from the parents of "to", a blank "to" means HEAD
+//
+// BuildChangelog 遍历范围内的提交，并按类型和范围对约定式提交分组
+// "from" 为空表示从 "to" 的父提交可达的最新语义版本标签，"to" 为空表示 HEAD
+func BuildChangelog(projectRoot string, options *ChangelogOptions) (*Changelog, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+
+	fromRevision, toRevision, _ := strings.Cut(options.Range, "..")
+	toRevision = zerotern.VV(toRevision, "HEAD")
+	toHash, err := repo.ResolveRevision(plumbing.Revision(toRevision))
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if fromRevision == "" {
+		if fromRevision, err = findPreviousTag(repo, *toHash); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	var fromHash *plumbing.Hash
+	if fromRevision != "" {
+		if fromHash, err = repo.ResolveRevision(plumbing.Revision(fromRevision)); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+
+	commits, err := listCommitsInRange(repo, fromHash, *toHash)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	changelog := &Changelog{From: fromRevision, To: toRevision}
+	groupMap := map[string]*ChangelogGroup{}
+	for _, commitObject := range commits {
+		conventional, err := ParseConventionalMessage(commitObject.Message)
+		if len(commitObject.ParentHashes) > 1 || err != nil {
+			changelog.Skipped++
+			continue
+		}
+		entry := &ChangelogEntry{
+			Hash:        commitObject.Hash.String(),
+			ShortHash:   commitObject.Hash.String()[:7],
+			Type:        conventional.Type,
+			Scope:       conventional.Scope,
+			Description: conventional.Description,
+			Body:        conventional.Body,
+			Breaking:    conventional.IsBreaking(),
+			Footers:     conventional.Footers,
+			Author:      options.Config.signatureNameOf(commitObject.Author.Name, commitObject.Author.Email),
+			Mailbox:     commitObject.Author.Email,
+			When:        commitObject.Author.When,
+		}
+		group, exists := groupMap[entry.Type]
+		if !exists {
+			group = &ChangelogGroup{Type: entry.Type, Title: changelogTypeTitle(entry.Type)}
+			groupMap[entry.Type] = group
+			changelog.Groups = append(changelog.Groups, group)
+		}
+		group.Entries = append(group.Entries, entry)
+		if entry.Breaking {
+			changelog.Breaking = append(changelog.Breaking, entry)
+		}
+	}
+
+	sort.SliceStable(changelog.Groups, func(i, j int) bool {
+		return changelogTypeOrder(changelog.Groups[i].Type) < changelogTypeOrder(changelog.Groups[j].Type)
+	})
+	for _, group := range changelog.Groups {
+		sort.SliceStable(group.Entries, func(i, j int) bool {
+			return group.Entries[i].Scope < group.Entries[j].Scope
+		})
+	}
+	return changelog, nil
+}
+
+// changelogTypeTitle returns the section title of the type, unknown types are capitalized
+// changelogTypeTitle 返回类型的章节标题，未知类型首字母大写
+func changelogTypeTitle(commitType string) string {
+	for _, item := range changelogTypeTitles {
+		if item.Type == commitType {
+			return item.Title
+		}
+	}
+	return strings.ToUpper(commitType[:1]) + commitType[1:]
+}
+
+// changelogTypeOrder returns the position of the type, unknown types come last
+// changelogTypeOrder 返回类型的位置，未知类型排在最后
+func changelogTypeOrder(commitType string) int {
+	for idx, item := range changelogTypeTitles {
+		if item.Type == commitType {
+			return idx
+		}
+	}
+	return len(changelogTypeTitles)
+}
+
+// signatureNameOf returns the name of the signature with the mailbox, then the username, else the author name
+// A nil config gives the author name
+//
+// signatureNameOf 返回邮箱匹配的签名名称，其次为用户名匹配的签名名称，否则返回作者名称
+// nil 配置返回作者名称
+func (config *CommitConfig) signatureNameOf(username string, mailbox string) string {
+	if config == nil {
+		return username
+	}
+	for _, signature := range config.Signatures {
+		if signature.Name != "" && mailbox != "" && strings.EqualFold(zerotern.VV(signature.Mailbox, signature.Eddress), mailbox) {
+			return signature.Name
+		}
+	}
+	for _, signature := range config.Signatures {
+		if signature.Name != "" && username != "" && signature.Username == username {
+			return signature.Name
+		}
+	}
+	return username
+}
+
+// listCommitsInRange returns the commits reachable from "to" and not from "from", newest first
+// A nil "from" gives the whole history of "to"
+//
+// listCommitsInRange 返回从 "to" 可达而从 "from" 不可达的提交，最新的在前
+// "from" 为 nil 时返回 "to" 的全部历史
+func listCommitsInRange(repo *git.Repository, from *plumbing.Hash, to plumbing.Hash) ([]*object.Commit, error) {
+	excluded := map[plumbing.Hash]bool{}
+	if from != nil {
+		fromCommit, err := repo.CommitObject(*from)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		err = object.NewCommitPreorderIter(fromCommit, nil, nil).ForEach(func(commitObject *object.Commit) error {
+			excluded[commitObject.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	toCommit, err := repo.CommitObject(to)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	var commits []*object.Commit
+	err = object.NewCommitIterCTime(toCommit, excluded, nil).ForEach(func(commitObject *object.Commit) error {
+		commits = append(commits, commitObject)
+		return nil
+	})
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return commits, nil
+}
+
+// findLatestTag returns the highest "prefix" + semver tag on a commit reachable from the head, with its commit
+// The accept function filters versions, nil accepts each, returns blank when no such tag exists
+//
+// findLatestTag 返回从 head 可达的提交上最高的 "prefix" + 语义版本标签及其提交
+// accept 函数过滤版本，nil 接受全部，不存在此类标签时返回空
+func findLatestTag(repo *git.Repository, head plumbing.Hash, prefix string, accept func(version string) bool) (string, plumbing.Hash) {
+	tagged := map[plumbing.Hash][]string{}
+	tags, err := repo.Tags()
+	if err != nil {
+		return "", plumbing.ZeroHash
+	}
+	_ = tags.ForEach(func(reference *plumbing.Reference) error {
+		version, found := strings.CutPrefix(reference.Name().Short(), prefix)
+		if !found || !semver.IsValid(version) || (accept != nil && !accept(version)) {
+			return nil
+		}
+		hash := reference.Hash()
+		if tagObject, err := repo.TagObject(hash); err == nil {
+			hash = tagObject.Target
+		}
+		tagged[hash] = append(tagged[hash], reference.Name().Short())
+		return nil
+	})
+	if len(tagged) == 0 {
+		return "", plumbing.ZeroHash
+	}
+
+	headCommit, err := repo.CommitObject(head)
+	if err != nil {
+		return "", plumbing.ZeroHash
+	}
+	latest, latestHash := "", plumbing.ZeroHash
+	_ = object.NewCommitIterCTime(headCommit, nil, nil).ForEach(func(commitObject *object.Commit) error {
+		for _, name := range tagged[commitObject.Hash] {
+			if latest == "" || semver.Compare(strings.TrimPrefix(name, prefix), strings.TrimPrefix(latest, prefix)) > 0 {
+				latest, latestHash = name, commitObject.Hash
+			}
+		}
+		return nil
+	})
+	return latest, latestHash
+}
+
+// findPreviousTag returns the latest semver tag reachable from the parents of the commit
+// Tags on the commit itself are left out, so "..v1.2.0" lists the changes since the tag before v1.2.0
+//
+// findPreviousTag 返回从该提交的父提交可达的最新语义版本标签
+// 不包括该提交本身的标签，因此 "..v1.2.0" 列出 v1.2.0 之前的标签以来的更改
+func findPreviousTag(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	commitObject, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	latest := ""
+	for _, parentHash := range commitObject.ParentHashes {
+		if name, _ := findLatestTag(repo, parentHash, "", nil); name != "" && (latest == "" || semver.Compare(name, latest) > 0) {
+			latest = name
+		}
+	}
+	return latest, nil
+}
+
+// RenderMarkdown renders the changelog with the text/template, blank means DefaultChangelogTemplate
+// RenderMarkdown 使用 text/template 渲染变更日志，为空表示使用 DefaultChangelogTemplate
+func (c *Changelog) RenderMarkdown(text string) (string, error) {
+	tmpl, err := template.New("changelog").Parse(zerotern.VV(text, DefaultChangelogTemplate))
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, c); err != nil {
+		return "", erero.Wro(err)
+	}
+	return strings.TrimSpace(buf.String()) + "\n", nil
+}
+
+// RenderJSON renders the changelog as indented JSON
+// RenderJSON 将变更日志渲染为缩进的 JSON
+func (c *Changelog) RenderJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return data, nil
+}
+
+// UpdateChangelogFile writes the Markdown under the "Unreleased" heading of the changelog file and stages it
+// The heading is added before the first release heading when missing, the file is created when absent
+//
+// UpdateChangelogFile 将 Markdown 写入变更日志文件的 "Unreleased" 标题下并暂存该文件
+// 缺少该标题时在第一个发布标题前添加，文件不存在时创建
+func UpdateChangelogFile(projectRoot string, name string, markdown string) error {
+	name = zerotern.VV(name, DefaultChangelogFile)
+	content, err := os.ReadFile(filepath.Join(projectRoot, name))
+	if err != nil && !os.IsNotExist(err) {
+		return erero.Wro(err)
+	}
+	updated := replaceUnreleasedSection(string(content), markdown)
+	if err := os.WriteFile(filepath.Join(projectRoot, name), []byte(updated), 0644); err != nil {
+		return erero.Wro(err)
+	}
+
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	if _, err := client.Tree().Add(filepath.ToSlash(name)); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// replaceUnreleasedSection replaces the body of the "Unreleased" section with the Markdown
+// replaceUnreleasedSection 使用 Markdown 替换 "Unreleased" 节的内容
+func replaceUnreleasedSection(content string, markdown string) string {
+	section := "## Unreleased\n\n" + strings.TrimSpace(markdown) + "\n"
+	if strings.TrimSpace(content) == "" {
+		return "# Changelog\n\n" + section
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	start, end := -1, len(lines)
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 {
+			if unreleasedHeadingRegexp.MatchString(trimmed) {
+				start = idx
+				section = strings.TrimSpace(line) + "\n\n" + strings.TrimSpace(markdown) + "\n"
+			}
+			continue
+		}
+		if level := headingLevel(trimmed); level > 0 && level <= headingLevel(strings.TrimSpace(lines[start])) {
+			end = idx
+			break
+		}
+	}
+	if start >= 0 {
+		return strings.Join(lines[:start], "") + section + joinAfterSection(lines[end:])
+	}
+
+	// Insert the section before the first release heading, else after the title
+	// 在第一个发布标题前插入该节，否则插入到标题之后
+	for idx, line := range lines {
+		if headingLevel(strings.TrimSpace(line)) == 2 {
+			return strings.Join(lines[:idx], "") + section + "\n" + strings.Join(lines[idx:], "")
+		}
+	}
+	return strings.TrimRight(content, "\n") + "\n\n" + section
+}
+
+// joinAfterSection joins the lines after the replaced section, keeping one blank line before them
+// joinAfterSection 拼接被替换节之后的行，并在其前保留一个空行
+func joinAfterSection(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(lines, "")
+}
+
+// headingLevel returns the level of the Markdown heading line, 0 when it is not a heading
+// headingLevel 返回 Markdown 标题行的级别，不是标题时返回 0
+func headingLevel(line string) int {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
@@ -0,0 +1,119 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// setupChangelogTestRepo commits a tagged release followed by conventional and plain commits
+// setupChangelogTestRepo 提交一个带标签的发布，随后是约定式提交和普通提交
+func setupChangelogTestRepo(t *testing.T) string {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "a.txt", "1\n", &CommitFlags{Message: "feat: first release"})
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), rese.P1(repo.Head()).Hash())))
+
+	commitTestFile(t, tempDIR, "a.txt", "2\n", &CommitFlags{Message: "fix(parser): handle empty input"})
+	commitTestFile(t, tempDIR, "a.txt", "3\n", &CommitFlags{Message: "update readme"})
+	commitTestFile(t, tempDIR, "a.txt", "4\n", &CommitFlags{Message: "feat(api)!: drop Open\n\nRefs: #7"})
+	commitTestFile(t, tempDIR, "a.txt", "5\n", &CommitFlags{Message: "feat(cli): add changelog"})
+	return tempDIR
+}
+
+// TestBuildChangelog validates grouping commits since the latest tag by type and scope
+// Tests signature names of authors, breaking entries and skipped commits
+//
+// TestBuildChangelog 验证按类型和范围对最新标签以来的提交分组
+// 测试作者的签名名称、破坏性条目和跳过的提交
+func TestBuildChangelog(t *testing.T) {
+	tempDIR := setupChangelogTestRepo(t)
+	config := &CommitConfig{Signatures: []*SignatureConfig{{Name: "tester", Username: "someone", Mailbox: "TEST@example.com"}}}
+
+	changelog, err := BuildChangelog(tempDIR, &ChangelogOptions{Config: config})
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", changelog.From)
+	require.Equal(t, 1, changelog.Skipped)
+	require.Len(t, changelog.Groups, 2)
+	require.Equal(t, "Features", changelog.Groups[0].Title)
+	require.Equal(t, "api", changelog.Groups[0].Entries[0].Scope)
+	require.Equal(t, "#7", changelog.Groups[0].Entries[0].Footers[0].Value)
+	require.Equal(t, "tester", changelog.Groups[1].Entries[0].Author)
+	require.Len(t, changelog.Breaking, 1)
+
+	markdown, err := changelog.RenderMarkdown("")
+	require.NoError(t, err)
+	lines := []string{
+		"### Breaking Changes", "",
+		"- **api:** drop Open (" + changelog.Breaking[0].ShortHash + ", tester)", "",
+		"### Features", "",
+		"- **api:** drop Open (" + changelog.Breaking[0].ShortHash + ", tester)",
+		"- **cli:** add changelog (" + changelog.Groups[0].Entries[1].ShortHash + ", tester)", "",
+		"### Bug Fixes", "",
+		"- **parser:** handle empty input (" + changelog.Groups[1].Entries[0].ShortHash + ", tester)",
+	}
+	require.Equal(t, joinLines(lines), markdown)
+
+	changelog, err = BuildChangelog(tempDIR, &ChangelogOptions{Range: "HEAD~1..HEAD"})
+	require.NoError(t, err)
+	require.Len(t, changelog.Groups, 1)
+	require.Equal(t, "Test Username", changelog.Groups[0].Entries[0].Author)
+	require.Contains(t, string(rese.V1(changelog.RenderJSON())), `"description": "add changelog"`)
+}
+
+// TestBuildChangelog_ToTag validates a blank "from" skips the tags on "to" itself
+// TestBuildChangelog_ToTag 验证 "from" 为空时跳过 "to" 本身的标签
+func TestBuildChangelog_ToTag(t *testing.T) {
+	tempDIR := setupChangelogTestRepo(t)
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.1.0"), rese.P1(repo.Head()).Hash())))
+
+	for _, text := range []string{"..v1.1.0", ""} {
+		changelog, err := BuildChangelog(tempDIR, &ChangelogOptions{Range: text})
+		require.NoError(t, err)
+		require.Equal(t, "v1.0.0", changelog.From, text)
+		require.Len(t, changelog.Groups, 2, text)
+		require.Equal(t, 1, changelog.Skipped, text)
+	}
+
+	changelog, err := BuildChangelog(tempDIR, &ChangelogOptions{Range: "..v1.0.0"})
+	require.NoError(t, err)
+	require.Empty(t, changelog.From)
+	require.Len(t, changelog.Groups, 1)
+}
+
+func TestUpdateChangelogFile(t *testing.T) {
+	tempDIR := setupChangelogTestRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "CHANGELOG.md"), []byte("# Changelog\n\n## Unreleased\n\n- old entry\n\n## v1.0.0\n\n- first\n"), 0644))
+	require.NoError(t, UpdateChangelogFile(tempDIR, "", "### Features\n\n- new entry\n"))
+	content := string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "CHANGELOG.md"))))
+	require.Equal(t, "# Changelog\n\n## Unreleased\n\n### Features\n\n- new entry\n\n## v1.0.0\n\n- first\n", content)
+
+	status := rese.V1(rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree()).Status())
+	require.Equal(t, git.Added, status.File("CHANGELOG.md").Staging)
+}
+
+func TestReplaceUnreleasedSection(t *testing.T) {
+	require.Equal(t, "# Changelog\n\n## Unreleased\n\n- a\n", replaceUnreleasedSection("", "- a\n"))
+	require.Equal(t, "# Changelog\n\n## Unreleased\n\n- a\n\n## v1.0.0\n", replaceUnreleasedSection("# Changelog\n\n## v1.0.0\n", "- a"))
+	require.Equal(t, "# Changelog\n\n## [Unreleased]\n\n- a\n", replaceUnreleasedSection("# Changelog\n\n## [Unreleased]\n\n### Fixes\n- b\n", "- a"))
+	require.Equal(t, "# Changelog\n\n## Unreleased\n\n- a\n", replaceUnreleasedSection("# Changelog\n", "- a"))
+}
+
+// joinLines joins the lines with newlines and ends with a newline
+// joinLines 使用换行符拼接各行并以换行符结尾
+func joinLines(lines []string) string {
+	var content string
+	for _, line := range lines {
+		content += line + "\n"
+	}
+	return content
+}
@@ -0,0 +1,589 @@
+// Package commitmate journals each commit workflow and undoes the last one
+// Records the previous and new HEAD, the index trees and the rewritten files under .git/go-commit/
+// Refs under refs/go-commit/journal/ keep the recorded objects reachable so git gc does not prune them
+// Undo resets the branch and the index, and restores the files as they were before formatting when asked
+//
+// commitmate 包记录每次提交工作流程并撤销最近一次
+// 在 .git/go-commit/ 下记录之前和新的 HEAD、索引文件树以及被重写的文件
+// refs/go-commit/journal/ 下的引用使已记录的对象保持可达，避免被 git gc 清理
+// 撤销会重置分支和索引，并在要求时将文件恢复为格式化之前的内容
+package commitmate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// journalLimit is the count of operations the journal keeps, older ones are dropped
+// journalLimit 是日志保留的操作数量，更早的操作会被丢弃
+const journalLimit = 100
+
+// JournalRefPrefix is the prefix of the refs keeping the objects of each journal entry reachable
+// Entry N has <prefix>N/prev and <prefix>N/new for its heads and <prefix>N/objects for its index trees and blobs
+//
+// JournalRefPrefix 是使每个日志条目的对象保持可达的引用前缀
+// 条目 N 以 <prefix>N/prev 和 <prefix>N/new 指向其 HEAD，以 <prefix>N/objects 指向其索引文件树和数据对象
+const JournalRefPrefix = "refs/go-commit/journal/"
+
+// legacyJournalRef is the single anchor ref of older journals, removed when the journal is saved
+// legacyJournalRef 是旧版日志的单一锚定引用，保存日志时将其移除
+const legacyJournalRef = plumbing.ReferenceName("refs/go-commit/journal")
+
+// JournalFile is one file the workflow rewrote, with blob hashes of its content before and after
+// JournalFile 是工作流程重写的一个文件，包含其之前和之后内容的数据对象哈希
+type JournalFile struct {
+	Path   string      `json:"path"`   // Slash path in the repo // 仓库中的斜杠路径
+	Mode   fs.FileMode `json:"mode"`   // Permission bits before the workflow // 工作流程之前的权限位
+	Before string      `json:"before"` // Blob of the content before the workflow // 工作流程之前内容的数据对象
+	After  string      `json:"after"`  // Blob of the content after the workflow // 工作流程之后内容的数据对象
+}
+
+// JournalEntry is one run of GitCommit recorded in the journal
+// JournalEntry 是日志中记录的一次 GitCommit 运行
+type JournalEntry struct {
+	ID           int            `json:"id"`                     // Sequence number of the operation // 操作的序号
+	Time         time.Time      `json:"time"`                   // When the workflow ended // 工作流程结束的时间
+	Ref          string         `json:"ref"`                    // Branch HEAD points to, or HEAD when detached // HEAD 指向的分支，分离时为 HEAD
+	PreviousHead string         `json:"previousHead,omitempty"` // Commit before the workflow, blank when the branch was unborn // 工作流程之前的提交，分支未诞生时为空
+	NewHead      string         `json:"newHead,omitempty"`      // Commit after the workflow // 工作流程之后的提交
+	IndexBefore  string         `json:"indexBefore"`            // Tree of the index before the workflow // 工作流程之前索引的文件树
+	IndexAfter   string         `json:"indexAfter"`             // Tree of the index after the workflow // 工作流程之后索引的文件树
+	Files        []*JournalFile `json:"files,omitempty"`        // Files the workflow rewrote // 工作流程重写的文件
+	Flags        *CommitFlags   `json:"flags"`                  // Flags of the run // 运行时的标志
+	Error        string         `json:"error,omitempty"`        // Failure kept by NoRollback // 由 NoRollback 保留的失败
+	Undone       bool           `json:"undone,omitempty"`       // Reverted by undo // 已被撤销
+}
+
+// Operation names the kind of the run from its flags
+// Operation 根据标志给出运行的类型
+func (e *JournalEntry) Operation() string {
+	switch {
+	case e.Flags == nil:
+		return "commit"
+	case e.Flags.NoCommit:
+		return "stage"
+	case e.Flags.IsAmend && e.Flags.IsForce:
+		return "amend --force"
+	case e.Flags.IsAmend:
+		return "amend"
+	case e.Flags.SplitBy != "":
+		return "split"
+	case e.Flags.Fixup != "":
+		return "fixup"
+	case e.Flags.Squash != "":
+		return "squash"
+	default:
+		return "commit"
+	}
+}
+
+// String returns one line describing the operation
+// String 返回描述该操作的一行文本
+func (e *JournalEntry) String() string {
+	line := fmt.Sprintf("#%d %s %-13s %s %s -> %s", e.ID, e.Time.Local().Format(time.DateTime), e.Operation(), plumbing.ReferenceName(e.Ref).Short(), shortHash(e.PreviousHead), shortHash(e.NewHead))
+	if len(e.Files) > 0 {
+		line += fmt.Sprintf(" %d file(s) rewritten", len(e.Files))
+	}
+	if e.Error != "" {
+		line += " (failed)"
+	}
+	if e.Undone {
+		line += " [undone]"
+	}
+	return line
+}
+
+// shortHash abbreviates the commit hash, blank means no commit
+// shortHash 缩写提交哈希，空表示没有提交
+func shortHash(hash string) string {
+	if hash == "" {
+		return "(none)"
+	}
+	return hash[:min(len(hash), 7)]
+}
+
+// UndoOptions configures UndoLastOperation
+// UndoOptions 配置 UndoLastOperation
+type UndoOptions struct {
+	RestoreFiles bool // Restore the file contents from before the workflow // 恢复工作流程之前的文件内容
+}
+
+// UndoResult is the undone operation with the files restored and skipped
+// UndoResult 是被撤销的操作以及已恢复和已跳过的文件
+type UndoResult struct {
+	Entry    *JournalEntry // Undone operation // 被撤销的操作
+	Restored []string      // Files written back // 已写回的文件
+	Skipped  []string      // Files edited since the operation, left alone // 操作之后被编辑过而保持不变的文件
+}
+
+// journalPath returns the journal file under the git DIR of the repo
+// journalPath 返回仓库 git 目录下的日志文件
+func journalPath(projectRoot string, repo *git.Repository) string {
+	gitDIR := filepath.Join(projectRoot, ".git")
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		gitDIR = storage.Filesystem().Root()
+	}
+	return filepath.Join(gitDIR, "go-commit", "journal.jsonl")
+}
+
+// loadJournal reads the journal entries, oldest first, a missing journal gives no entries
+// loadJournal 读取日志条目（最早的在前），日志不存在时返回空
+func loadJournal(journalFile string) ([]*JournalEntry, error) {
+	content, err := os.ReadFile(journalFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	var entries []*JournalEntry
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		entry := &JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, erero.Wro(err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, erero.Wro(err)
+	}
+	return entries, nil
+}
+
+// saveJournal anchors the objects of the last journalLimit entries, then writes them replacing the journal file at once
+// saveJournal 锚定最后 journalLimit 个条目的对象，然后写入它们并一次性替换日志文件
+func saveJournal(repo *git.Repository, journalFile string, entries []*JournalEntry) error {
+	if len(entries) > journalLimit {
+		entries = entries[len(entries)-journalLimit:]
+	}
+	var content bytes.Buffer
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	if err := anchorJournal(repo, entries); err != nil {
+		return erero.Wro(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(journalFile), 0755); err != nil {
+		return erero.Wro(err)
+	}
+	tempFile := journalFile + ".tmp"
+	if err := os.WriteFile(tempFile, content.Bytes(), 0644); err != nil {
+		return erero.Wro(err)
+	}
+	if err := os.Rename(tempFile, journalFile); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// journalRefName returns the ref of the entry holding the kind: prev, new or objects
+// journalRefName 返回条目中保存该类型（prev、new 或 objects）的引用
+func journalRefName(id int, kind string) plumbing.ReferenceName {
+	return plumbing.ReferenceName(JournalRefPrefix + strconv.Itoa(id) + "/" + kind)
+}
+
+// anchorJournal points the refs of each entry to its heads and to a tree of its index trees and file blobs
+// Refs of entries already anchored are kept, refs of entries dropped from the journal are removed
+//
+// anchorJournal 将每个条目的引用指向其 HEAD 以及包含其索引文件树和文件数据对象的文件树
+// 已锚定条目的引用保持不变，已从日志中丢弃条目的引用会被移除
+func anchorJournal(repo *git.Repository, entries []*JournalEntry) error {
+	kept := map[plumbing.ReferenceName]bool{}
+	for _, entry := range entries {
+		for kind, head := range map[string]string{"prev": entry.PreviousHead, "new": entry.NewHead} {
+			if head == "" {
+				continue
+			}
+			refName := journalRefName(entry.ID, kind)
+			kept[refName] = true
+			if err := setJournalRef(repo, refName, plumbing.NewHash(head)); err != nil {
+				return erero.Wro(err)
+			}
+		}
+
+		refName := journalRefName(entry.ID, "objects")
+		kept[refName] = true
+		if _, err := repo.Reference(refName, false); err == nil {
+			continue
+		}
+		files := map[string]treeFile{
+			"index-before": {mode: filemode.Dir, hash: plumbing.NewHash(entry.IndexBefore)},
+			"index-after":  {mode: filemode.Dir, hash: plumbing.NewHash(entry.IndexAfter)},
+		}
+		for idx, file := range entry.Files {
+			files["files/"+strconv.Itoa(idx)+"/before"] = treeFile{mode: filemode.Regular, hash: plumbing.NewHash(file.Before)}
+			files["files/"+strconv.Itoa(idx)+"/after"] = treeFile{mode: filemode.Regular, hash: plumbing.NewHash(file.After)}
+		}
+		treeHash, err := writeTree(repo, files)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		if err := setJournalRef(repo, refName, treeHash); err != nil {
+			return erero.Wro(err)
+		}
+	}
+
+	references, err := repo.References()
+	if err != nil {
+		return erero.Wro(err)
+	}
+	var dropped []plumbing.ReferenceName
+	if err := references.ForEach(func(reference *plumbing.Reference) error {
+		name := reference.Name()
+		if name == legacyJournalRef || (strings.HasPrefix(name.String(), JournalRefPrefix) && !kept[name]) {
+			dropped = append(dropped, name)
+		}
+		return nil
+	}); err != nil {
+		return erero.Wro(err)
+	}
+	for _, name := range dropped {
+		if err := repo.Storer.RemoveReference(name); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	return nil
+}
+
+// setJournalRef points the ref to the hash unless it does already
+// A legacy single anchor ref is removed first, it would block the refs below it
+//
+// setJournalRef 将引用指向该哈希，已指向时不做改动
+// 先移除旧版的单一锚定引用，它会阻止在其下创建引用
+func setJournalRef(repo *git.Repository, refName plumbing.ReferenceName, hash plumbing.Hash) error {
+	if reference, err := repo.Reference(refName, false); err == nil && reference.Hash() == hash {
+		return nil
+	}
+	if _, err := repo.Reference(legacyJournalRef, false); err == nil {
+		if err := repo.Storer.RemoveReference(legacyJournalRef); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// ListJournal returns the recorded operations of the repo, oldest first
+// ListJournal 返回仓库中已记录的操作（最早的在前）
+func ListJournal(projectRoot string) ([]*JournalEntry, error) {
+	repo, err := git.PlainOpen(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	entries, err := loadJournal(journalPath(projectRoot, repo))
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return entries, nil
+}
+
+// indexTree stores the entries of the index as a tree and returns its hash
+// indexTree 将索引条目存储为文件树并返回其哈希
+func indexTree(repo *git.Repository, stagedIndex *index.Index) (plumbing.Hash, error) {
+	files := make(map[string]treeFile, len(stagedIndex.Entries))
+	for _, entry := range stagedIndex.Entries {
+		files[entry.Name] = treeFile{mode: entry.Mode, hash: entry.Hash}
+	}
+	return writeTree(repo, files)
+}
+
+// sameIndexEntries reports whether the two indexes stage the same paths, modes and blobs
+// sameIndexEntries 判断两个索引是否暂存了相同的路径、模式和数据对象
+func sameIndexEntries(a *index.Index, b *index.Index) bool {
+	return slices.EqualFunc(a.Entries, b.Entries, func(x *index.Entry, y *index.Entry) bool {
+		return x.Name == y.Name && x.Stage == y.Stage && x.Mode == y.Mode && x.Hash == y.Hash
+	})
+}
+
+// recordJournal appends the run to the journal, runs changing nothing are not recorded
+// recordJournal 将运行追加到日志，未改变任何内容的运行不记录
+func (s *commitSnapshot) recordJournal(commitFlags *CommitFlags, runErr error) error {
+	entry := &JournalEntry{Time: time.Now(), Ref: s.refName.String(), Flags: commitFlags}
+	if !s.refHash.IsZero() {
+		entry.PreviousHead = s.refHash.String()
+	}
+	reference, err := s.repo.Reference(s.refName, false)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return erero.Wro(err)
+	}
+	if err == nil {
+		entry.NewHead = reference.Hash().String()
+	}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+
+	// Compare before writing objects, runs changing nothing write none
+	// 写入对象之前先比较，未改变任何内容的运行不写入对象
+	stagedIndex, err := s.repo.Storer.Index()
+	if err != nil {
+		return erero.Wro(err)
+	}
+	sameIndex := sameIndexEntries(s.index, stagedIndex)
+	var changedNames []string
+	changedContents := map[string][]byte{}
+	for name, file := range s.files {
+		content, err := os.ReadFile(filepath.Join(s.projectRoot, filepath.FromSlash(name)))
+		if err != nil || bytes.Equal(content, file.content) {
+			continue
+		}
+		changedNames = append(changedNames, name)
+		changedContents[name] = content
+	}
+	if entry.PreviousHead == entry.NewHead && sameIndex && len(changedNames) == 0 {
+		zaplog.SUG.Debugln("nothing changed, not journaled")
+		return nil
+	}
+
+	indexBefore, err := indexTree(s.repo, s.index)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	indexAfter := indexBefore
+	if !sameIndex {
+		if indexAfter, err = indexTree(s.repo, stagedIndex); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	entry.IndexBefore, entry.IndexAfter = indexBefore.String(), indexAfter.String()
+
+	sort.Strings(changedNames)
+	for _, name := range changedNames {
+		file := s.files[name]
+		before, err := writeBlob(s.repo, string(file.content))
+		if err != nil {
+			return erero.Wro(err)
+		}
+		after, err := writeBlob(s.repo, string(changedContents[name]))
+		if err != nil {
+			return erero.Wro(err)
+		}
+		entry.Files = append(entry.Files, &JournalFile{Path: name, Mode: file.mode, Before: before.String(), After: after.String()})
+	}
+
+	journalFile := journalPath(s.projectRoot, s.repo)
+	entries, err := loadJournal(journalFile)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	if err := saveJournal(s.repo, journalFile, append(entries, entry)); err != nil {
+		return erero.Wro(err)
+	}
+	zaplog.SUG.Debugln("journaled operation", entry.String())
+	return nil
+}
+
+// UndoLastOperation reverts the last operation not undone yet: the branch and the index go back to before it
+// Refuses when the branch or the index moved since, files edited since are skipped when restoring
+//
+// UndoLastOperation 撤销最近一次尚未撤销的操作：分支和索引回到操作之前
+// 如果之后分支或索引发生了变化则拒绝，恢复时跳过之后被编辑过的文件
+func UndoLastOperation(projectRoot string, options *UndoOptions) (*UndoResult, error) {
+	repo, err := git.PlainOpen(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	journalFile := journalPath(projectRoot, repo)
+	entries, err := loadJournal(journalFile)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	var entry *JournalEntry
+	for idx := len(entries) - 1; idx >= 0 && entry == nil; idx-- {
+		if !entries[idx].Undone {
+			entry = entries[idx]
+		}
+	}
+	if entry == nil {
+		return nil, erero.New("nothing to undo")
+	}
+
+	// The branch and the index must be as the operation left them
+	// 分支和索引必须保持操作结束时的状态
+	refName := plumbing.ReferenceName(entry.Ref)
+	reference, err := repo.Reference(refName, false)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, erero.Wro(err)
+	}
+	var currentHead string
+	if err == nil {
+		currentHead = reference.Hash().String()
+	}
+	if currentHead != entry.NewHead {
+		return nil, erero.Errorf("%s moved from %s to %s since operation #%d, refusing to undo", refName.Short(), shortHash(entry.NewHead), shortHash(currentHead), entry.ID)
+	}
+	stagedIndex, err := repo.Storer.Index()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	currentIndex, err := indexTree(repo, stagedIndex)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if currentIndex.String() != entry.IndexAfter {
+		return nil, erero.Errorf("index changed since operation #%d, refusing to undo", entry.ID)
+	}
+
+	if err := checkJournalObjects(repo, entry, options != nil && options.RestoreFiles); err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	result := &UndoResult{Entry: entry}
+	if options != nil && options.RestoreFiles {
+		if result.Restored, result.Skipped, err = restoreJournalFiles(projectRoot, repo, entry.Files); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	if err := resetIndex(repo, stagedIndex, plumbing.NewHash(entry.IndexBefore)); err != nil {
+		return nil, erero.Wro(err)
+	}
+	if entry.NewHead != entry.PreviousHead {
+		if reference != nil {
+			if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName("ORIG_HEAD"), reference.Hash())); err != nil {
+				return nil, erero.Wro(err)
+			}
+		}
+		if entry.PreviousHead == "" {
+			err = repo.Storer.RemoveReference(refName)
+		} else {
+			err = repo.Storer.CheckAndSetReference(plumbing.NewHashReference(refName, plumbing.NewHash(entry.PreviousHead)), reference)
+		}
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+
+	entry.Undone = true
+	if err := saveJournal(repo, journalFile, entries); err != nil {
+		return nil, erero.Wro(err)
+	}
+	return result, nil
+}
+
+// checkJournalObjects checks the objects the undo reads exist before anything changes
+// checkJournalObjects 在任何内容改变之前检查撤销所读取的对象是否存在
+func checkJournalObjects(repo *git.Repository, entry *JournalEntry, restoreFiles bool) error {
+	missing := func(kind string, hash string) error {
+		return erero.Errorf("%s %s of operation #%d is missing, it may have been pruned by git gc", kind, shortHash(hash), entry.ID)
+	}
+	if _, err := repo.TreeObject(plumbing.NewHash(entry.IndexBefore)); err != nil {
+		return missing("index tree", entry.IndexBefore)
+	}
+	if entry.PreviousHead != "" {
+		if _, err := repo.CommitObject(plumbing.NewHash(entry.PreviousHead)); err != nil {
+			return missing("commit", entry.PreviousHead)
+		}
+	}
+	if restoreFiles {
+		for _, file := range entry.Files {
+			for _, hash := range []string{file.Before, file.After} {
+				if _, err := repo.BlobObject(plumbing.NewHash(hash)); err != nil {
+					return missing("blob of "+file.Path, hash)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// restoreJournalFiles writes back the content before the workflow of the files still holding the content after it
+// restoreJournalFiles 将仍为工作流程之后内容的文件写回工作流程之前的内容
+func restoreJournalFiles(projectRoot string, repo *git.Repository, files []*JournalFile) ([]string, []string, error) {
+	var restored, skipped []string
+	for _, file := range files {
+		absPath := filepath.Join(projectRoot, filepath.FromSlash(file.Path))
+		content, err := os.ReadFile(absPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, erero.Wro(err)
+		}
+		after, err := readBlob(repo, plumbing.NewHash(file.After))
+		if err != nil {
+			return nil, nil, erero.Wro(err)
+		}
+		if string(content) != after {
+			zaplog.SUG.Warnln("file edited since the operation, not restored:", file.Path)
+			skipped = append(skipped, file.Path)
+			continue
+		}
+		before, err := readBlob(repo, plumbing.NewHash(file.Before))
+		if err != nil {
+			return nil, nil, erero.Wro(err)
+		}
+		if err := os.WriteFile(absPath, []byte(before), file.Mode); err != nil {
+			return nil, nil, erero.Wro(err)
+		}
+		if err := os.Chmod(absPath, file.Mode); err != nil {
+			return nil, nil, erero.Wro(err)
+		}
+		restored = append(restored, file.Path)
+	}
+	return restored, skipped, nil
+}
+
+// resetIndex replaces the index with the entries of the tree, entries staying the same keep their stat info
+// resetIndex 用文件树的条目替换索引，未变化的条目保留其状态信息
+func resetIndex(repo *git.Repository, stagedIndex *index.Index, treeHash plumbing.Hash) error {
+	tree, err := repo.TreeObject(treeHash)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	files, err := flattenTree(tree)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	existing := make(map[string]*index.Entry, len(stagedIndex.Entries))
+	for _, entry := range stagedIndex.Entries {
+		existing[entry.Name] = entry
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	newIndex := &index.Index{Version: stagedIndex.Version}
+	for _, name := range names {
+		file := files[name]
+		if entry, ok := existing[name]; ok && entry.Hash == file.hash && entry.Mode == file.mode {
+			newIndex.Entries = append(newIndex.Entries, entry)
+			continue
+		}
+		newIndex.Entries = append(newIndex.Entries, &index.Entry{Name: name, Mode: file.mode, Hash: file.hash})
+	}
+	return repo.Storer.SetIndex(newIndex)
+}
@@ -0,0 +1,206 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/osexec"
+	"github.com/yyle88/rese"
+)
+
+// TestUndoLastOperation_Commit validates undoing a formatting commit resets HEAD and the index and restores the files
+// TestUndoLastOperation_Commit 验证撤销格式化提交会重置 HEAD 和索引并恢复文件
+func TestUndoLastOperation_Commit(t *testing.T) {
+	tempDIR, status := setupRollbackRepo(t)
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true}
+	require.NoError(t, GitCommit(tempDIR, commitFlags))
+	require.NotEqual(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+
+	entries := rese.V1(ListJournal(tempDIR))
+	entry := entries[len(entries)-1]
+	require.Equal(t, "commit", entry.Operation())
+	require.NotEqual(t, entry.PreviousHead, entry.NewHead)
+	require.Len(t, entry.Files, 1)
+	require.Equal(t, "demo.go", entry.Files[0].Path)
+
+	result := rese.P1(UndoLastOperation(tempDIR, &UndoOptions{RestoreFiles: true}))
+	require.Equal(t, entry.ID, result.Entry.ID)
+	require.Equal(t, []string{"demo.go"}, result.Restored)
+	require.Empty(t, result.Skipped)
+	requireRolledBack(t, tempDIR, status, "add legacy")
+
+	entries = rese.V1(ListJournal(tempDIR))
+	require.True(t, entries[len(entries)-1].Undone)
+	require.Contains(t, entries[len(entries)-1].String(), "[undone]")
+}
+
+// TestUndoLastOperation_ForceAmend validates undoing a forced amend brings back the amended commit
+// TestUndoLastOperation_ForceAmend 验证撤销强制 amend 会恢复被 amend 的提交
+func TestUndoLastOperation_ForceAmend(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	defer cleanup()
+
+	commitTestFile(t, tempDIR, "demo.go", legacySource, &CommitFlags{Message: "add legacy"})
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headHash := rese.P1(repo.Head()).Hash()
+
+	commitTestFile(t, tempDIR, "demo.go", legacyChange, &CommitFlags{Message: "amended", IsAmend: true, IsForce: true})
+	require.NotEqual(t, headHash, rese.P1(repo.Head()).Hash())
+
+	result := rese.P1(UndoLastOperation(tempDIR, &UndoOptions{}))
+	require.Equal(t, "amend --force", result.Entry.Operation())
+	require.Equal(t, headHash, rese.P1(repo.Head()).Hash())
+
+	// Without restoring files, the amended change stays in the working tree as an unstaged edit
+	// 不恢复文件时，amend 的更改作为未暂存的编辑保留在工作树中
+	require.Equal(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+	status := rese.V1(rese.P1(repo.Worktree()).Status())
+	require.False(t, hasStagedChanges(status))
+	require.False(t, status.IsClean())
+
+	// The operation before is undone next, back to the initial commit
+	// 接下来撤销之前的操作，回到初始提交
+	result = rese.P1(UndoLastOperation(tempDIR, nil))
+	require.Equal(t, "commit", result.Entry.Operation())
+	require.Equal(t, "Initial commit\n", rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash())).Message)
+
+	_, err := UndoLastOperation(tempDIR, nil)
+	require.ErrorContains(t, err, "nothing to undo")
+}
+
+// TestUndoLastOperation_Refuse validates undo refuses when the branch moved and skips files edited since
+// TestUndoLastOperation_Refuse 验证分支移动后拒绝撤销，并跳过之后被编辑过的文件
+func TestUndoLastOperation_Refuse(t *testing.T) {
+	tempDIR, _ := setupRollbackRepo(t)
+
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{FormatGo: true, NoCommit: true}))
+	entries := rese.V1(ListJournal(tempDIR))
+	require.Equal(t, "stage", entries[len(entries)-1].Operation())
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.go"), []byte(legacySource), 0644))
+	result := rese.P1(UndoLastOperation(tempDIR, &UndoOptions{RestoreFiles: true}))
+	require.Equal(t, []string{"demo.go"}, result.Skipped)
+	require.Equal(t, legacySource, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+
+	// The commit of the setup is the last operation left, a commit made outside go-commit moves the branch
+	// 设置中的提交是剩下的最后一个操作，在 go-commit 之外创建的提交会移动分支
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.go"), []byte(legacyChange), 0644))
+	worktree := rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree())
+	rese.V1(worktree.Add("demo.go"))
+	rese.V1(worktree.Commit("outside", &git.CommitOptions{Author: &object.Signature{Name: "Test Username", Email: "test@example.com"}}))
+	_, err := UndoLastOperation(tempDIR, nil)
+	require.ErrorContains(t, err, "refusing to undo")
+}
+
+// listJournalRefs returns the sorted refs under JournalRefPrefix
+// listJournalRefs 返回 JournalRefPrefix 下的引用（已排序）
+func listJournalRefs(t *testing.T, repo *git.Repository) []plumbing.ReferenceName {
+	var refNames []plumbing.ReferenceName
+	references := rese.V1(repo.References())
+	require.NoError(t, references.ForEach(func(reference *plumbing.Reference) error {
+		if strings.HasPrefix(reference.Name().String(), JournalRefPrefix) {
+			refNames = append(refNames, reference.Name())
+		}
+		return nil
+	}))
+	slices.Sort(refNames)
+	return refNames
+}
+
+// pruneTestRepo expires the reflogs and prunes each unreachable object with the git CLI
+// pruneTestRepo 使用 git 命令行使引用日志过期并清理所有不可达的对象
+func pruneTestRepo(t *testing.T, projectRoot string) {
+	rese.V1(osexec.NewExecConfig().WithPath(projectRoot).Exec("git", "reflog", "expire", "--expire=now", "--all"))
+	rese.V1(osexec.NewExecConfig().WithPath(projectRoot).Exec("git", "gc", "--prune=now", "--quiet"))
+}
+
+// TestUndoLastOperation_AfterGC validates the journal ref keeps the objects of the undo through git gc
+// Tests that the amended commit, the index tree and the blobs survive, and that a pruned journal fails clearly
+//
+// TestUndoLastOperation_AfterGC 验证日志引用使撤销所需的对象在 git gc 之后仍然保留
+// 测试被 amend 的提交、索引文件树和数据对象得以保留，且被清理的日志会明确报错
+func TestUndoLastOperation_AfterGC(t *testing.T) {
+	tempDIR, _ := setupRollbackRepo(t)
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headHash := rese.P1(repo.Head()).Hash()
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "amended", IsAmend: true, IsForce: true, FormatGo: true}
+	require.NoError(t, GitCommit(tempDIR, commitFlags))
+	require.NotEqual(t, headHash, rese.P1(repo.Head()).Hash())
+	pruneTestRepo(t, tempDIR)
+
+	result := rese.P1(UndoLastOperation(tempDIR, &UndoOptions{RestoreFiles: true}))
+	require.Equal(t, []string{"demo.go"}, result.Restored)
+	repo = rese.P1(git.PlainOpen(tempDIR))
+	require.Equal(t, headHash, rese.P1(repo.Head()).Hash())
+	require.Equal(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+
+	// Without the journal ref, gc prunes the objects and undo reports them missing
+	// 没有日志引用时，gc 会清理这些对象，撤销报告它们缺失
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.go"), []byte(legacySource+"\nfunc  Extra(){}\n"), 0644))
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "amended again", IsAmend: true, IsForce: true, FormatGo: true}))
+	for _, refName := range listJournalRefs(t, repo) {
+		require.NoError(t, repo.Storer.RemoveReference(refName))
+	}
+	pruneTestRepo(t, tempDIR)
+	_, err := UndoLastOperation(tempDIR, &UndoOptions{RestoreFiles: true})
+	require.ErrorContains(t, err, "pruned by git gc")
+	require.NotEqual(t, headHash, rese.P1(rese.P1(git.PlainOpen(tempDIR)).Head()).Hash())
+}
+
+// TestRecordJournal_Refs validates each entry gets its own refs, dropped entries lose them and no-op runs write nothing
+// TestRecordJournal_Refs 验证每个条目拥有自己的引用，被丢弃的条目失去引用，且无操作的运行不写入任何内容
+func TestRecordJournal_Refs(t *testing.T) {
+	tempDIR, _ := setupRollbackRepo(t)
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true}))
+	repo := rese.P1(git.PlainOpen(tempDIR))
+
+	entries := rese.V1(ListJournal(tempDIR))
+	require.Len(t, entries, 2)
+	first, last := entries[0], entries[1]
+	require.Equal(t, []plumbing.ReferenceName{
+		journalRefName(first.ID, "new"),
+		journalRefName(first.ID, "objects"),
+		journalRefName(first.ID, "prev"),
+		journalRefName(last.ID, "new"),
+		journalRefName(last.ID, "objects"),
+		journalRefName(last.ID, "prev"),
+	}, listJournalRefs(t, repo))
+	require.Equal(t, last.NewHead, rese.P1(repo.Reference(journalRefName(last.ID, "new"), false)).Hash().String())
+	require.Equal(t, last.PreviousHead, rese.P1(repo.Reference(journalRefName(last.ID, "prev"), false)).Hash().String())
+	objectsTree := rese.P1(repo.TreeObject(rese.P1(repo.Reference(journalRefName(last.ID, "objects"), false)).Hash()))
+	require.Equal(t, last.IndexBefore, rese.P1(objectsTree.FindEntry("index-before")).Hash.String())
+
+	// A run changing nothing writes no objects and keeps the journal
+	// 未改变任何内容的运行不写入对象，并保持日志不变
+	countObjects := func() int {
+		var count int
+		objects := rese.V1(repo.Objects())
+		require.NoError(t, objects.ForEach(func(object.Object) error {
+			count++
+			return nil
+		}))
+		return count
+	}
+	objectCount := countObjects()
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "nothing"}))
+	require.Equal(t, objectCount, countObjects())
+	require.Len(t, rese.V1(ListJournal(tempDIR)), 2)
+
+	// Entries dropped from the journal lose their refs
+	// 从日志中丢弃的条目失去其引用
+	require.NoError(t, saveJournal(repo, journalPath(tempDIR, repo), entries[1:]))
+	require.Equal(t, []plumbing.ReferenceName{
+		journalRefName(last.ID, "new"),
+		journalRefName(last.ID, "objects"),
+		journalRefName(last.ID, "prev"),
+	}, listJournalRefs(t, repo))
+}
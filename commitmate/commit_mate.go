@@ -10,24 +10,20 @@
 package commitmate
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
+	"context"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/go-mate/go-commit/internal/utils"
 	"github.com/go-xlan/gogit"
 	"github.com/go-xlan/gogit/gogitassist"
-	"github.com/go-xlan/gogit/gogitchange"
 	"github.com/yyle88/erero"
-	"github.com/yyle88/formatgo"
-	"github.com/yyle88/must"
 	"github.com/yyle88/neatjson/neatjsons"
 	"github.com/yyle88/osexistpath/osmustexist"
 	"github.com/yyle88/rese"
 	"github.com/yyle88/tern/zerotern"
 	"github.com/yyle88/zaplog"
-	"go.uber.org/zap"
 )
 
 // CommitFlags represents the configuration in commit operations
@@ -38,15 +34,28 @@ import (
 // 包含允许自定义提交行为的选项
 // 支持 amend 模式、强制操作和选择性 Go 格式化
 type CommitFlags struct {
-	Username string // Git account username // Git 账户用户名
-	Message  string // Commit message content // 提交消息内容
-	IsAmend  bool   // Enable amend mode on previous commit // 启用对上一次提交的 amend 模式
-	IsForce  bool   // Force amend even if pushed to remote // 即使推送到远程也强制 amend
-	Mailbox  string // Git account mailbox address (preferred) // Git 账户邮箱地址（优先）
-	Eddress  string // Git account mailbox address (fallback) // Git 账户邮箱地址（备选）
-	NoCommit bool   // Stage changes without committing // 仅暂存更改而不提交
-	FormatGo bool   // Format changed Go files before commit // 提交前格式化已改变的 Go 文件
-	AutoSign bool   // Use Git config as fallback // 使用 Git 配置作为备选
+	Username      string // Git account username // Git 账户用户名
+	Message       string // Commit message content // 提交消息内容
+	IsAmend       bool   // Enable amend mode on previous commit // 启用对上一次提交的 amend 模式
+	IsForce       bool   // Force amend even if pushed to remote // 即使推送到远程也强制 amend
+	Mailbox       string // Git account mailbox address (preferred) // Git 账户邮箱地址（优先）
+	Eddress       string // Git account mailbox address (fallback) // Git 账户邮箱地址（备选）
+	NoCommit      bool   // Stage changes without committing // 仅暂存更改而不提交
+	FormatGo      bool   // Format changed Go files before commit // 提交前格式化已改变的 Go 文件
+	AutoSign      bool   // Use Git config as fallback // 使用 Git 配置作为备选
+	Fixup         string // Revision the commit becomes a "fixup!" of // 提交作为其 "fixup!" 的目标修订
+	Squash        string // Revision the commit becomes a "squash!" of // 提交作为其 "squash!" 的目标修订
+	Suggest       bool   // Suggest the message from staged changes when blank // 消息为空时根据已暂存的更改建议消息
+	Conventional  bool   // Require conventional commit messages // 要求约定式提交消息
+	SplitBy       string // Split into one commit per package, module or dir // 按 package、module 或 dir 拆分为多个提交
+	SplitMsg      string // Message template of split commits // 拆分提交的消息模板
+	StagedOnly    bool   // Commit the index as staged, unstaged edits stay out // 按暂存状态提交索引，未暂存的编辑不进入提交
+	FormatScope   string // Format whole files or just the changed hunks // 格式化整个文件或仅格式化已更改的块
+	Jobs          int    // Go files formatted at once, 0 means the CPU count // 同时格式化的 Go 文件数，0 表示 CPU 数量
+	NormalizeText bool   // Normalize whitespace, line endings and BOMs of changed text files // 规范化已改变文本文件的空白、行尾和 BOM
+	NoRollback    bool   // Keep the changes of a failed workflow instead of restoring them // 保留失败工作流程的更改而不恢复
+
+	ModulePolicy *ModulePolicy // Checks of the staged go.mod and go.work files from the config // 来自配置的已暂存 go.mod 和 go.work 文件检查
 }
 
 // ValidateFlags performs basic validation on commit flags and returns warnings
@@ -71,6 +80,38 @@ func (f *CommitFlags) ValidateFlags() []string {
 		warnings = append(warnings, "commit message provided but no-commit flag is set")
 	}
 
+	// Check whether fixup and squash are combined with each other or with amend
+	// 检查 fixup 和 squash 是否相互组合或与 amend 组合
+	if f.Fixup != "" && f.Squash != "" {
+		warnings = append(warnings, "fixup and squash both set - fixup takes effect")
+	}
+	if f.IsAmend && (f.Fixup != "" || f.Squash != "") {
+		warnings = append(warnings, "fixup or squash set with amend - amend takes effect")
+	}
+
+	// Check whether split-by is combined with amend, fixup or squash
+	// 检查 split-by 是否与 amend、fixup 或 squash 组合
+	if f.SplitBy != "" {
+		if err := checkSplitMode(f.SplitBy); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		if f.IsAmend {
+			warnings = append(warnings, "split-by set with amend - amend takes effect")
+		} else if f.Fixup != "" || f.Squash != "" {
+			warnings = append(warnings, "split-by set with fixup or squash - split-by takes effect")
+		}
+	}
+
+	// Check whether the format scope is known and takes effect
+	// 检查格式化范围是否已知且生效
+	if f.FormatScope != "" {
+		if err := checkFormatScope(f.FormatScope); err != nil {
+			warnings = append(warnings, err.Error())
+		} else if !f.FormatGo {
+			warnings = append(warnings, "format-scope set but format-go is disabled - format-scope has no effect")
+		}
+	}
+
 	// Check whether authentication info is missing (when not using AutoSign)
 	// 检查缺失的身份验证信息（当不使用 AutoSign 时）
 	if !f.AutoSign && f.Username == "" && f.Mailbox == "" && f.Eddress == "" {
@@ -83,11 +124,24 @@ func (f *CommitFlags) ValidateFlags() []string {
 // GitCommit performs the complete commit workflow with selective Go code formatting
 // Stages all changes, formats Go files when needed, and creates commits as requested
 // Returns error if some step in the commit process fails
+// Changed files, the index and the branch are restored when it fails, unless NoRollback is set
+// Each run changing something is journaled under .git/go-commit/ so UndoLastOperation can revert it
 //
 // 执行完整的提交工作流程，可选的 Go 代码格式化
 // 暂存所有更改，可选格式化 Go 文件，并创建或 amend 提交
 // 如果提交过程中的某个步骤失败则返回错误
+// 失败时恢复已改变的文件、索引和分支，除非设置了 NoRollback
+// 每次改变了内容的运行都记录在 .git/go-commit/ 下，以便 UndoLastOperation 撤销
 func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
+	return GitCommitContext(context.Background(), projectRoot, commitFlags)
+}
+
+// GitCommitContext performs GitCommit and stops at the next checkpoint once the context is canceled
+// A canceled run is rolled back like a failed one, the caller decides how signals cancel the context
+//
+// GitCommitContext 执行 GitCommit，并在上下文取消后于下一个检查点停止
+// 被取消的运行与失败的运行一样回滚，由调用方决定信号如何取消上下文
+func GitCommitContext(ctx context.Context, projectRoot string, commitFlags *CommitFlags) error {
 	// Log project context and commit configuration
 	// 记录项目上下文和提交配置
 	zaplog.SUG.Debugln(projectRoot, neatjsons.S(commitFlags))
@@ -107,31 +161,92 @@ func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
 	}
 	zaplog.SUG.Debugln(neatjsons.S(status))
 
-	// Stage changes before commit
-	// 为提交暂存所有更改
+	// Snapshot what the workflow may mutate before the first change
+	// 在第一次修改之前对工作流程可能修改的内容进行快照
+	snapshot, err := takeCommitSnapshot(projectRoot, client.Repo(), status)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	if commitFlags.NoRollback {
+		err = gitCommit(ctx, projectRoot, client, commitFlags, nil)
+	} else {
+		err = snapshot.run(func() error {
+			return gitCommit(ctx, projectRoot, client, commitFlags, snapshot)
+		})
+	}
+
+	// Journal the run to undo it later, failures are journaled when NoRollback keeps their changes
+	// 记录本次运行以便之后撤销，当 NoRollback 保留失败的更改时也记录失败
+	if err == nil || commitFlags.NoRollback {
+		if journalErr := snapshot.recordJournal(commitFlags, err); journalErr != nil {
+			zaplog.SUG.Warnln("journal the operation failed:", journalErr)
+		}
+	}
+	if err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// stageAll adds all changes and stores LF blobs of the files git keeps as LF
+// stageAll 添加所有更改，并为 git 以 LF 保存的文件存储 LF 数据对象
+func stageAll(projectRoot string, client *gogit.Client) error {
 	if err := client.AddAll(); err != nil {
 		return erero.Wro(err)
 	}
+	if err := cleanStagedLineEndings(projectRoot, client.Repo()); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// gitCommit runs the steps of GitCommit, stopping at the checkpoints once the context is canceled
+// gitCommit 运行 GitCommit 的各个步骤，上下文取消后在检查点处停止
+func gitCommit(ctx context.Context, projectRoot string, client *gogit.Client, commitFlags *CommitFlags, snapshot *commitSnapshot) error {
+	var err error
+
+	// Stage changes before commit (staged-only keeps the index as the user staged it)
+	// 为提交暂存所有更改（staged-only 保持用户暂存的索引）
+	if !commitFlags.StagedOnly {
+		if err := stageAll(projectRoot, client); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	if err := checkpoint(ctx, StepStaged); err != nil {
+		return erero.Wro(err)
+	}
 
 	// Check staged changes
 	// 检查已暂存的更改
-	status = rese.V1(client.Status())
+	status := rese.V1(client.Status())
 	zaplog.SUG.Debugln(neatjsons.S(status))
 
 	// Format Go files if requested
 	// 如果请求则格式化 Go 文件
-	if commitFlags.FormatGo {
+	if commitFlags.FormatGo && commitFlags.StagedOnly {
+		zaplog.SUG.Debugln("format staged go files")
+
+		// Format the staged blobs, the working tree copies follow when safe
+		// 格式化已暂存的数据对象，工作树副本在安全时跟随
+		results, err := FormatStagedGoFiles(projectRoot, client, commitFlags.GetFormatOptions())
+		if err != nil {
+			return erero.Wro(err)
+		}
+		for _, result := range results {
+			zaplog.SUG.Infoln("formatted staged:", result.Path, "working tree:", result.Worktree)
+		}
+	} else if commitFlags.FormatGo {
 		zaplog.SUG.Debugln("format changed go files")
 
 		// Format changed Go files
 		// 对已改变的文件应用 Go 格式化
-		if err := FormatChangedGoFiles(projectRoot, client, DefaultAllowFormat); err != nil {
+		if err := FormatChangedGoFilesWithOptions(projectRoot, client, commitFlags.GetFormatOptions()); err != nil {
 			return erero.Wro(err)
 		}
 
 		// Re-stage files when formatting done
 		// 格式化完成后重新暂存文件
-		if err := client.AddAll(); err != nil {
+		if err := stageAll(projectRoot, client); err != nil {
 			return erero.Wro(err)
 		}
 
@@ -140,6 +255,34 @@ func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
 		status = rese.V1(client.Status())
 		zaplog.SUG.Debugln(neatjsons.S(status))
 	}
+	if err := checkpoint(ctx, StepFormatted); err != nil {
+		return erero.Wro(err)
+	}
+
+	// Normalize changed text files if requested, staged-only rewrites the staged blobs
+	// 如果请求则规范化已改变的文本文件，staged-only 重写已暂存的数据对象
+	if commitFlags.NormalizeText && commitFlags.StagedOnly {
+		results, err := NormalizeStagedTextFiles(projectRoot, client)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		for _, result := range results {
+			zaplog.SUG.Infoln("normalized staged:", result.Path, "working tree:", result.Worktree)
+		}
+	} else if commitFlags.NormalizeText {
+		if _, err := NormalizeChangedTextFiles(projectRoot, client); err != nil {
+			return erero.Wro(err)
+		}
+		if err := stageAll(projectRoot, client); err != nil {
+			return erero.Wro(err)
+		}
+		status = rese.V1(client.Status())
+		zaplog.SUG.Debugln(neatjsons.S(status))
+	}
+
+	if err := checkpoint(ctx, StepNormalized); err != nil {
+		return erero.Wro(err)
+	}
 
 	// Prepare commit information from flags
 	// 从标志准备提交信息
@@ -161,7 +304,7 @@ func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
 
 	// Exit when no changes to commit
 	// 如果没有更改要提交则提前退出
-	if status = rese.V1(client.Status()); len(status) == 0 {
+	if status = rese.V1(client.Status()); !hasStagedChanges(status) {
 		canContinue := commitFlags.IsAmend && detectMetadataChange(client, commitInfo)
 		if !canContinue {
 			zaplog.SUG.Debugln("no change return")
@@ -176,6 +319,59 @@ func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
 		return nil
 	}
 
+	// Check the staged go.mod and go.work files against the module policy
+	// 按模块策略检查已暂存的 go.mod 和 go.work 文件
+	if err := CheckModulePolicy(client.Repo(), commitFlags.ModulePolicy); err != nil {
+		return erero.Wro(err)
+	}
+
+	// Conventional mode checks the header against the exported API changes (fixup and amend messages are skipped)
+	// 约定式模式根据导出 API 更改检查标题（跳过 fixup 和 amend 消息）
+	conventional := commitFlags.Conventional && !commitFlags.IsAmend && commitFlags.Fixup == "" && commitFlags.Squash == ""
+	var report *APIReport
+	if conventional {
+		if report, err = CheckAPIChanges(projectRoot); err != nil {
+			return erero.Wro(err)
+		}
+	}
+
+	// Suggest the message from the staged changes when blank (amend keeps the previous message)
+	// 消息为空时根据已暂存的更改建议消息（amend 保留之前的消息）
+	if commitFlags.Suggest && !commitFlags.IsAmend && commitInfo.Message == "" {
+		if conventional {
+			commitInfo.Message, err = SuggestConventionalMessage(projectRoot, report)
+		} else {
+			commitInfo.Message, err = SuggestMessage(projectRoot)
+		}
+		if err != nil {
+			return erero.Wro(err)
+		}
+		zaplog.SUG.Infoln("suggested message:", commitInfo.Message)
+	}
+
+	// Check the conventional header and mark breaking API changes, split commits check each message they get
+	// 检查约定式标题并标记破坏性 API 更改，拆分提交检查其得到的每条消息
+	if conventional {
+		messages := []string{commitInfo.Message}
+		if commitFlags.SplitBy != "" {
+			if messages, err = splitMessages(projectRoot, client.Repo(), commitFlags.SplitBy, commitFlags.SplitMsg, commitInfo.Message); err != nil {
+				return erero.Wro(err)
+			}
+		}
+		for _, message := range messages {
+			if err := checkConventionalReport(message, report); err != nil {
+				return erero.Wro(err)
+			}
+		}
+		if len(report.Changes) > 0 {
+			zaplog.SUG.Infoln("exported API changes:\n" + report.String())
+		}
+	}
+
+	if err := checkpoint(ctx, StepChecked); err != nil {
+		return erero.Wro(err)
+	}
+
 	// Execute commit or amend based on flags
 	// 根据标志执行提交或 amend
 	if commitFlags.IsAmend {
@@ -188,15 +384,39 @@ func GitCommit(projectRoot string, commitFlags *CommitFlags) error {
 		if err != nil {
 			return erero.Wro(err)
 		}
-	} else {
-		// Create new commit
-		// 创建新提交
-		_, err = client.CommitAll(commitInfo)
+	} else if commitFlags.SplitBy != "" {
+		// Split changes into one commit per group
+		// 将更改拆分为每组一个提交
+		groups, err := SplitCommit(projectRoot, client, commitInfo, commitFlags.SplitBy, commitFlags.SplitMsg)
 		if err != nil {
 			return erero.Wro(err)
 		}
+		for _, group := range groups {
+			zaplog.SUG.Infoln("split commit:", group.Hash, group.Message)
+		}
+	} else {
+		// Target an earlier commit with a "fixup!" or "squash!" message when requested
+		// 如果请求则使用 "fixup!" 或 "squash!" 消息指向更早的提交
+		if commitFlags.Fixup != "" || commitFlags.Squash != "" {
+			prefix, revision := FixupPrefix, commitFlags.Fixup
+			if revision == "" {
+				prefix, revision = SquashPrefix, commitFlags.Squash
+			}
+			commitInfo.Message, err = BuildFixupMessage(client.Repo(), prefix, revision, commitInfo.Message)
+			if err != nil {
+				return erero.Wro(err)
+			}
+		}
+
+		// Create new commit from the index, CommitAll would add the modified files and the CRLF copies of LF blobs back
+		// 从索引创建新提交，CommitAll 会重新添加已修改的文件以及 LF 数据对象的 CRLF 副本
+		if _, err = commitStaged(client, commitInfo); err != nil {
+			return erero.Wro(err)
+		}
 	}
 
+	snapshot.markCommitted()
+
 	// Debug repo state when commit done
 	// 提交完成后调试代码库状态
 	gogitassist.DebugRepo(client.Repo())
@@ -254,46 +474,74 @@ func detectMetadataChange(client *gogit.Client, commitInfo *gogit.CommitInfo) bo
 // 使用 allowFormat 函数确定哪些文件需要格式化
 // 对匹配的文件应用 Go 格式化并记录过程
 func FormatChangedGoFiles(projectRoot string, client *gogit.Client, allowFormat func(path string) bool) error {
-	// Configure matching options for Go files with custom function
-	// 配置 Go 文件的匹配选项，使用自定义过滤器
-	matchOptions := gogitchange.NewMatchOptions().MatchType(".go").MatchPath(func(path string) bool {
-		zaplog.SUG.Debugln("path:", path)
-
-		// Use custom format function
-		// 应用用户定义的格式过滤器
-		pass := allowFormat(path)
-		if pass {
-			zaplog.SUG.Debugln("pass:", path)
-		} else {
-			zaplog.SUG.Debugln("skip:", path)
-		}
-		return pass
-	})
+	return FormatChangedGoFilesWithOptions(projectRoot, client, &FormatOptions{AllowFormat: allowFormat, Scope: FormatScopeFile})
+}
 
-	// Process each changed Go file with formatting
-	// 处理每个已改变的 Go 文件进行格式化
-	err := gogitchange.NewChangedFileManager(projectRoot, client.Tree()).ForeachChangedGoFile(matchOptions, func(path string) error {
-		// Double-check file extension to ensure correctness
-		// 为安全起见双重检查文件扩展名
-		if filepath.Ext(path) != ".go" {
-			return nil
-		}
+// FormatChangedGoFilesWithOptions applies formatting to changed Go files in the scope of the options
+// The hunks scope keeps formatting edits of the lines changed against HEAD and their declarations
+//
+// FormatChangedGoFilesWithOptions 按选项中的范围对已改变的 Go 文件应用格式化
+// hunks 范围保留相对 HEAD 已更改的行及其所在声明的格式化编辑
+func FormatChangedGoFilesWithOptions(projectRoot string, client *gogit.Client, options *FormatOptions) error {
+	if _, err := FormatGoFiles(projectRoot, client, options); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
 
-		// Log formatting operation
-		// 记录格式化操作
-		zaplog.ZAPS.Skip1.LOG.Info("golang-format-source", zap.String("path", path))
+// FormatSummary counts the Go files of one formatting run
+// FormatSummary 统计一次格式化运行中的 Go 文件
+type FormatSummary struct {
+	Scanned int // Go files selected, skipped ones included // 选中的 Go 文件数，包括跳过的文件
+	Changed int // Files rewritten with formatted content // 以格式化内容重写的文件数
+	Skipped int // Files the filter skipped as generated // 被过滤器作为生成文件跳过的文件数
+}
 
-		// Format the Go file
-		// 对文件应用 Go 格式化
-		if err := formatgo.FormatFile(path); err != nil {
-			return erero.Wro(err)
+// String returns the summary as one line
+// String 以单行形式返回统计结果
+func (s *FormatSummary) String() string {
+	return fmt.Sprintf("%d go file(s) scanned, %d changed, %d skipped as generated", s.Scanned, s.Changed, s.Skipped)
+}
+
+// FormatGoFiles formats the Go files the options select, the changed ones by default
+// Selected go.mod and go.work files are canonicalized with modfile formatting
+// Calls the progress function of the options as each file completes
+//
+// FormatGoFiles 格式化选项选中的 Go 文件，默认为已改变的文件
+// 选中的 go.mod 和 go.work 文件使用 modfile 格式化进行规范化
+// 每个文件完成时调用选项中的进度函数
+func FormatGoFiles(projectRoot string, client *gogit.Client, options *FormatOptions) (*FormatSummary, error) {
+	if err := checkFormatScope(options.Scope); err != nil {
+		return nil, erero.Wro(err)
+	}
+	targets, err := listFormatTargets(projectRoot, client, options)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	summary := &FormatSummary{Scanned: len(targets.paths) + targets.skipped, Skipped: targets.skipped}
+
+	// Format each selected Go file with the bounded jobs
+	// 使用有限数量的任务格式化每个选中的 Go 文件
+	var mutex sync.Mutex
+	err = runFormatJobs(targets.paths, options.Jobs, func(idx int) (bool, error) {
+		changed, err := formatGoFile(targets.paths[idx], targets.bases[idx], options.Scope)
+		if err != nil {
+			return false, erero.Wro(err)
 		}
-		return nil
+		mutex.Lock()
+		defer mutex.Unlock()
+		if changed {
+			summary.Changed++
+		}
+		if options.Progress != nil {
+			options.Progress(targets.paths[idx], changed)
+		}
+		return changed, nil
 	})
 	if err != nil {
-		return erero.Wro(err)
+		return nil, erero.Wro(err)
 	}
-	return nil
+	return summary, nil
 }
 
 // ApplyProjectConfig applies project-specific configuration to commit flags
@@ -306,6 +554,7 @@ func FormatChangedGoFiles(projectRoot string, client *gogit.Client, allowFormat
 func (f *CommitFlags) ApplyProjectConfig(projectRoot string, config *CommitConfig) {
 	zaplog.SUG.Debugln("applying project config to commit flags")
 	f.ApplySignature(config.ResolveSignature(projectRoot))
+	f.ModulePolicy = config.ModulePolicy
 }
 
 // ApplySignature applies signature configuration to flags
@@ -345,11 +594,14 @@ func (f *CommitFlags) ApplySignature(signature *SignatureConfig) {
 // 支持复杂的通配符匹配以实现灵活的远程模式定义
 // 基于代码库远程配置实现自动身份切换
 type SignatureConfig struct {
-	Name           string   `json:"name"`           // Config name as reference // 配置名称用于引用
-	Username       string   `json:"username"`       // Git username in commits // 用于提交的 Git 用户名
-	Mailbox        string   `json:"mailbox"`        // Git mailbox in commits (preferred) // 用于提交的 Git 邮箱（优先）
-	Eddress        string   `json:"eddress"`        // Git mailbox in commits (fallback) // 用于提交的 Git 邮箱（备选）
-	RemotePatterns []string `json:"remotePatterns"` // Remote URL patterns (supports wildcards) // 远程 URL 模式（支持通配符）
+	Name           string          `json:"name"`                    // Config name as reference // 配置名称用于引用
+	Username       string          `json:"username"`                // Git username in commits // 用于提交的 Git 用户名
+	Mailbox        string          `json:"mailbox"`                 // Git mailbox in commits (preferred) // 用于提交的 Git 邮箱（优先）
+	Eddress        string          `json:"eddress"`                 // Git mailbox in commits (fallback) // 用于提交的 Git 邮箱（备选）
+	SigningKey     string          `json:"signingKey,omitempty"`    // Git user.signingkey written by identity apply // 由 identity apply 写入的 Git user.signingkey
+	RemotePatterns []string        `json:"remotePatterns"`          // Remote URL patterns (supports wildcards) // 远程 URL 模式（支持通配符）
+	RemoteTargets  []*RemoteTarget `json:"remoteTargets,omitempty"` // Structured host/owner/repo targets // 结构化的 host/owner/repo 目标
+	Conditions     *MatchCondition `json:"conditions,omitempty"`    // Path, branch, hostname and env conditions // 路径、分支、主机名和环境变量条件
 }
 
 // CommitConfig represents the comprehensive configuration system for go-commit
@@ -362,41 +614,37 @@ type SignatureConfig struct {
 // 基于 Git 远程 URL 模式匹配实现自动签名选择
 // 支持基于评分的通配符模式匹配，适用于企业和自定义工作流程
 type CommitConfig struct {
-	Signatures []*SignatureConfig `json:"signatures"` // List of configured signatures // 配置的签名列表
+	Schema       string             `json:"$schema,omitempty"`      // Optional JSON Schema reference // 可选的 JSON Schema 引用
+	Signatures   []*SignatureConfig `json:"signatures"`             // List of configured signatures // 配置的签名列表
+	RemotePolicy *RemotePolicy      `json:"remotePolicy,omitempty"` // Optional remote selection policy // 可选的远程选择策略
+	ModulePolicy *ModulePolicy      `json:"modulePolicy,omitempty"` // Optional checks of go.mod and go.work files // 可选的 go.mod 和 go.work 文件检查
+
+	matcher atomic.Pointer[signatureMatcher] // Compiled patterns, see Compile // 已编译的模式，参见 Compile
 }
 
 // LoadConfig loads the go-commit configuration from the specified file path
 // Reads, validates, and parses the JSON configuration file with signature mappings
-// Utilizes osmustexist for file validation and rese/must for robust error handling
+// Utilizes osmustexist for file validation and panics when LoadConfigE reports problems
 // Returns complete loaded configuration suited for signature matching operations
 //
 // LoadConfig 从指定文件路径加载 go-commit 配置
 // 读取、验证并解析包含签名映射的 JSON 配置文件
-// 使用 osmustexist 进行文件验证和 rese/must 进行强健的错误处理
+// 使用 osmustexist 进行文件验证，当 LoadConfigE 报告问题时 panic
 // 返回完全加载的配置，准备进行签名匹配操作
 func LoadConfig(configPath string) *CommitConfig {
-	data := rese.A1(os.ReadFile(osmustexist.FILE(configPath)))
-
-	// Parse JSON configuration
-	// 解析 JSON 配置
-	var config CommitConfig
-	must.Done(json.Unmarshal(data, &config))
-
-	// Validate loaded configuration
-	// 验证加载的配置
-	validateConfig(&config)
-
-	zaplog.SUG.Debugln("loaded config from:", configPath)
-	return &config
+	return rese.P1(LoadConfigE(osmustexist.FILE(configPath)))
 }
 
 // validateConfig performs basic validation on the loaded configuration
-// Ensures signature configurations have required fields and valid patterns
+// Warns about signature configurations missing optional fields
 //
 // validateConfig 对加载的配置执行基本验证
-// 确保签名配置具有必需字段和有效模式
+// 对缺少可选字段的签名配置发出警告
 func validateConfig(config *CommitConfig) {
 	for idx, signature := range config.Signatures {
+		if signature == nil {
+			continue
+		}
 		// Check core signature fields for completeness
 		// 检查关键签名字段的完整性
 		if signature.Username == "" {
@@ -407,7 +655,7 @@ func validateConfig(config *CommitConfig) {
 		if signature.Mailbox == "" && signature.Eddress == "" {
 			zaplog.SUG.Warnf("signature[%d] missing mailbox (mailbox or eddress)", idx)
 		}
-		if len(signature.RemotePatterns) == 0 {
+		if len(signature.RemotePatterns) == 0 && len(signature.RemoteTargets) == 0 && signature.Conditions == nil {
 			zaplog.SUG.Warnf("signature[%d] missing remote patterns", idx)
 		}
 	}
@@ -423,14 +671,7 @@ func validateConfig(config *CommitConfig) {
 // 优先使用 'origin' 远程，但在签名解析时回退到第一个可用远程
 // 返回最佳匹配的签名，如果没有合适的模式匹配远程配置则返回 nil
 func (config *CommitConfig) ResolveSignature(projectRoot string) *SignatureConfig {
-	remoteURL := getOriginRemoteURL(projectRoot)
-	if remoteURL == "" {
-		zaplog.SUG.Debugln("skip signature resolution: remote URL is not configured")
-		return nil
-	}
-	zaplog.SUG.Debugln("remote URL:", remoteURL)
-
-	signature := config.MatchSignature(remoteURL)
+	signature := config.ResolveSignatureDetailed(projectRoot).Signature()
 	if signature == nil {
 		zaplog.SUG.Debugln("no matching signature found")
 		return nil
@@ -441,32 +682,37 @@ func (config *CommitConfig) ResolveSignature(projectRoot string) *SignatureConfi
 
 // MatchSignature finds the best signature configuration for the specified remote URL
 // Employs sophisticated pattern matching with wildcards and score-based ranking selection
-// Evaluates all configured signature patterns and returns the highest-scoring match
+// Evaluates the compiled patterns by highest possible score and returns the highest-scoring match
 // Returns the best matched signature or nil if no patterns match the remote URL
 //
 // MatchSignature 为指定的远程 URL 找到最佳的签名配置
 // 采用复杂的通配符模式匹配和基于评分的优先级选择
-// 评估所有配置的签名模式并返回得分最高的匹配
+// 按最高可能分数评估已编译的模式并返回得分最高的匹配
 // 返回最佳匹配的签名，如果没有模式匹配远程 URL 则返回 nil
 func (config *CommitConfig) MatchSignature(remoteURL string) *SignatureConfig {
-	var bestMatch *SignatureConfig
-	bestMatchScore := -1
-
-	// Iterate through all configured signatures
-	// 遍历所有配置的签名
-	for _, signature := range config.Signatures {
-		// Check each remote pattern for this signature
-		// 检查此签名的每个远程模式
-		for _, pattern := range signature.RemotePatterns {
-			score := utils.MatchRemotePattern(pattern, remoteURL)
-			if score > bestMatchScore {
-				bestMatchScore = score
-				bestMatch = signature
-			}
-		}
-	}
+	return config.MatchSignatureInContext(remoteURL, nil)
+}
 
-	return bestMatch
+// MatchSignatureInContext finds the signature of the remote URL in the checkout context
+// Signatures selected by conditions alone come first, then the best remote match, then the default
+// A nil context has no path, branch, hostname or environment, the remote URL may be blank
+//
+// MatchSignatureInContext 在检出上下文中为远程 URL 找到签名
+// 仅由条件选中的签名优先，其次是最佳远程匹配，最后是默认签名
+// nil 上下文没有路径、分支、主机名和环境变量，远程 URL 可以为空
+func (config *CommitConfig) MatchSignatureInContext(remoteURL string, ctx *MatchContext) *SignatureConfig {
+	matcher := config.compiledMatcher()
+	decisions := matcher.decide(ctx)
+	if idx := selectedBy(decisions, func(decision *conditionDecision) bool { return decision.selects }); idx >= 0 {
+		return matcher.signatures[idx]
+	}
+	if rule, _ := matcher.best(newRemoteForms(remoteURL), decisions); rule != nil {
+		return rule.signature
+	}
+	if idx := selectedBy(decisions, func(decision *conditionDecision) bool { return decision.fallback }); idx >= 0 {
+		return matcher.signatures[idx]
+	}
+	return nil
 }
 
 // DefaultAllowFormat is the default check function in Go files formatting
@@ -97,6 +97,29 @@ func TestGitCommit_WithNewFile(t *testing.T) {
 	require.Empty(t, status)
 }
 
+func TestGitCommit_WithDeletedFile(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	must.Done(os.Remove(filepath.Join(tempDIR, "README.md")))
+
+	flags := &CommitFlags{
+		Username: "Test User",
+		Eddress:  "test@example.com",
+		Message:  "Remove readme",
+	}
+
+	require.NoError(t, GitCommit(tempDIR, flags))
+
+	client := rese.P1(gogit.New(tempDIR))
+	status := rese.V1(client.Status())
+	require.Empty(t, status)
+	headCommit := rese.P1(client.Repo().CommitObject(rese.P1(client.Repo().Head()).Hash()))
+	require.Equal(t, "Remove readme", headCommit.Message)
+	_, err := headCommit.File("README.md")
+	require.Error(t, err)
+}
+
 func TestGitCommit_NoCommitFlag(t *testing.T) {
 	tempDIR, cleanup := setupTestRepo()
 	t.Cleanup(cleanup)
@@ -564,6 +587,28 @@ func TestCommitConfig_MatchSignature_Priority(t *testing.T) {
 	require.Equal(t, "specific-user", signature.Name)
 }
 
+func TestCommitConfig_MatchSignature_PatternKinds(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "general", RemotePatterns: []string{"git@*"}},
+			{Name: "corp", RemotePatterns: []string{"git@{github,gitlab}.corp.com:*"}},
+			{Name: "regex", RemotePatterns: []string{`re:git@github\.corp\.com:team-[0-9]+/.*`}},
+			{Name: "owner", RemoteTargets: []*RemoteTarget{{Host: "github.com", Owner: "acme"}}},
+		},
+	}
+
+	// Regex scores 27 with its literal characters, higher than the brace pattern at 20
+	// 正则以其字面字符计 27 分，高于花括号模式的 20 分
+	require.Equal(t, "regex", config.MatchSignature("git@github.corp.com:team-42/repo.git").Name)
+	require.Equal(t, "corp", config.MatchSignature("git@gitlab.corp.com:team-42/repo.git").Name)
+	require.Equal(t, "general", config.MatchSignature("git@bitbucket.org:team/repo.git").Name)
+
+	// Target components are matched on their own, so nested owners do not match "acme"
+	// 目标的各部分单独匹配，因此嵌套的所有者不匹配 "acme"
+	require.Equal(t, "owner", config.MatchSignature("https://github.com/acme/repo").Name)
+	require.Nil(t, config.MatchSignature("https://github.com/acme/sub/repo"))
+}
+
 func TestLoadConfig_FileExists(t *testing.T) {
 	tempDIR := rese.V1(os.MkdirTemp("", "config-test-*"))
 	t.Cleanup(func() { must.Done(os.RemoveAll(tempDIR)) })
@@ -795,6 +840,18 @@ func TestCommitFlags_ValidateFlags(t *testing.T) {
 		require.Contains(t, warnings[0], "commit message provided but no-commit flag is set")
 	})
 
+	t.Run("Format scope without format-go should generate warning", func(t *testing.T) {
+		flags := &CommitFlags{
+			Username:    "test-user",
+			Mailbox:     "test@example.com",
+			FormatScope: FormatScopeHunks, // Scope without FormatGo
+		}
+
+		warnings := flags.ValidateFlags()
+		require.Len(t, warnings, 1)
+		require.Contains(t, warnings[0], "format-scope set but format-go is disabled")
+	})
+
 	t.Run("Missing authentication info without auto-sign should generate warning", func(t *testing.T) {
 		flags := &CommitFlags{
 			Username: "", // Empty
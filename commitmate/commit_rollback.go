@@ -0,0 +1,201 @@
+// Package commitmate makes the commit workflow transactional
+// Snapshots the changed files, the index and the branch before mutation and restores them on failure or cancellation
+//
+// commitmate 包使提交工作流程具有事务性
+// 在修改之前对已改变的文件、索引和分支进行快照，并在失败或取消时恢复它们
+package commitmate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// Checkpoints of the commit workflow, each one follows the step of its name
+// 提交工作流程的检查点，每个检查点位于同名步骤之后
+const (
+	StepStaged     = "staged"     // Changes added to the index // 更改已添加到索引
+	StepFormatted  = "formatted"  // Go and module files formatted and restaged // Go 和模块文件已格式化并重新暂存
+	StepNormalized = "normalized" // Text files normalized and restaged // 文本文件已规范化并重新暂存
+	StepChecked    = "checked"    // Module policy, message suggestion and conventional checks done // 模块策略、消息建议和约定式检查已完成
+)
+
+// commitStepHook runs at each checkpoint, tests replace it to fail the workflow at a step
+// commitStepHook 在每个检查点运行，测试替换它以使工作流程在某个步骤失败
+var commitStepHook = func(step string) error {
+	return nil
+}
+
+// fileSnapshot is the content of one changed file before the workflow
+// fileSnapshot 是工作流程之前一个已改变文件的内容
+type fileSnapshot struct {
+	content []byte      // File content // 文件内容
+	mode    fs.FileMode // Permission bits // 权限位
+}
+
+// commitSnapshot holds what the commit workflow may mutate, to restore it on failure
+// commitSnapshot 保存提交工作流程可能修改的内容，以便在失败时恢复
+type commitSnapshot struct {
+	projectRoot string                   // Repo root DIR // 仓库根目录
+	repo        *git.Repository          // Repo of the workflow // 工作流程所在的仓库
+	index       *index.Index             // Index before the workflow // 工作流程之前的索引
+	files       map[string]*fileSnapshot // Changed regular files by slash path // 按斜杠路径存放的已改变普通文件
+	refName     plumbing.ReferenceName   // Branch HEAD points to, or HEAD when detached // HEAD 指向的分支，分离时为 HEAD
+	refHash     plumbing.Hash            // Commit of the ref, zero when the branch is unborn // 引用指向的提交，分支未诞生时为零值
+
+	committed atomic.Bool // Set once the commit is created, no rollback after it // 提交创建后设置，之后不再回滚
+}
+
+// takeCommitSnapshot snapshots the changed files in the status, the index and the branch
+// takeCommitSnapshot 对状态中已改变的文件、索引和分支进行快照
+func takeCommitSnapshot(projectRoot string, repo *git.Repository, status git.Status) (*commitSnapshot, error) {
+	snapshot := &commitSnapshot{projectRoot: projectRoot, repo: repo, files: map[string]*fileSnapshot{}}
+
+	stagedIndex, err := repo.Storer.Index()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	snapshot.index = stagedIndex
+
+	headReference, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	snapshot.refName, snapshot.refHash = plumbing.HEAD, headReference.Hash()
+	if headReference.Type() == plumbing.SymbolicReference {
+		snapshot.refName, snapshot.refHash = headReference.Target(), plumbing.ZeroHash
+		branchReference, err := repo.Reference(headReference.Target(), false)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, erero.Wro(err)
+		}
+		if err == nil {
+			snapshot.refHash = branchReference.Hash()
+		}
+	}
+
+	for name := range status {
+		absPath := filepath.Join(projectRoot, filepath.FromSlash(name))
+		info, err := os.Lstat(absPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		snapshot.files[name] = &fileSnapshot{content: content, mode: info.Mode().Perm()}
+	}
+	return snapshot, nil
+}
+
+// checkpoint runs the step hook and stops the workflow once the context is canceled
+// checkpoint 运行步骤钩子，并在上下文取消后停止工作流程
+func checkpoint(ctx context.Context, step string) error {
+	if err := commitStepHook(step); err != nil {
+		return erero.Wro(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return erero.Wrapf(err, "interrupted after step %s", step)
+	}
+	return nil
+}
+
+// markCommitted records the commit is created, failures after it keep the result
+// markCommitted 记录提交已创建，之后的失败保留结果
+func (s *commitSnapshot) markCommitted() {
+	if s != nil {
+		s.committed.Store(true)
+	}
+}
+
+// run runs the workflow and restores the snapshot when it fails or panics before the commit is created
+// run 运行工作流程，并在提交创建之前失败或 panic 时恢复快照
+func (s *commitSnapshot) run(workflow func() error) (err error) {
+	defer func() {
+		if cause := recover(); cause != nil {
+			s.rollback(fmt.Errorf("panic: %v", cause))
+			panic(cause)
+		}
+		if err != nil {
+			if rollbackErr := s.rollback(err); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		}
+	}()
+	return workflow()
+}
+
+// rollback restores the snapshot unless the commit is created
+// rollback 在提交未创建时恢复快照
+func (s *commitSnapshot) rollback(cause error) error {
+	if s.committed.Load() {
+		return nil
+	}
+	zaplog.SUG.Warnln("commit workflow failed, rolling back:", cause)
+	if err := s.restore(); err != nil {
+		zaplog.SUG.Errorln("rollback failed:", err)
+		return erero.Wro(err)
+	}
+	zaplog.SUG.Infoln("rolled back the working tree, the index and", s.refName.Short())
+	return nil
+}
+
+// restore writes back the changed files, the index and the ref
+// Files created by the workflow stay, the workflow only rewrites existing ones
+//
+// restore 写回已改变的文件、索引和引用
+// 工作流程创建的文件保持不变，工作流程只会重写已有的文件
+func (s *commitSnapshot) restore() error {
+	for name, file := range s.files {
+		absPath := filepath.Join(s.projectRoot, filepath.FromSlash(name))
+		content, err := os.ReadFile(absPath)
+		if err == nil && bytes.Equal(content, file.content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			return erero.Wro(err)
+		}
+		if err := os.WriteFile(absPath, file.content, file.mode); err != nil {
+			return erero.Wro(err)
+		}
+		if err := os.Chmod(absPath, file.mode); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	if err := s.repo.Storer.SetIndex(s.index); err != nil {
+		return erero.Wro(err)
+	}
+
+	reference, err := s.repo.Reference(s.refName, false)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return erero.Wro(err)
+	}
+	switch {
+	case s.refHash.IsZero() && err == nil:
+		if err := s.repo.Storer.RemoveReference(s.refName); err != nil {
+			return erero.Wro(err)
+		}
+	case !s.refHash.IsZero() && (err != nil || reference.Hash() != s.refHash):
+		if err := s.repo.Storer.SetReference(plumbing.NewHashReference(s.refName, s.refHash)); err != nil {
+			return erero.Wro(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package commitmate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/rese"
+)
+
+const rollbackNotes = "draft  \r\n"
+
+// setupRollbackRepo commits the legacy source, then leaves an unformatted edit and an untracked text file
+// setupRollbackRepo 提交旧源码，然后留下一个未格式化的编辑和一个未跟踪的文本文件
+func setupRollbackRepo(t *testing.T) (string, git.Status) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "demo.go", legacySource, &CommitFlags{Message: "add legacy"})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.go"), []byte(legacyChange), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "notes.txt"), []byte(rollbackNotes), 0600))
+	status := rese.V1(rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree()).Status())
+	return tempDIR, status
+}
+
+// failAtStep makes the workflow fail at the checkpoint until the test ends
+// failAtStep 使工作流程在检查点失败，直到测试结束
+func failAtStep(t *testing.T, failStep string) {
+	commitStepHook = func(step string) error {
+		if step == failStep {
+			return erero.Errorf("failure at %s", step)
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		commitStepHook = func(step string) error { return nil }
+	})
+}
+
+// requireRolledBack checks the files, the index and HEAD are as before the workflow
+// requireRolledBack 检查文件、索引和 HEAD 与工作流程之前相同
+func requireRolledBack(t *testing.T, tempDIR string, status git.Status, headMessage string) {
+	require.Equal(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+	require.Equal(t, rollbackNotes, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "notes.txt")))))
+	require.Equal(t, os.FileMode(0600), rese.V1(os.Stat(filepath.Join(tempDIR, "notes.txt"))).Mode().Perm())
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.Equal(t, status, rese.V1(rese.P1(repo.Worktree()).Status()))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, headMessage, headCommit.Message)
+}
+
+// TestGitCommit_RollbackAtEachStep validates restoring the working tree, the index and HEAD on a failure after each step
+// TestGitCommit_RollbackAtEachStep 验证在每个步骤之后失败时恢复工作树、索引和 HEAD
+func TestGitCommit_RollbackAtEachStep(t *testing.T) {
+	for _, step := range []string{StepStaged, StepFormatted, StepNormalized, StepChecked} {
+		t.Run(step, func(t *testing.T) {
+			tempDIR, status := setupRollbackRepo(t)
+			failAtStep(t, step)
+
+			commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true, NormalizeText: true}
+			require.ErrorContains(t, GitCommit(tempDIR, commitFlags), "failure at "+step)
+			requireRolledBack(t, tempDIR, status, "add legacy")
+		})
+	}
+}
+
+func TestGitCommit_RollbackOnCommitError(t *testing.T) {
+	tempDIR, status := setupRollbackRepo(t)
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true, NormalizeText: true, Fixup: "no-such-revision"}
+	require.Error(t, GitCommit(tempDIR, commitFlags))
+	requireRolledBack(t, tempDIR, status, "add legacy")
+}
+
+func TestGitCommit_RollbackOnPanic(t *testing.T) {
+	tempDIR, status := setupRollbackRepo(t)
+	commitStepHook = func(step string) error {
+		if step == StepFormatted {
+			panic("broken step")
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		commitStepHook = func(step string) error { return nil }
+	})
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true}
+	require.PanicsWithValue(t, "broken step", func() {
+		_ = GitCommit(tempDIR, commitFlags)
+	})
+	requireRolledBack(t, tempDIR, status, "add legacy")
+}
+
+// TestGitCommit_RollbackOnInterrupt validates canceling the context stops the workflow at the checkpoint
+// TestGitCommit_RollbackOnInterrupt 验证取消上下文会在检查点停止工作流程
+func TestGitCommit_RollbackOnInterrupt(t *testing.T) {
+	tempDIR, status := setupRollbackRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	commitStepHook = func(step string) error {
+		if step == StepStaged {
+			cancel()
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		commitStepHook = func(step string) error { return nil }
+	})
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true}
+	err := GitCommitContext(ctx, tempDIR, commitFlags)
+	require.ErrorContains(t, err, "interrupted after step "+StepStaged)
+	require.ErrorIs(t, err, context.Canceled)
+	requireRolledBack(t, tempDIR, status, "add legacy")
+}
+
+func TestGitCommit_NoRollback(t *testing.T) {
+	tempDIR, _ := setupRollbackRepo(t)
+	failAtStep(t, StepChecked)
+
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change", FormatGo: true, NoRollback: true}
+	require.Error(t, GitCommit(tempDIR, commitFlags))
+	require.NotEqual(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.True(t, hasStagedChanges(rese.V1(rese.P1(repo.Worktree()).Status())))
+}
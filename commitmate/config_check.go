@@ -0,0 +1,496 @@
+// Package commitmate provides strict configuration checking
+// Contains the JSON Schema, typed config errors, and the non-panicking config loader
+//
+// commitmate 包提供严格的配置检查
+// 包含 JSON Schema、类型化的配置错误以及不会 panic 的配置加载函数
+package commitmate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+//go:embed go-commit-config.schema.json
+var configSchemaJSON []byte
+
+// ConfigSchema returns the JSON Schema describing go-commit configuration files
+// Editors can reference it through the "$schema" field in the config file
+//
+// ConfigSchema 返回描述 go-commit 配置文件的 JSON Schema
+// 编辑器可以通过配置文件中的 "$schema" 字段引用它
+func ConfigSchema() []byte {
+	return slices.Clone(configSchemaJSON)
+}
+
+// ConfigProblem describes one problem found in a configuration file
+// Path uses JSON path notation such as "$.signatures[1].remotePatterns[0]"
+//
+// ConfigProblem 描述配置文件中发现的一个问题
+// Path 使用 JSON 路径表示法，例如 "$.signatures[1].remotePatterns[0]"
+type ConfigProblem struct {
+	Path    string // JSON path of the problem value // 问题值的 JSON 路径
+	Message string // Problem description // 问题描述
+}
+
+// String formats the problem as "path: message"
+// String 将问题格式化为 "path: message"
+func (p *ConfigProblem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ConfigError is returned when a configuration file fails strict validation
+// Lists each problem found instead of stopping at the first one
+//
+// ConfigError 在配置文件未通过严格验证时返回
+// 列出发现的全部问题，而不是在第一个问题处停止
+type ConfigError struct {
+	ConfigPath string           // Config file path, blank when parsed from bytes // 配置文件路径，从字节解析时为空
+	Problems   []*ConfigProblem // Problems found in the config // 配置中发现的问题
+}
+
+// Error joins the problems into a multi-line message
+// Error 将所有问题拼接为多行消息
+func (e *ConfigError) Error() string {
+	var sb strings.Builder
+	if e.ConfigPath != "" {
+		sb.WriteString(fmt.Sprintf("invalid config %s: %d problem(s)", e.ConfigPath, len(e.Problems)))
+	} else {
+		sb.WriteString(fmt.Sprintf("invalid config: %d problem(s)", len(e.Problems)))
+	}
+	for _, problem := range e.Problems {
+		sb.WriteString("\n  ")
+		sb.WriteString(problem.String())
+	}
+	return sb.String()
+}
+
+// LoadConfigE loads the go-commit configuration without panicking
+// Returns *ConfigError listing the problems when the file fails strict validation
+//
+// LoadConfigE 加载 go-commit 配置且不会 panic
+// 当文件未通过严格验证时返回列出问题的 *ConfigError
+func LoadConfigE(configPath string) (*CommitConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		var configError *ConfigError
+		if errors.As(err, &configError) {
+			configError.ConfigPath = configPath
+		}
+		return nil, err
+	}
+
+	zaplog.SUG.Debugln("loaded config from:", configPath)
+	return config, nil
+}
+
+// ParseConfig parses and strictly validates configuration content
+// Rejects unknown fields and wrong value types, then runs the semantic checks of Validate
+// Returns *ConfigError listing each problem found
+//
+// ParseConfig 解析并严格验证配置内容
+// 拒绝未知字段和错误的值类型，然后执行 Validate 的语义检查
+// 返回列出每个问题的 *ConfigError
+func ParseConfig(data []byte) (*CommitConfig, error) {
+	config, problems := decodeConfig(data)
+	if config == nil {
+		return nil, &ConfigError{Problems: problems}
+	}
+
+	problems = append(problems, config.checkProblems()...)
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+
+	// Log soft issues that do not make the config unusable
+	// 记录不会导致配置不可用的轻微问题
+	validateConfig(config)
+	config.Compile()
+	return config, nil
+}
+
+// decodeConfig decodes configuration content and reports structural problems
+// Returns nil config when the content cannot be decoded into CommitConfig
+//
+// decodeConfig 解码配置内容并报告结构性问题
+// 当内容无法解码为 CommitConfig 时返回 nil 配置
+func decodeConfig(data []byte) (*CommitConfig, []*ConfigProblem) {
+	// Decode into generic values first to locate unknown fields with paths
+	// 先解码为通用值，以便带路径定位未知字段
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, []*ConfigProblem{newSyntaxProblem(err)}
+	}
+	problems := collectUnknownFields("$", document, reflect.TypeOf(CommitConfig{}))
+
+	// Decode into the typed config, wrong value types stop further checks
+	// 解码为类型化配置，值类型错误时停止后续检查
+	var config CommitConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, append(problems, newSyntaxProblem(err))
+	}
+	return &config, problems
+}
+
+// Validate runs the semantic checks on the configuration
+// Checks mailbox syntax, pattern syntax, duplicate names and shadowed patterns
+// Returns *ConfigError listing each problem found, nil when the config is valid
+//
+// Validate 对配置执行语义检查
+// 检查邮箱语法、模式语法、重复名称以及被遮蔽的模式
+// 返回列出每个问题的 *ConfigError，配置有效时返回 nil
+func (config *CommitConfig) Validate() error {
+	if problems := config.checkProblems(); len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// checkProblems collects the semantic problems of the configuration
+// checkProblems 收集配置的语义问题
+func (config *CommitConfig) checkProblems() []*ConfigProblem {
+	problems := config.checkFieldProblems()
+	problems = append(problems, config.checkShadowedPatterns()...)
+	return problems
+}
+
+// checkFieldProblems collects problems found by looking at each signature on its own
+// checkFieldProblems 收集逐个查看签名时发现的问题
+func (config *CommitConfig) checkFieldProblems() []*ConfigProblem {
+	var problems []*ConfigProblem
+	nameIndexes := map[string]int{}
+
+	defaultIdx := -1
+	for idx, signature := range config.Signatures {
+		path := fmt.Sprintf("$.signatures[%d]", idx)
+		if signature == nil {
+			problems = append(problems, &ConfigProblem{Path: path, Message: "signature is null"})
+			continue
+		}
+
+		// Names are used as references, so they must be unique
+		// 名称用于引用，因此必须唯一
+		if signature.Name != "" {
+			if first, exists := nameIndexes[signature.Name]; exists {
+				problems = append(problems, &ConfigProblem{
+					Path:    path + ".name",
+					Message: fmt.Sprintf("duplicate name %q, first defined at $.signatures[%d].name", signature.Name, first),
+				})
+			} else {
+				nameIndexes[signature.Name] = idx
+			}
+		}
+
+		problems = append(problems, checkMailbox(path+".mailbox", signature.Mailbox)...)
+		problems = append(problems, checkMailbox(path+".eddress", signature.Eddress)...)
+
+		patternIndexes := map[string]int{}
+		for patternIdx, pattern := range signature.RemotePatterns {
+			patternPath := fmt.Sprintf("%s.remotePatterns[%d]", path, patternIdx)
+			if err := utils.ValidatePattern(pattern); err != nil {
+				problems = append(problems, &ConfigProblem{Path: patternPath, Message: err.Error()})
+				continue
+			}
+			if first, exists := patternIndexes[pattern]; exists {
+				problems = append(problems, &ConfigProblem{
+					Path:    patternPath,
+					Message: fmt.Sprintf("duplicate pattern %q, first defined at %s.remotePatterns[%d]", pattern, path, first),
+				})
+				continue
+			}
+			patternIndexes[pattern] = patternIdx
+		}
+		for targetIdx, target := range signature.RemoteTargets {
+			problems = append(problems, checkRemoteTargetProblems(fmt.Sprintf("%s.remoteTargets[%d]", path, targetIdx), target)...)
+		}
+
+		// Only the first default signature can ever apply
+		// 只有第一个默认签名可能生效
+		if signature.Conditions != nil {
+			problems = append(problems, checkConditionProblems(path+".conditions", signature.Conditions, true)...)
+			if signature.Conditions.Default {
+				if defaultIdx >= 0 {
+					problems = append(problems, &ConfigProblem{
+						Path:    path + ".conditions.default",
+						Message: fmt.Sprintf("duplicate default, first defined at $.signatures[%d].conditions.default", defaultIdx),
+					})
+				} else {
+					defaultIdx = idx
+				}
+			}
+		}
+	}
+	problems = append(problems, checkRemotePolicyProblems(config.RemotePolicy)...)
+	problems = append(problems, checkModulePolicyProblems(config.ModulePolicy)...)
+	return problems
+}
+
+// checkMailbox validates the mailbox syntax when the value is set
+// checkMailbox 在值已设置时验证邮箱语法
+func checkMailbox(path string, mailbox string) []*ConfigProblem {
+	if mailbox == "" {
+		return nil
+	}
+	address, err := mail.ParseAddress(mailbox)
+	if err != nil || address.Name != "" || address.Address != mailbox {
+		return []*ConfigProblem{{Path: path, Message: fmt.Sprintf("invalid mailbox %q", mailbox)}}
+	}
+	return nil
+}
+
+// patternEntry locates one remote pattern or structured target in the configuration
+// patternEntry 定位配置中的一个远程模式或结构化目标
+type patternEntry struct {
+	signatureIdx int              // Index of the signature // 签名的索引
+	patternIdx   int              // Index of the pattern in the signature // 模式在签名中的索引
+	signature    *SignatureConfig // Signature owning the pattern // 拥有该模式的签名
+	pattern      string           // Pattern content, the equivalent glob of a target // 模式内容，目标则为等价的 glob
+	target       *RemoteTarget    // Structured target, nil for remote patterns // 结构化目标，远程模式时为 nil
+	rule         *matcherRule     // Compiled rule used in scoring // 评分时使用的已编译规则
+}
+
+// path returns the JSON path of the pattern
+// path 返回模式的 JSON 路径
+func (e *patternEntry) path() string {
+	if e.target != nil {
+		return fmt.Sprintf("$.signatures[%d].remoteTargets[%d]", e.signatureIdx, e.patternIdx)
+	}
+	return fmt.Sprintf("$.signatures[%d].remotePatterns[%d]", e.signatureIdx, e.patternIdx)
+}
+
+// isSimple reports whether the entry uses "*" wildcards alone, which static analysis requires
+// isSimple 判断条目是否只使用 "*" 通配符，静态分析需要满足此条件
+func (e *patternEntry) isSimple() bool {
+	if e.target != nil {
+		return e.target.isSimple()
+	}
+	return utils.IsSimplePattern(e.pattern)
+}
+
+// score scores the entry against the forms of one remote URL
+// score 针对一个远程 URL 的各种形式为条目评分
+func (e *patternEntry) score(forms *remoteForms) int {
+	return e.rule.score(forms)
+}
+
+// listPatternEntries flattens the valid remote patterns with their locations
+// listPatternEntries 展开所有有效的远程模式及其位置
+func (config *CommitConfig) listPatternEntries() []*patternEntry {
+	var entries []*patternEntry
+	for signatureIdx, signature := range config.Signatures {
+		if signature == nil {
+			continue
+		}
+		for patternIdx, pattern := range signature.RemotePatterns {
+			if utils.ValidatePattern(pattern) != nil {
+				continue
+			}
+			entries = append(entries, &patternEntry{
+				signatureIdx: signatureIdx,
+				patternIdx:   patternIdx,
+				signature:    signature,
+				pattern:      pattern,
+				rule:         newPatternRule(signatureIdx, signature, patternIdx, pattern),
+			})
+		}
+		for targetIdx, target := range signature.RemoteTargets {
+			if len(checkRemoteTargetProblems("", target)) > 0 {
+				continue
+			}
+			entries = append(entries, &patternEntry{
+				signatureIdx: signatureIdx,
+				patternIdx:   targetIdx,
+				signature:    signature,
+				pattern:      target.Pattern(),
+				target:       target,
+				rule:         newTargetRule(signatureIdx, signature, targetIdx, target),
+			})
+		}
+	}
+	return entries
+}
+
+// findShadowingEntry returns the pattern of another signature that wins each URL the entry matches
+// MatchSignature keeps the first signature on equal scores, so earlier patterns win ties
+// Targets match the "host/owner/repo" form alone, so they can shadow nothing but targets
+//
+// findShadowingEntry 返回另一个签名中在该模式能匹配的所有 URL 上都胜出的模式
+// MatchSignature 在分数相同时保留先出现的签名，因此靠前的模式赢得平局
+// 目标仅匹配 "host/owner/repo" 形式，因此只能遮蔽其他目标
+func findShadowingEntry(entry *patternEntry, entries []*patternEntry) *patternEntry {
+	if !entry.isSimple() {
+		return nil
+	}
+	score := utils.CountPatternScore(entry.pattern)
+	for _, other := range entries {
+		// Signatures with conditions apply in some checkouts alone, so they shadow nothing
+		// 带条件的签名仅在部分检出中适用，因此不会遮蔽其他模式
+		if other.signatureIdx == entry.signatureIdx || (other.target != nil && entry.target == nil) || other.signature.Conditions != nil {
+			continue
+		}
+		otherScore := utils.CountPatternScore(other.pattern)
+		if otherScore < score || (otherScore == score && other.signatureIdx > entry.signatureIdx) {
+			continue
+		}
+		if other.target != nil && entry.target != nil {
+			if other.isSimple() && coversTarget(other.target, entry.target) {
+				return other
+			}
+		} else if utils.CoversPattern(other.pattern, entry.pattern) {
+			return other
+		}
+	}
+	return nil
+}
+
+// checkShadowedPatterns reports patterns that can never select their signature
+// checkShadowedPatterns 报告永远无法选中其所属签名的模式
+func (config *CommitConfig) checkShadowedPatterns() []*ConfigProblem {
+	var problems []*ConfigProblem
+	entries := config.listPatternEntries()
+	for _, entry := range entries {
+		if other := findShadowingEntry(entry, entries); other != nil {
+			problems = append(problems, &ConfigProblem{
+				Path:    entry.path(),
+				Message: fmt.Sprintf("pattern %q is shadowed by %s (%q)", entry.pattern, other.path(), other.pattern),
+			})
+		}
+	}
+	return problems
+}
+
+// collectUnknownFields walks the decoded JSON document against the Go type
+// Reports object keys that do not map to a json tag of the target struct
+//
+// collectUnknownFields 按照 Go 类型遍历解码后的 JSON 文档
+// 报告无法对应到目标结构体 json 标签的对象键
+func collectUnknownFields(path string, value any, typ reflect.Type) []*ConfigProblem {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var problems []*ConfigProblem
+	switch typ.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil // Type mismatches are reported by the typed decoding // 类型不匹配由类型化解码报告
+		}
+		fieldTypes := jsonFieldTypes(typ)
+		for _, key := range sortedKeys(object) {
+			fieldType, exists := fieldTypes[key]
+			if !exists {
+				problems = append(problems, &ConfigProblem{Path: path + "." + key, Message: "unknown field"})
+				continue
+			}
+			problems = append(problems, collectUnknownFields(path+"."+key, object[key], fieldType)...)
+		}
+	case reflect.Slice:
+		array, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		for idx, item := range array {
+			problems = append(problems, collectUnknownFields(fmt.Sprintf("%s[%d]", path, idx), item, typ.Elem())...)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(object) {
+			problems = append(problems, collectUnknownFields(path+"."+key, object[key], typ.Elem())...)
+		}
+	default:
+	}
+	return problems
+}
+
+// jsonFieldTypes maps the json tag names of a struct to the field types
+// jsonFieldTypes 将结构体的 json 标签名映射到字段类型
+func jsonFieldTypes(typ reflect.Type) map[string]reflect.Type {
+	fieldTypes := map[string]reflect.Type{}
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldTypes[name] = field.Type
+	}
+	return fieldTypes
+}
+
+// sortedKeys returns the keys of the object in sorted sequence
+// sortedKeys 返回排序后的对象键
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// convertFieldPath converts the dotted field path of encoding/json into JSON path notation
+// Numeric segments such as "signatures.0.remotePatterns" become "$.signatures[0].remotePatterns"
+//
+// convertFieldPath 将 encoding/json 的点分字段路径转换为 JSON 路径表示法
+// 数字段例如 "signatures.0.remotePatterns" 会变为 "$.signatures[0].remotePatterns"
+func convertFieldPath(field string) string {
+	path := "$"
+	if field == "" {
+		return path
+	}
+	for _, segment := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(segment); err == nil {
+			path += "[" + segment + "]"
+		} else {
+			path += "." + segment
+		}
+	}
+	return path
+}
+
+// newSyntaxProblem converts JSON decoding errors into problems with the best known path
+// newSyntaxProblem 将 JSON 解码错误转换为带有已知最佳路径的问题
+func newSyntaxProblem(err error) *ConfigProblem {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return &ConfigProblem{
+			Path:    convertFieldPath(typeError.Field),
+			Message: fmt.Sprintf("expect %s but got JSON %s", typeError.Type, typeError.Value),
+		}
+	}
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		return &ConfigProblem{
+			Path:    "$",
+			Message: fmt.Sprintf("invalid JSON at offset %d: %s", syntaxError.Offset, syntaxError.Error()),
+		}
+	}
+	return &ConfigProblem{Path: "$", Message: err.Error()}
+}
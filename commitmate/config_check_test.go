@@ -0,0 +1,117 @@
+package commitmate
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/must"
+	"github.com/yyle88/rese"
+)
+
+// collectProblemPaths extracts problem paths from a *ConfigError
+// collectProblemPaths 从 *ConfigError 中提取问题路径
+func collectProblemPaths(t *testing.T, err error) []string {
+	var configError *ConfigError
+	require.True(t, errors.As(err, &configError))
+	var paths []string
+	for _, problem := range configError.Problems {
+		paths = append(paths, problem.Path)
+	}
+	return paths
+}
+
+func TestConfigSchema(t *testing.T) {
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(ConfigSchema(), &schema))
+	require.Equal(t, false, schema["additionalProperties"])
+	require.Contains(t, schema, "$defs")
+}
+
+func TestParseConfig_Valid(t *testing.T) {
+	config, err := ParseConfig([]byte(`{
+		"$schema": "./go-commit-config.schema.json",
+		"signatures": [
+			{"name": "work", "username": "worker", "mailbox": "worker@corp.com", "remotePatterns": ["git@github.corp.com:*"]},
+			{"name": "home", "username": "home-dev", "eddress": "home@example.com", "remotePatterns": ["git@github.com:home-dev/*", "*"]}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, config.Signatures, 2)
+	require.Equal(t, "home", config.MatchSignature("git@github.com:home-dev/repo.git").Name)
+}
+
+// TestParseConfig_Problems validates that all problems are listed with JSON paths
+// Tests unknown fields, mailbox syntax, pattern syntax, duplicates and shadowed patterns together
+//
+// TestParseConfig_Problems 验证所有问题都带有 JSON 路径被列出
+// 同时测试未知字段、邮箱语法、模式语法、重复项和被遮蔽的模式
+func TestParseConfig_Problems(t *testing.T) {
+	_, err := ParseConfig([]byte(`{
+		"signatures": [
+			{"name": "work", "mailbox": "not-a-mailbox", "remotePatterns": ["git@github.com:*", ""], "extra": 1},
+			{"name": "work", "eddress": "Work <work@corp.com>", "remotePatterns": ["git@github.com:*", "*", "*"]}
+		],
+		"unknown": true
+	}`))
+	require.Error(t, err)
+	require.Equal(t, []string{
+		"$.signatures[0].extra",
+		"$.unknown",
+		"$.signatures[0].mailbox",
+		"$.signatures[0].remotePatterns[1]",
+		"$.signatures[1].name",
+		"$.signatures[1].eddress",
+		"$.signatures[1].remotePatterns[2]",
+		"$.signatures[1].remotePatterns[0]",
+	}, collectProblemPaths(t, err))
+}
+
+func TestParseConfig_InvalidJSON(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"signatures": [`))
+	require.Equal(t, []string{"$"}, collectProblemPaths(t, err))
+
+	_, err = ParseConfig([]byte(`{"signatures": [{"remotePatterns": "git@github.com:*"}]}`))
+	require.Equal(t, []string{"$.signatures[0].remotePatterns"}, collectProblemPaths(t, err))
+}
+
+func TestCommitConfig_Validate(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "general", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "specific", RemotePatterns: []string{"git@github.com:user/*"}},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	// Same pattern in a later signature can never win
+	// 后面签名中的相同模式永远无法胜出
+	config.Signatures = append(config.Signatures, &SignatureConfig{Name: "copy", RemotePatterns: []string{"git@github.com:user/*"}})
+	require.Equal(t, []string{"$.signatures[2].remotePatterns[0]"}, collectProblemPaths(t, config.Validate()))
+}
+
+func TestLoadConfigE(t *testing.T) {
+	tempDIR := rese.V1(os.MkdirTemp("", "config-check-test-*"))
+	t.Cleanup(func() { must.Done(os.RemoveAll(tempDIR)) })
+
+	// Missing file returns an error instead of panicking
+	// 文件缺失时返回错误而不是 panic
+	_, err := LoadConfigE(filepath.Join(tempDIR, "missing.json"))
+	require.Error(t, err)
+
+	configPath := filepath.Join(tempDIR, "go-commit-config.json")
+	must.Done(os.WriteFile(configPath, []byte(`{"signatures": [{"name": "x", "mailbox": "bad"}]}`), 0644))
+
+	_, err = LoadConfigE(configPath)
+	var configError *ConfigError
+	require.True(t, errors.As(err, &configError))
+	require.Equal(t, configPath, configError.ConfigPath)
+	require.Contains(t, configError.Error(), "$.signatures[0].mailbox")
+
+	require.Panics(t, func() {
+		LoadConfig(configPath)
+	})
+}
@@ -0,0 +1,211 @@
+// Package commitmate provides static analysis of signature remote patterns
+// Detects ambiguous overlaps, unreachable patterns, and patterns that match none of the sample remotes
+//
+// commitmate 包提供签名远程模式的静态分析
+// 检测有歧义的重叠、不可达的模式以及不匹配任何样本远程的模式
+package commitmate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/yyle88/erero"
+)
+
+// LintSeverity tells how serious a lint finding is
+// LintSeverity 表示检查结果的严重程度
+type LintSeverity string
+
+const (
+	LintSeverityError   LintSeverity = "error"   // Config does not behave as written // 配置的行为与书写不一致
+	LintSeverityWarning LintSeverity = "warning" // Config works but is fragile // 配置可用但较脆弱
+)
+
+// LintKind names the category of a lint finding
+// LintKind 表示检查结果的类别
+type LintKind string
+
+const (
+	LintKindInvalidConfig     LintKind = "invalid-config"      // Field problem found by Validate // Validate 发现的字段问题
+	LintKindAmbiguousOverlap  LintKind = "ambiguous-overlap"   // Equal-score patterns of different signatures overlap // 不同签名中得分相同的模式存在重叠
+	LintKindUnreachable       LintKind = "unreachable"         // Pattern can never select its signature // 模式永远无法选中其签名
+	LintKindUnmatchedSamples  LintKind = "unmatched-samples"   // Pattern matches none of the sample remotes // 模式不匹配任何样本远程
+	LintKindOutscoredOnSample LintKind = "outscored-on-sample" // Pattern matches samples but other signatures win them // 模式匹配样本但其他签名胜出
+)
+
+// LintFinding describes one problem found by the config linter
+// LintFinding 描述配置检查器发现的一个问题
+type LintFinding struct {
+	Severity    LintSeverity // Finding severity // 问题严重程度
+	Kind        LintKind     // Finding category // 问题类别
+	Path        string       // JSON path of the pattern // 模式的 JSON 路径
+	Message     string       // Finding description // 问题描述
+	RelatedPath string       // JSON path of the related pattern, when present // 相关模式的 JSON 路径（如果存在）
+}
+
+// String formats the finding as a single report line
+// String 将问题格式化为单行报告
+func (f *LintFinding) String() string {
+	return fmt.Sprintf("%s %s %s: %s", f.Severity, f.Path, f.Kind, f.Message)
+}
+
+// LintReport collects the findings of one lint run
+// LintReport 收集一次检查的全部结果
+type LintReport struct {
+	Findings    []*LintFinding // Findings sorted by path // 按路径排序的问题
+	SampleCount int            // Count of sample remotes analyzed // 已分析的样本远程数量
+}
+
+// ExitCode maps the report to a process exit code usable in CI
+// Returns 0 when clean, 1 when just warnings exist, 2 when some error exists
+//
+// ExitCode 将报告映射为可在 CI 中使用的进程退出码
+// 无问题返回 0，仅有警告返回 1，存在错误返回 2
+func (r *LintReport) ExitCode() int {
+	exitCode := 0
+	for _, finding := range r.Findings {
+		switch finding.Severity {
+		case LintSeverityError:
+			return 2
+		case LintSeverityWarning:
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// LintConfigFile decodes the config file and lints it against the sample remotes
+// Structural problems such as bad JSON or unknown fields are returned as *ConfigError
+//
+// LintConfigFile 解码配置文件并使用样本远程进行检查
+// 诸如 JSON 错误或未知字段之类的结构性问题以 *ConfigError 返回
+func LintConfigFile(configPath string, sampleRemotes []string) (*LintReport, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	config, problems := decodeConfig(data)
+	if len(problems) > 0 {
+		return nil, &ConfigError{ConfigPath: configPath, Problems: problems}
+	}
+	return config.Lint(sampleRemotes), nil
+}
+
+// Lint analyzes all remote patterns of the configuration
+// Reports equal-score overlaps across signatures that MatchSignature resolves by signature order,
+// patterns that can never win, and patterns that match none of the sample remotes
+//
+// Lint 分析配置中的所有远程模式
+// 报告不同签名间得分相同且重叠（MatchSignature 按签名顺序裁决）的模式、
+// 永远无法胜出的模式，以及不匹配任何样本远程的模式
+func (config *CommitConfig) Lint(sampleRemotes []string) *LintReport {
+	report := &LintReport{SampleCount: len(sampleRemotes)}
+
+	for _, problem := range config.checkFieldProblems() {
+		report.Findings = append(report.Findings, &LintFinding{
+			Severity: LintSeverityError,
+			Kind:     LintKindInvalidConfig,
+			Path:     problem.Path,
+			Message:  problem.Message,
+		})
+	}
+
+	entries := config.listPatternEntries()
+	report.Findings = append(report.Findings, lintPatternEntries(entries)...)
+	if len(sampleRemotes) > 0 {
+		report.Findings = append(report.Findings, lintSampleRemotes(entries, sampleRemotes)...)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Path < report.Findings[j].Path
+	})
+	return report
+}
+
+// lintPatternEntries runs the static analysis on pattern pairs
+// lintPatternEntries 对模式两两进行静态分析
+func lintPatternEntries(entries []*patternEntry) []*LintFinding {
+	var findings []*LintFinding
+	for idx, entry := range entries {
+		if other := findShadowingEntry(entry, entries); other != nil {
+			findings = append(findings, &LintFinding{
+				Severity:    LintSeverityError,
+				Kind:        LintKindUnreachable,
+				Path:        entry.path(),
+				Message:     fmt.Sprintf("pattern %q never wins, %q of signature %q takes each remote it matches", entry.pattern, other.pattern, other.signature.Name),
+				RelatedPath: other.path(),
+			})
+		}
+
+		score := utils.CountPatternScore(entry.pattern)
+		for _, other := range entries[idx+1:] {
+			if other.signatureIdx == entry.signatureIdx || utils.CountPatternScore(other.pattern) != score {
+				continue
+			}
+			if !entry.isSimple() || !other.isSimple() || !utils.IntersectPatterns(entry.pattern, other.pattern) {
+				continue
+			}
+			findings = append(findings, &LintFinding{
+				Severity:    LintSeverityWarning,
+				Kind:        LintKindAmbiguousOverlap,
+				Path:        other.path(),
+				Message:     fmt.Sprintf("pattern %q overlaps %q with equal score %d, ties go to the earlier signature %q", other.pattern, entry.pattern, score, entry.signature.Name),
+				RelatedPath: entry.path(),
+			})
+		}
+	}
+	return findings
+}
+
+// lintSampleRemotes checks each pattern against the sample remotes
+// lintSampleRemotes 使用样本远程检查每个模式
+func lintSampleRemotes(entries []*patternEntry, sampleRemotes []string) []*LintFinding {
+	matchCounts := make([]int, len(entries))
+	signatureWinCounts := make([]int, len(entries))
+
+	scores := make([]int, len(entries))
+	for _, remoteURL := range sampleRemotes {
+		forms := newRemoteForms(remoteURL)
+		winner, bestScore := -1, -1
+		for idx, entry := range entries {
+			scores[idx] = entry.score(forms)
+			if scores[idx] > bestScore {
+				winner, bestScore = idx, scores[idx]
+			}
+		}
+		for idx, entry := range entries {
+			if scores[idx] < 0 {
+				continue
+			}
+			matchCounts[idx]++
+			// Losing to a pattern of the same signature still selects the signature
+			// 输给同一签名中的模式时仍然选中了该签名
+			if entries[winner].signatureIdx == entry.signatureIdx {
+				signatureWinCounts[idx]++
+			}
+		}
+	}
+
+	var findings []*LintFinding
+	for idx, entry := range entries {
+		switch {
+		case matchCounts[idx] == 0:
+			findings = append(findings, &LintFinding{
+				Severity: LintSeverityWarning,
+				Kind:     LintKindUnmatchedSamples,
+				Path:     entry.path(),
+				Message:  fmt.Sprintf("pattern %q matches none of the %d sample remotes", entry.pattern, len(sampleRemotes)),
+			})
+		case signatureWinCounts[idx] == 0:
+			findings = append(findings, &LintFinding{
+				Severity: LintSeverityWarning,
+				Kind:     LintKindOutscoredOnSample,
+				Path:     entry.path(),
+				Message:  fmt.Sprintf("pattern %q matches %d sample remotes but other signatures win each of them", entry.pattern, matchCounts[idx]),
+			})
+		}
+	}
+	return findings
+}
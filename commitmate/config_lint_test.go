@@ -0,0 +1,94 @@
+package commitmate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/must"
+	"github.com/yyle88/rese"
+)
+
+// collectFindingKinds extracts "path kind" pairs from a lint report
+// collectFindingKinds 从检查报告中提取 "路径 类别" 对
+func collectFindingKinds(report *LintReport) []string {
+	var results []string
+	for _, finding := range report.Findings {
+		results = append(results, finding.Path+" "+string(finding.Kind))
+	}
+	return results
+}
+
+func TestCommitConfig_Lint_Clean(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "work", RemotePatterns: []string{"git@github.corp.com:*"}},
+			{Name: "home", RemotePatterns: []string{"git@github.com:home-dev/*"}},
+		},
+	}
+	report := config.Lint([]string{"git@github.corp.com:team/project.git", "git@github.com:home-dev/tool.git"})
+	require.Empty(t, report.Findings)
+	require.Equal(t, 0, report.ExitCode())
+	require.Equal(t, 2, report.SampleCount)
+}
+
+// TestCommitConfig_Lint_Static validates ambiguous and unreachable pattern detection
+// Tests that equal-score overlaps are warnings and shadowed patterns are errors
+//
+// TestCommitConfig_Lint_Static 验证歧义模式和不可达模式的检测
+// 测试得分相同的重叠是警告，被遮蔽的模式是错误
+func TestCommitConfig_Lint_Static(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "org", RemotePatterns: []string{"git@github.com:org/*"}},
+			{Name: "tool", RemotePatterns: []string{"*github.com:org/tool*"}},
+			{Name: "copy", RemotePatterns: []string{"git@github.com:org/*"}},
+		},
+	}
+	report := config.Lint(nil)
+	require.Equal(t, []string{
+		"$.signatures[1].remotePatterns[0] ambiguous-overlap",
+		"$.signatures[2].remotePatterns[0] ambiguous-overlap",
+		"$.signatures[2].remotePatterns[0] ambiguous-overlap",
+		"$.signatures[2].remotePatterns[0] unreachable",
+	}, collectFindingKinds(report))
+	require.Equal(t, 2, report.ExitCode())
+}
+
+func TestCommitConfig_Lint_Samples(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "github", RemotePatterns: []string{"git@github.com:*", "https://github.com/*"}},
+			{Name: "personal", RemotePatterns: []string{"git@github.com:*/*"}},
+			{Name: "gitlab", RemotePatterns: []string{"git@gitlab.com:*"}},
+		},
+	}
+	report := config.Lint([]string{"git@github.com:user/repo.git"})
+	require.Equal(t, []string{
+		"$.signatures[0].remotePatterns[0] outscored-on-sample",
+		"$.signatures[0].remotePatterns[1] unmatched-samples",
+		"$.signatures[2].remotePatterns[0] unmatched-samples",
+	}, collectFindingKinds(report))
+	require.Equal(t, 1, report.ExitCode())
+}
+
+func TestLintConfigFile(t *testing.T) {
+	tempDIR := rese.V1(os.MkdirTemp("", "config-lint-test-*"))
+	t.Cleanup(func() { must.Done(os.RemoveAll(tempDIR)) })
+
+	configPath := filepath.Join(tempDIR, "go-commit-config.json")
+	must.Done(os.WriteFile(configPath, []byte(`{"signatures": [{"name": "a", "mailbox": "bad", "remotePatterns": ["*"]}]}`), 0644))
+
+	// Field problems become lint findings instead of failing the load
+	// 字段问题会成为检查结果，而不会导致加载失败
+	report, err := LintConfigFile(configPath, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"$.signatures[0].mailbox invalid-config"}, collectFindingKinds(report))
+
+	must.Done(os.WriteFile(configPath, []byte(`{"signatures": [{"name": "a", "remote": "*"}]}`), 0644))
+	_, err = LintConfigFile(configPath, nil)
+	var configError *ConfigError
+	require.True(t, errors.As(err, &configError))
+}
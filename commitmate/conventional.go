@@ -0,0 +1,137 @@
+// Package commitmate parses Conventional Commits messages
+// Splits the header into type, scope, breaking mark and description, and reads the trailing footers
+//
+// commitmate 包解析 Conventional Commits 消息
+// 将标题拆分为类型、范围、破坏性标记和描述，并读取末尾的脚注
+package commitmate
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yyle88/erero"
+)
+
+// conventionalHeaderRegexp matches "type(scope)!: description"
+// conventionalHeaderRegexp 匹配 "type(scope)!: description"
+var conventionalHeaderRegexp = regexp.MustCompile(`^([A-Za-z][\w-]*)(?:\(([^()\r\n]*)\))?(!)?: (\S.*)$`)
+
+// conventionalFooterRegexp matches "Token: value", "Token #value" and "BREAKING CHANGE: value"
+// conventionalFooterRegexp 匹配 "Token: value"、"Token #value" 和 "BREAKING CHANGE: value"
+var conventionalFooterRegexp = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][\w-]*)(?:: | #)(.*)$`)
+
+// ConventionalFooter is one footer (trailer) of a conventional commit message
+// ConventionalFooter 是约定式提交消息中的一个脚注（trailer）
+type ConventionalFooter struct {
+	Token string `json:"token"` // Footer token such as "Refs" or "BREAKING CHANGE" // 脚注标记，例如 "Refs" 或 "BREAKING CHANGE"
+	Value string `json:"value"` // Footer value, continuation lines included // 脚注值，包含续行
+}
+
+// ConventionalMessage is a parsed conventional commit message
+// ConventionalMessage 是解析后的约定式提交消息
+type ConventionalMessage struct {
+	Type        string                // Commit type such as "feat" or "fix" // 提交类型，例如 "feat" 或 "fix"
+	Scope       string                // Optional scope in parentheses // 括号中的可选范围
+	Bang        bool                  // Header marked breaking with "!" // 标题使用 "!" 标记为破坏性
+	Description string                // Header description // 标题描述
+	Body        string                // Body between header and footers // 标题与脚注之间的正文
+	Footers     []*ConventionalFooter // Trailing footers // 末尾的脚注
+}
+
+// IsBreaking reports whether the header has "!" or a footer is "BREAKING CHANGE"
+// IsBreaking 判断标题是否带有 "!" 或存在 "BREAKING CHANGE" 脚注
+func (m *ConventionalMessage) IsBreaking() bool {
+	return m.Bang || m.Footer("BREAKING CHANGE") != "" || m.Footer("BREAKING-CHANGE") != ""
+}
+
+// Footer returns the value of the first footer with the token, blank when missing
+// Footer 返回第一个具有该标记的脚注的值，不存在时返回空
+func (m *ConventionalMessage) Footer(token string) string {
+	for _, footer := range m.Footers {
+		if strings.EqualFold(footer.Token, token) {
+			return footer.Value
+		}
+	}
+	return ""
+}
+
+// ParseConventionalMessage parses the message as a conventional commit
+// Returns an error when the header is not "type(scope)!: description"
+//
+// ParseConventionalMessage 将消息解析为约定式提交
+// 当标题不是 "type(scope)!: description" 格式时返回错误
+func ParseConventionalMessage(message string) (*ConventionalMessage, error) {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	header, rest, _ := strings.Cut(message, "\n")
+	matches := conventionalHeaderRegexp.FindStringSubmatch(strings.TrimSpace(header))
+	if matches == nil {
+		return nil, erero.Errorf("header %q is not a conventional commit, use \"type(scope): description\"", header)
+	}
+	result := &ConventionalMessage{
+		Type:        strings.ToLower(matches[1]),
+		Scope:       matches[2],
+		Bang:        matches[3] == "!",
+		Description: matches[4],
+	}
+
+	paragraphs := strings.Split(strings.TrimSpace(rest), "\n\n")
+	if last := len(paragraphs) - 1; paragraphs[last] != "" {
+		if footers := parseConventionalFooters(paragraphs[last]); footers != nil {
+			result.Footers = footers
+			paragraphs = paragraphs[:last]
+		}
+	}
+	result.Body = strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+	return result, nil
+}
+
+// parseConventionalFooters parses the paragraph as footers, returns nil when it does not start with a footer
+// parseConventionalFooters 将段落解析为脚注，段落不以脚注开头时返回 nil
+func parseConventionalFooters(paragraph string) []*ConventionalFooter {
+	var footers []*ConventionalFooter
+	for _, line := range strings.Split(paragraph, "\n") {
+		if matches := conventionalFooterRegexp.FindStringSubmatch(line); matches != nil {
+			footers = append(footers, &ConventionalFooter{Token: matches[1], Value: matches[2]})
+		} else if len(footers) == 0 {
+			return nil
+		} else {
+			last := footers[len(footers)-1]
+			last.Value += "\n" + line
+		}
+	}
+	return footers
+}
+
+// CheckConventionalCommit validates the message as a conventional commit against the staged API changes
+// Breaking API changes need "!" in the header or a "BREAKING CHANGE:" footer
+// Returns the API report so callers can print it
+//
+// CheckConventionalCommit 根据已暂存的 API 更改验证消息是否为约定式提交
+// 破坏性 API 更改需要标题中的 "!" 或 "BREAKING CHANGE:" 脚注
+// 返回 API 报告以便调用方输出
+func CheckConventionalCommit(projectRoot string, message string) (*APIReport, error) {
+	if _, err := ParseConventionalMessage(message); err != nil {
+		return nil, erero.Wro(err)
+	}
+	report, err := CheckAPIChanges(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if err := checkConventionalReport(message, report); err != nil {
+		return report, erero.Wro(err)
+	}
+	return report, nil
+}
+
+// checkConventionalReport validates the message as a conventional commit against the API report
+// checkConventionalReport 根据 API 报告验证消息是否为约定式提交
+func checkConventionalReport(message string, report *APIReport) error {
+	conventional, err := ParseConventionalMessage(message)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	if len(report.BreakingChanges()) > 0 && !conventional.IsBreaking() {
+		return erero.Errorf("breaking API changes need \"%s!: ...\" or a \"BREAKING CHANGE:\" footer\n%s", conventional.Type, report.String())
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalMessage(t *testing.T) {
+	message, err := ParseConventionalMessage("feat(api)!: drop Open\n\nUse Dial instead.\n\nRefs #12\nBREAKING CHANGE: Open is gone,\n  use Dial\nReviewed-by: someone")
+	require.NoError(t, err)
+	require.Equal(t, "feat", message.Type)
+	require.Equal(t, "api", message.Scope)
+	require.True(t, message.Bang)
+	require.Equal(t, "drop Open", message.Description)
+	require.Equal(t, "Use Dial instead.", message.Body)
+	require.Len(t, message.Footers, 3)
+	require.Equal(t, "12", message.Footer("refs"))
+	require.Equal(t, "Open is gone,\n  use Dial", message.Footer("BREAKING CHANGE"))
+	require.True(t, message.IsBreaking())
+
+	message, err = ParseConventionalMessage("fix: handle nil config\n\nthe body: not a footer paragraph\nsince it has two lines")
+	require.NoError(t, err)
+	require.False(t, message.IsBreaking())
+	require.Empty(t, message.Footers)
+	require.Contains(t, message.Body, "since it has two lines")
+
+	_, err = ParseConventionalMessage("Update things")
+	require.ErrorContains(t, err, "not a conventional commit")
+}
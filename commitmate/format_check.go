@@ -0,0 +1,73 @@
+// Package commitmate checks the formatting of changed Go files without writing them
+// Applies the same filter and scope as formatting, so CI enforces the rules developers apply locally
+//
+// commitmate 包检查已改变 Go 文件的格式而不写入文件
+// 使用与格式化相同的过滤器和范围，使 CI 执行与开发者本地相同的规则
+package commitmate
+
+import (
+	"path/filepath"
+
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+)
+
+// formatCheckContext is the count of context lines in the diffs of the format check
+// formatCheckContext 是格式检查差异中的上下文行数
+const formatCheckContext = 3
+
+// FormatCheck is a changed Go file whose content is not formatted
+// FormatCheck 是一个内容未格式化的已改变 Go 文件
+type FormatCheck struct {
+	Path string // Path relative to the repo root // 相对于仓库根目录的路径
+	Diff string // Unified diff from the content to the formatted content // 从当前内容到格式化内容的统一差异
+}
+
+// CheckChangedGoFiles reports the changed Go files that formatting would change, with unified diffs
+// Uses the selection, filter, scope and jobs of the options as FormatGoFiles does, and writes nothing
+//
+// CheckChangedGoFiles 报告格式化会改变的已改变 Go 文件，并附带统一差异
+// 与 FormatGoFiles 一样使用选项中的文件选择、过滤器、范围和任务数，且不写入任何内容
+func CheckChangedGoFiles(projectRoot string, client *gogit.Client, options *FormatOptions) ([]*FormatCheck, error) {
+	if err := checkFormatScope(options.Scope); err != nil {
+		return nil, erero.Wro(err)
+	}
+	targets, err := listFormatTargets(projectRoot, client, options)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	paths := targets.paths
+
+	checks := make([]*FormatCheck, len(paths))
+	err = runFormatJobs(paths, options.Jobs, func(idx int) (bool, error) {
+		content, formatted, err := formatGoContent(paths[idx], targets.bases[idx], options.Scope)
+		if err != nil {
+			return false, erero.Wro(err)
+		}
+		if formatted == content {
+			return false, nil
+		}
+		relPath, err := filepath.Rel(projectRoot, paths[idx])
+		if err != nil {
+			return false, erero.Wro(err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		checks[idx] = &FormatCheck{
+			Path: relPath,
+			Diff: utils.UnifiedDiff("a/"+relPath, "b/"+relPath, content, formatted, formatCheckContext),
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	var results []*FormatCheck
+	for _, check := range checks {
+		if check != nil {
+			results = append(results, check)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,51 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xlan/gogit"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// TestCheckChangedGoFiles validates reporting unformatted changed files without writing or staging
+// Tests the DefaultAllowFormat exclusions and the hunks scope apply the same as in formatting
+//
+// TestCheckChangedGoFiles 验证报告未格式化的已改变文件，且不写入也不暂存
+// 测试 DefaultAllowFormat 排除规则和 hunks 范围与格式化时一致
+func TestCheckChangedGoFiles(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "demo.go", legacySource, &CommitFlags{Message: "add legacy"})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.go"), []byte(legacyChange), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "demo.pb.go"), []byte(unformattedSource(1)), 0644))
+	client := rese.P1(gogit.New(tempDIR))
+
+	checks, err := CheckChangedGoFiles(tempDIR, client, NewFormatOptions())
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	require.Equal(t, "demo.go", checks[0].Path)
+	require.Contains(t, checks[0].Diff, "--- a/demo.go\n+++ b/demo.go\n")
+	require.Contains(t, checks[0].Diff, "-func Old()  int {\n-\treturn   1\n+func Old() int {\n+\treturn 1\n")
+	require.Contains(t, checks[0].Diff, "-\tvalue :=   20\n")
+
+	options := NewFormatOptions()
+	options.Scope = FormatScopeHunks
+	checks, err = CheckChangedGoFiles(tempDIR, client, options)
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	require.NotContains(t, checks[0].Diff, "func Old()")
+	require.Contains(t, checks[0].Diff, "+\tvalue := 20\n")
+
+	require.Equal(t, legacyChange, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+	status := rese.V1(client.Tree().Status())
+	require.False(t, hasStagedChanges(status))
+
+	require.NoError(t, FormatChangedGoFilesWithOptions(tempDIR, client, options))
+	checks, err = CheckChangedGoFiles(tempDIR, client, options)
+	require.NoError(t, err)
+	require.Empty(t, checks)
+}
@@ -0,0 +1,60 @@
+// Package commitmate runs Go formatting jobs with a bounded worker pool
+// Logs the results in input order and cancels the remaining jobs on the first error
+//
+// commitmate 包使用有限的工作池运行 Go 格式化任务
+// 按输入顺序记录结果，并在出现第一个错误时取消剩余任务
+package commitmate
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// runFormatJobs runs the format function on each name with at most jobs at once, 0 means the CPU count
+// The function reports whether the content changed, results are logged in the order of the names
+//
+// runFormatJobs 对每个名称运行格式化函数，同时最多运行 jobs 个，0 表示 CPU 数量
+// 函数报告内容是否改变，结果按名称顺序记录
+func runFormatJobs(names []string, jobs int, format func(idx int) (bool, error)) error {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	changes := make([]*bool, len(names))
+
+	group, ctx := errgroup.WithContext(context.Background())
+	group.SetLimit(jobs)
+	for idx := range names {
+		// Stop handing out jobs once some job fails
+		// 一旦有任务失败就停止分发任务
+		if ctx.Err() != nil {
+			break
+		}
+		group.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			changed, err := format(idx)
+			if err != nil {
+				return erero.Wro(err)
+			}
+			changes[idx] = &changed
+			return nil
+		})
+	}
+	err := group.Wait()
+
+	for idx, changed := range changes {
+		if changed != nil {
+			zaplog.LOG.Info("golang-format-source", zap.String("path", names[idx]), zap.Bool("changed", *changed))
+		}
+	}
+	if err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
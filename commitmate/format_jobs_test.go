@@ -0,0 +1,107 @@
+package commitmate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-xlan/gogit"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/rese"
+)
+
+// unformattedSource returns a Go file with formatting issues in each function
+// unformattedSource 返回每个函数中都有格式问题的 Go 文件
+func unformattedSource(number int) string {
+	return fmt.Sprintf("package gen\n\nfunc F%d()  int {\n\tvalue :=   %d\n\treturn  value\n}\n\nvar  V%d = map[string]int{\"a\":1,\"bb\":2}\n", number, number, number)
+}
+
+// setupFormatJobsRepo commits the Go files and then rewrites them unformatted
+// setupFormatJobsRepo 提交 Go 文件，然后以未格式化的内容重写它们
+func setupFormatJobsRepo(t testing.TB, count int) (string, func()) {
+	tempDIR, cleanup := setupTestRepo()
+	for number := 0; number < count; number++ {
+		path := filepath.Join(tempDIR, "gen", fmt.Sprintf("f%04d.go", number))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("package gen\n"), 0644))
+	}
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add gen"}))
+	writeUnformattedFiles(t, tempDIR, count)
+	return tempDIR, cleanup
+}
+
+// writeUnformattedFiles rewrites the generated Go files unformatted
+// writeUnformattedFiles 以未格式化的内容重写生成的 Go 文件
+func writeUnformattedFiles(t testing.TB, projectRoot string, count int) {
+	for number := 0; number < count; number++ {
+		path := filepath.Join(projectRoot, "gen", fmt.Sprintf("f%04d.go", number))
+		require.NoError(t, os.WriteFile(path, []byte(unformattedSource(number)), 0644))
+	}
+}
+
+// TestFormatChangedGoFilesWithOptions_Jobs validates formatting each changed file with concurrent jobs
+// TestFormatChangedGoFilesWithOptions_Jobs 验证使用并发任务格式化每个已改变的文件
+func TestFormatChangedGoFilesWithOptions_Jobs(t *testing.T) {
+	tempDIR, cleanup := setupFormatJobsRepo(t, 40)
+	t.Cleanup(cleanup)
+
+	options := NewFormatOptions()
+	options.Jobs = 4
+	require.NoError(t, FormatChangedGoFilesWithOptions(tempDIR, rese.P1(gogit.New(tempDIR)), options))
+	for _, number := range []int{0, 17, 39} {
+		content := string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "gen", fmt.Sprintf("f%04d.go", number)))))
+		require.Contains(t, content, fmt.Sprintf("func F%d() int {\n\tvalue := %d\n\treturn value\n}", number, number))
+	}
+}
+
+func TestRunFormatJobs_Cancel(t *testing.T) {
+	names := make([]string, 200)
+	for idx := range names {
+		names[idx] = fmt.Sprintf("f%d.go", idx)
+	}
+	var calls atomic.Int32
+	err := runFormatJobs(names, 2, func(idx int) (bool, error) {
+		calls.Add(1)
+		if idx == 3 {
+			return false, erero.New("syntax error")
+		}
+		return true, nil
+	})
+	require.ErrorContains(t, err, "syntax error")
+	require.Less(t, int(calls.Load()), len(names))
+
+	calls.Store(0)
+	require.NoError(t, runFormatJobs(names, 0, func(idx int) (bool, error) {
+		calls.Add(1)
+		return idx%2 == 0, nil
+	}))
+	require.Equal(t, int32(len(names)), calls.Load())
+}
+
+// BenchmarkFormatChangedGoFiles compares sequential and concurrent formatting on a synthetic repo
+// The speedup follows the CPU count, jobs beyond it add nothing
+//
+// BenchmarkFormatChangedGoFiles 在合成仓库上比较顺序格式化与并发格式化
+// 加速比取决于 CPU 数量，超过 CPU 数量的任务数不会带来提升
+func BenchmarkFormatChangedGoFiles(b *testing.B) {
+	const count = 2000
+	tempDIR, cleanup := setupFormatJobsRepo(b, count)
+	b.Cleanup(cleanup)
+	client := rese.P1(gogit.New(tempDIR))
+
+	for _, jobs := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			options := NewFormatOptions()
+			options.Jobs = jobs
+			for b.Loop() {
+				b.StopTimer()
+				writeUnformattedFiles(b, tempDIR, count)
+				b.StartTimer()
+				require.NoError(b, FormatChangedGoFilesWithOptions(tempDIR, client, options))
+			}
+		})
+	}
+}
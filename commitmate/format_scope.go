@@ -0,0 +1,274 @@
+// Package commitmate limits Go formatting to the changed lines of each file
+// Keeps formatting edits that overlap the lines changed against HEAD or their enclosing declarations
+//
+// commitmate 包将 Go 格式化限制在每个文件的已更改行上
+// 保留与相对 HEAD 已更改的行或其所在声明重叠的格式化编辑
+package commitmate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/formatgo"
+	"github.com/yyle88/tern/zerotern"
+)
+
+// Format scopes of the changed Go files
+// 已更改 Go 文件的格式化范围
+const (
+	FormatScopeFile  = "file"  // Format the whole file // 格式化整个文件
+	FormatScopeHunks = "hunks" // Format the changed lines and their declarations // 格式化已更改的行及其所在声明
+)
+
+// FormatOptions configures formatting of the changed Go files
+// FormatOptions 配置已更改 Go 文件的格式化
+type FormatOptions struct {
+	AllowFormat func(path string) bool          // Filter of the absolute file paths // 绝对文件路径的过滤函数
+	Scope       string                          // Format scope, file or hunks // 格式化范围，file 或 hunks
+	Jobs        int                             // Files formatted at once, 0 means the CPU count // 同时格式化的文件数，0 表示 CPU 数量
+	All         bool                            // Select every tracked Go file, not just the changed ones // 选择所有已跟踪的 Go 文件，而不仅是已改变的
+	Since       string                          // Select the files changed since the merge base with the revision // 选择自与该修订的合并基准以来改变的文件
+	Progress    func(path string, changed bool) // Called as each file completes, one call at a time // 每个文件完成时调用，每次一个调用
+}
+
+// NewFormatOptions creates options formatting whole files with DefaultAllowFormat
+// NewFormatOptions 创建使用 DefaultAllowFormat 格式化整个文件的选项
+func NewFormatOptions() *FormatOptions {
+	return &FormatOptions{
+		AllowFormat: DefaultAllowFormat,
+		Scope:       FormatScopeFile,
+	}
+}
+
+// GetFormatOptions returns the format options of the flags, a blank scope formats whole files
+// GetFormatOptions 返回标志对应的格式化选项，范围为空时格式化整个文件
+func (f *CommitFlags) GetFormatOptions() *FormatOptions {
+	options := NewFormatOptions()
+	options.Scope = zerotern.VV(f.FormatScope, FormatScopeFile)
+	options.Jobs = f.Jobs
+	return options
+}
+
+// checkFormatScope returns an error when the scope is unknown
+// checkFormatScope 在范围未知时返回错误
+func checkFormatScope(scope string) error {
+	switch scope {
+	case "", FormatScopeFile, FormatScopeHunks:
+		return nil
+	default:
+		return erero.Errorf("unknown format scope %q, use file or hunks", scope)
+	}
+}
+
+// formatSource formats the source in the scope, the hunks scope falls back to the whole file when the file is new
+// formatSource 按范围格式化源码，文件为新文件时 hunks 范围回退为整个文件
+func formatSource(source string, base string, isNew bool, scope string) (string, error) {
+	formatted, err := formatgo.FormatBytes([]byte(source))
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	if scope != FormatScopeHunks || isNew {
+		return string(formatted), nil
+	}
+	allowed, err := expandToDeclarations(source, changedLines(base, source))
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	return applyAllowedEdits(source, string(formatted), allowed), nil
+}
+
+// diffLines diffs the two texts line by line, each diff text holds whole lines
+// diffLines 逐行比较两个文本，每个差异文本都包含完整的行
+func diffLines(before string, after string) []diffmatchpatch.Diff {
+	dmp := diffmatchpatch.New()
+	chars1, chars2, lineArray := dmp.DiffLinesToChars(before, after)
+	return dmp.DiffCharsToLines(dmp.DiffMain(chars1, chars2, false), lineArray)
+}
+
+// countLines returns the number of lines in the diff text
+// countLines 返回差异文本中的行数
+func countLines(text string) int {
+	count := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		count++
+	}
+	return count
+}
+
+// changedLines returns the 1-based lines of the current text changed against the base
+// Pure deletions mark the lines around the place they were removed from
+//
+// changedLines 返回当前文本中相对基准已更改的行（从 1 开始）
+// 纯删除会标记被删除位置前后的行
+func changedLines(base string, current string) map[int]bool {
+	lines := map[int]bool{}
+	line, deletedAt := 1, 0
+	markDeletion := func() {
+		if deletedAt > 0 {
+			lines[deletedAt-1] = true
+			lines[deletedAt] = true
+			deletedAt = 0
+		}
+	}
+	for _, diff := range diffLines(base, current) {
+		count := countLines(diff.Text)
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			markDeletion()
+			line += count
+		case diffmatchpatch.DiffInsert:
+			deletedAt = 0
+			for idx := 0; idx < count; idx++ {
+				lines[line+idx] = true
+			}
+			line += count
+		case diffmatchpatch.DiffDelete:
+			if !lines[line-1] {
+				deletedAt = line
+			}
+		}
+	}
+	markDeletion()
+	delete(lines, 0)
+	return lines
+}
+
+// expandToDeclarations adds the lines of each top-level declaration holding a changed line
+// expandToDeclarations 添加包含已更改行的每个顶层声明的所有行
+func expandToDeclarations(source string, lines map[int]bool) (map[int]bool, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	allowed := make(map[int]bool, len(lines))
+	for line := range lines {
+		allowed[line] = true
+	}
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		switch node := decl.(type) {
+		case *ast.FuncDecl:
+			if node.Doc != nil {
+				start = node.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if node.Doc != nil {
+				start = node.Doc.Pos()
+			}
+		}
+		first, last := fset.Position(start).Line, fset.Position(decl.End()).Line
+		if !overlapsLines(lines, first, last) {
+			continue
+		}
+		for line := first; line <= last; line++ {
+			allowed[line] = true
+		}
+	}
+	return allowed, nil
+}
+
+// overlapsLines reports whether some line in [first, last] is in the set
+// overlapsLines 判断 [first, last] 中是否有行在集合中
+func overlapsLines(lines map[int]bool, first int, last int) bool {
+	for line := first; line <= last; line++ {
+		if lines[line] {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAllowedEdits keeps the formatting edits overlapping the allowed lines of the source
+// Each run of adjacent deletions and insertions is one edit, kept or dropped as a whole
+//
+// applyAllowedEdits 保留与源码允许行重叠的格式化编辑
+// 每段相邻的删除和插入为一个编辑，整体保留或丢弃
+func applyAllowedEdits(source string, formatted string, allowed map[int]bool) string {
+	var result strings.Builder
+	var deleted, inserted strings.Builder
+	line, editStart := 1, 1
+	flush := func() {
+		last := line - 1
+		if last < editStart {
+			// Pure insertion, check the lines around it
+			// 纯插入，检查其前后的行
+			editStart, last = editStart-1, editStart
+		}
+		if overlapsLines(allowed, editStart, last) {
+			result.WriteString(inserted.String())
+		} else {
+			result.WriteString(deleted.String())
+		}
+		deleted.Reset()
+		inserted.Reset()
+	}
+
+	inEdit := false
+	for _, diff := range diffLines(source, formatted) {
+		if diff.Type == diffmatchpatch.DiffEqual {
+			if inEdit {
+				flush()
+				inEdit = false
+			}
+			result.WriteString(diff.Text)
+			line += countLines(diff.Text)
+			continue
+		}
+		if !inEdit {
+			inEdit, editStart = true, line
+		}
+		if diff.Type == diffmatchpatch.DiffDelete {
+			deleted.WriteString(diff.Text)
+			line += countLines(diff.Text)
+		} else {
+			inserted.WriteString(diff.Text)
+		}
+	}
+	if inEdit {
+		flush()
+	}
+	return result.String()
+}
+
+// formatGoFile formats the file in the scope against its HEAD content, nil base means a new file
+// Returns whether the file content changed
+//
+// formatGoFile 相对 HEAD 内容按范围格式化文件，base 为 nil 表示新文件
+// 返回文件内容是否改变
+func formatGoFile(absPath string, base *string, scope string) (bool, error) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return false, erero.Wro(err)
+	}
+	content, formatted, err := formatGoContent(absPath, base, scope)
+	if err != nil {
+		return false, erero.Wro(err)
+	}
+	if formatted == content {
+		return false, nil
+	}
+	if err := os.WriteFile(absPath, []byte(formatted), info.Mode().Perm()); err != nil {
+		return false, erero.Wro(err)
+	}
+	return true, nil
+}
+
+// formatGoContent reads the file and returns its content with the formatted content, without writing
+// formatGoContent 读取文件并返回其内容和格式化后的内容，不写入文件
+func formatGoContent(absPath string, base *string, scope string) (string, string, error) {
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", "", erero.Wro(err)
+	}
+	formatted, err := formatFileSource(absPath, string(content), base, scope)
+	if err != nil {
+		return "", "", erero.Wro(err)
+	}
+	return string(content), formatted, nil
+}
@@ -0,0 +1,55 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+const (
+	legacySource = "package demo\n\nfunc Old()  int {\n\treturn   1\n}\n\nfunc Changed() int {\n\treturn 2\n}\n\nvar  legacy = 3\n"
+	legacyChange = "package demo\n\nfunc Old()  int {\n\treturn   1\n}\n\nfunc Changed() int {\n\tvalue :=   20\n\treturn  value\n}\n\nvar  legacy = 3\n"
+)
+
+// TestFormatSource validates the hunks scope keeps formatting of the changed declaration
+// Tests untouched legacy declarations keep their formatting and new files format as a whole
+//
+// TestFormatSource 验证 hunks 范围保留已更改声明的格式化
+// 测试未改动的旧声明保持原有格式，新文件整体格式化
+func TestFormatSource(t *testing.T) {
+	formatted, err := formatSource(legacyChange, legacySource, false, FormatScopeHunks)
+	require.NoError(t, err)
+	require.Equal(t, "package demo\n\nfunc Old()  int {\n\treturn   1\n}\n\nfunc Changed() int {\n\tvalue := 20\n\treturn value\n}\n\nvar  legacy = 3\n", formatted)
+
+	formatted, err = formatSource(legacyChange, "", true, FormatScopeHunks)
+	require.NoError(t, err)
+	require.Equal(t, "package demo\n\nfunc Old() int {\n\treturn 1\n}\n\nfunc Changed() int {\n\tvalue := 20\n\treturn value\n}\n\nvar legacy = 3\n", formatted)
+
+	formatted, err = formatSource(legacyChange, legacySource, false, FormatScopeFile)
+	require.NoError(t, err)
+	require.Contains(t, formatted, "func Old() int {")
+}
+
+func TestChangedLines(t *testing.T) {
+	require.Equal(t, map[int]bool{8: true, 9: true}, changedLines(legacySource, legacyChange))
+	require.Equal(t, map[int]bool{2: true, 3: true}, changedLines("a\nb\nc\nd\n", "a\nb\nd\n"))
+}
+
+func TestGitCommit_FormatScopeHunks(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "demo.go", legacySource, &CommitFlags{Message: "add legacy"})
+	commitTestFile(t, tempDIR, "demo.go", legacyChange, &CommitFlags{Message: "change", FormatGo: true, FormatScope: FormatScopeHunks})
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	content := readTestFile(t, headCommit, "demo.go")
+	require.Contains(t, content, "func Old()  int {")
+	require.Contains(t, content, "\tvalue := 20\n\treturn value\n")
+	require.Equal(t, content, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+}
@@ -0,0 +1,157 @@
+// Package commitmate selects the Go files to format, changed, changed since a base revision, or all tracked
+// Counts the files the filter skips as generated and reads base content of the hunks scope up front
+//
+// commitmate 包选择需要格式化的 Go 文件：已改变的、自基准修订以来改变的或所有已跟踪的
+// 统计被过滤器作为生成文件跳过的文件数，并预先读取 hunks 范围的基准内容
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-xlan/gogit"
+	"github.com/go-xlan/gogit/gogitchange"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// formatTargets holds the Go files selected to format
+// formatTargets 保存选中需要格式化的 Go 文件
+type formatTargets struct {
+	paths   []string  // Absolute paths of the files // 文件的绝对路径
+	bases   []*string // Base content of each file in the hunks scope, nil means new // hunks 范围中每个文件的基准内容，nil 表示新文件
+	skipped int       // Go files the filter rejected // 被过滤器拒绝的 Go 文件数
+}
+
+// listFormatTargets lists the Go files the options select and allow, sorted by path
+// The base is the merge base with Since, else HEAD
+//
+// listFormatTargets 列出选项选中且允许的 Go 文件，按路径排序
+// 设置 Since 时基准为合并基准，否则为 HEAD
+func listFormatTargets(projectRoot string, client *gogit.Client, options *FormatOptions) (*formatTargets, error) {
+	repo := client.Repo()
+	baseFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	var names []string
+	switch {
+	case options.All:
+		trackedFiles, err := indexFiles(repo)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		for name := range trackedFiles {
+			names = append(names, name)
+		}
+	case options.Since != "":
+		mergeBaseFiles, err := sinceMergeBaseFiles(repo, options.Since)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		// Files changed by the commits on the branch, then the uncommitted changes
+		// 分支上的提交改变的文件，然后是未提交的更改
+		for name, headFile := range baseFiles {
+			if mergeBaseFiles[name] != headFile {
+				names = append(names, name)
+			}
+		}
+		changedNames, err := listChangedNames(projectRoot, client)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		names = append(names, changedNames...)
+		baseFiles = mergeBaseFiles
+	default:
+		if names, err = listChangedNames(projectRoot, client); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	targets := &formatTargets{}
+	for _, name := range names {
+		absPath := filepath.Join(projectRoot, filepath.FromSlash(name))
+		if !isFormatTarget(name) {
+			continue
+		}
+		// Skip files deleted in the working tree
+		// 跳过已在工作树中删除的文件
+		if _, err := os.Stat(absPath); err != nil {
+			continue
+		}
+		if !options.AllowFormat(absPath) {
+			zaplog.SUG.Debugln("skip:", absPath)
+			targets.skipped++
+			continue
+		}
+		zaplog.SUG.Debugln("pass:", absPath)
+
+		// Read base content up front in the hunks scope, jobs then touch just the files
+		// 在 hunks 范围中预先读取基准内容，任务只需处理文件
+		var base *string
+		if baseFile, exists := baseFiles[name]; exists && options.Scope == FormatScopeHunks {
+			content, err := readBlob(repo, baseFile.hash)
+			if err != nil {
+				return nil, erero.Wro(err)
+			}
+			base = &content
+		}
+		targets.paths = append(targets.paths, absPath)
+		targets.bases = append(targets.bases, base)
+	}
+	return targets, nil
+}
+
+// listChangedNames lists the Go, go.mod and go.work files changed in the working tree as slash paths relative to the repo root
+// listChangedNames 列出工作树中已改变的 Go、go.mod 和 go.work 文件，路径为相对于仓库根目录的斜杠路径
+func listChangedNames(projectRoot string, client *gogit.Client) ([]string, error) {
+	matchOptions := gogitchange.NewMatchOptions().MatchPath(isFormatTarget)
+	changedPaths, err := gogitchange.NewChangedFileManager(projectRoot, client.Tree()).ListChangedFilePaths(matchOptions)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	names := make([]string, 0, len(changedPaths))
+	for _, changedPath := range changedPaths {
+		relPath, err := filepath.Rel(projectRoot, changedPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		names = append(names, filepath.ToSlash(relPath))
+	}
+	return names, nil
+}
+
+// sinceMergeBaseFiles returns the files of the merge base between HEAD and the revision
+// sinceMergeBaseFiles 返回 HEAD 与修订之间合并基准的文件
+func sinceMergeBaseFiles(repo *git.Repository, revision string) (map[string]treeFile, error) {
+	sinceHash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	sinceCommit, err := repo.CommitObject(*sinceHash)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	headReference, err := repo.Head()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	headCommit, err := repo.CommitObject(headReference.Hash())
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	mergeBases, err := headCommit.MergeBase(sinceCommit)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if len(mergeBases) == 0 {
+		return nil, erero.Errorf("no merge base between HEAD and %s", revision)
+	}
+	return commitFiles(mergeBases[0])
+}
@@ -0,0 +1,52 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-xlan/gogit"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// TestFormatGoFiles validates selecting every tracked file with All and the branch changes with Since
+// Tests the summary counts the files skipped as generated
+//
+// TestFormatGoFiles 验证使用 All 选择所有已跟踪的文件，使用 Since 选择分支上的更改
+// 测试统计结果包含作为生成文件跳过的文件数
+func TestFormatGoFiles(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "old.go", unformattedSource(1), &CommitFlags{Message: "add old"})
+	commitTestFile(t, tempDIR, "gen.pb.go", unformattedSource(2), &CommitFlags{Message: "add gen"})
+	commitTestFile(t, tempDIR, "branch.go", unformattedSource(3), &CommitFlags{Message: "add branch"})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "work.go"), []byte(unformattedSource(4)), 0644))
+	client := rese.P1(gogit.New(tempDIR))
+
+	var progress []string
+	options := NewFormatOptions()
+	options.Since = "HEAD~2"
+	options.Progress = func(path string, changed bool) {
+		require.True(t, changed)
+		progress = append(progress, filepath.Base(path))
+	}
+	summary, err := FormatGoFiles(tempDIR, client, options)
+	require.NoError(t, err)
+	require.Equal(t, &FormatSummary{Scanned: 3, Changed: 2, Skipped: 1}, summary)
+	require.ElementsMatch(t, []string{"branch.go", "work.go"}, progress)
+	require.Equal(t, unformattedSource(1), string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "old.go")))))
+
+	options = NewFormatOptions()
+	options.All = true
+	summary, err = FormatGoFiles(tempDIR, client, options)
+	require.NoError(t, err)
+	require.Equal(t, &FormatSummary{Scanned: 3, Changed: 1, Skipped: 1}, summary)
+	require.NotEqual(t, unformattedSource(1), string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "old.go")))))
+	require.Equal(t, unformattedSource(2), string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "gen.pb.go")))))
+
+	options.All, options.Since = false, "no-such-ref"
+	_, err = FormatGoFiles(tempDIR, client, options)
+	require.Error(t, err)
+}
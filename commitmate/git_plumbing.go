@@ -0,0 +1,379 @@
+// Package commitmate provides go-git plumbing helpers used in history rewriting
+// Flattens trees into path maps, replays commit changes onto other trees, and writes trees and blobs
+//
+// commitmate 包提供历史重写中使用的 go-git 底层辅助函数
+// 将文件树展开为路径映射，将提交的更改重放到其他文件树上，并写入文件树和数据对象
+package commitmate
+
+import (
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/yyle88/erero"
+)
+
+// treeFile is one file entry of a flattened tree
+// treeFile 是展开后文件树中的一个文件条目
+type treeFile struct {
+	mode filemode.FileMode // File mode // 文件模式
+	hash plumbing.Hash     // Blob hash // 数据对象哈希
+}
+
+// flattenTree maps each file path of the tree to its entry, a nil tree gives an empty map
+// flattenTree 将文件树中的每个文件路径映射到其条目，nil 文件树返回空映射
+func flattenTree(tree *object.Tree) (map[string]treeFile, error) {
+	files := map[string]treeFile{}
+	if tree == nil {
+		return files, nil
+	}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if entry.Mode != filemode.Dir {
+			files[name] = treeFile{mode: entry.Mode, hash: entry.Hash}
+		}
+	}
+}
+
+// commitFiles flattens the tree of the commit, a nil commit gives an empty map
+// commitFiles 展开提交的文件树，nil 提交返回空映射
+func commitFiles(commitObject *object.Commit) (map[string]treeFile, error) {
+	if commitObject == nil {
+		return map[string]treeFile{}, nil
+	}
+	tree, err := commitObject.Tree()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return flattenTree(tree)
+}
+
+// applyCommitChange replays the change between the parent and the commit onto the files
+// Paths untouched on the files side take the commit side, both-changed text files are merged by patches
+// Returns the first conflicting path when the change cannot be applied
+//
+// applyCommitChange 将父提交与提交之间的更改重放到文件上
+// 文件侧未改动的路径直接采用提交侧，两侧都改动的文本文件通过补丁合并
+// 无法应用更改时返回第一个冲突的路径
+func applyCommitChange(repo *git.Repository, parent *object.Commit, commitObject *object.Commit, files map[string]treeFile) (string, error) {
+	beforeFiles, err := commitFiles(parent)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	afterFiles, err := commitFiles(commitObject)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+
+	paths := make([]string, 0, len(afterFiles))
+	for name := range afterFiles {
+		paths = append(paths, name)
+	}
+	for name := range beforeFiles {
+		if _, exists := afterFiles[name]; !exists {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, name := range paths {
+		before, after, current := beforeFiles[name], afterFiles[name], files[name]
+		switch {
+		case before == after || current == after:
+			continue
+		case current == before:
+			setTreeFile(files, name, after)
+		case !before.hash.IsZero() && !after.hash.IsZero() && !current.hash.IsZero() && before.mode == after.mode && current.mode == after.mode:
+			mergedHash, ok, err := mergeBlob(repo, before.hash, after.hash, current.hash)
+			if err != nil {
+				return "", erero.Wro(err)
+			}
+			if !ok {
+				return name, nil
+			}
+			files[name] = treeFile{mode: after.mode, hash: mergedHash}
+		default:
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// setTreeFile sets the entry, a zero entry removes the path
+// setTreeFile 设置条目，零值条目表示删除该路径
+func setTreeFile(files map[string]treeFile, name string, file treeFile) {
+	if file.hash.IsZero() {
+		delete(files, name)
+	} else {
+		files[name] = file
+	}
+}
+
+// mergeBlob applies the line changes from the before content to the after content onto the current content
+// Returns false when some change does not apply
+//
+// mergeBlob 将从修改前内容到修改后内容的逐行更改应用到当前内容上
+// 当有更改无法应用时返回 false
+func mergeBlob(repo *git.Repository, beforeHash, afterHash, currentHash plumbing.Hash) (plumbing.Hash, bool, error) {
+	var contents [3]string
+	for idx, hash := range []plumbing.Hash{beforeHash, afterHash, currentHash} {
+		content, err := readBlob(repo, hash)
+		if err != nil {
+			return plumbing.ZeroHash, false, erero.Wro(err)
+		}
+		contents[idx] = content
+	}
+
+	merged, ok := mergeText(contents[0], contents[1], contents[2])
+	if !ok {
+		return plumbing.ZeroHash, false, nil
+	}
+	hash, err := writeBlob(repo, merged)
+	if err != nil {
+		return plumbing.ZeroHash, false, erero.Wro(err)
+	}
+	return hash, true, nil
+}
+
+// mergeText applies the line changes from the before text to the after text onto the current text
+// Changes of both sides that overlap or touch form one chunk, as in git merge-file
+// A chunk takes the side that changed it, or either side when both made the same change
+// Returns false when both sides changed a chunk differently
+//
+// mergeText 将从修改前文本到修改后文本的逐行更改应用到当前文本上
+// 与 git merge-file 一样，两边重叠或相接的更改组成一个块
+// 块采用改变了它的一边，当两边做出相同更改时采用任意一边
+// 当两边以不同方式改变同一个块时返回 false
+func mergeText(before, after, current string) (string, bool) {
+	afterChanges, currentChanges := lineChanges(before, after), lineChanges(before, current)
+	changes := slices.Concat(afterChanges, currentChanges)
+	slices.SortStableFunc(changes, func(a, b *lineChange) int {
+		return a.start - b.start
+	})
+
+	lines := splitLines(before)
+	var merged []string
+	line := 0
+	for idx := 0; idx < len(changes); {
+		// Collect the chunk of changes overlapping or touching each other
+		// 收集彼此重叠或相接的更改块
+		start, end := changes[idx].start, changes[idx].end
+		next := idx + 1
+		for ; next < len(changes) && changes[next].start <= end; next++ {
+			end = max(end, changes[next].end)
+		}
+		chunk := changes[idx:next]
+		idx = next
+
+		afterLines, afterChanged := applyLineChanges(lines, start, end, chunk, afterChanges)
+		currentLines, currentChanged := applyLineChanges(lines, start, end, chunk, currentChanges)
+		if afterChanged && currentChanged && !slices.Equal(afterLines, currentLines) {
+			return "", false
+		}
+		merged = append(merged, lines[line:start]...)
+		if afterChanged {
+			merged = append(merged, afterLines...)
+		} else {
+			merged = append(merged, currentLines...)
+		}
+		line = end
+	}
+	merged = append(merged, lines[line:]...)
+	return strings.Join(merged, ""), true
+}
+
+// applyLineChanges applies the changes of the chunk that belong to the side onto the lines [start, end)
+// Returns whether the side has some change in the chunk
+//
+// applyLineChanges 将块中属于这一边的更改应用到 [start, end) 行上
+// 返回这一边在块中是否有更改
+func applyLineChanges(lines []string, start, end int, chunk []*lineChange, side []*lineChange) ([]string, bool) {
+	var result []string
+	line, changed := start, false
+	for _, change := range chunk {
+		if !slices.Contains(side, change) {
+			continue
+		}
+		result = append(append(result, lines[line:change.start]...), change.lines...)
+		line, changed = change.end, true
+	}
+	return append(result, lines[line:end]...), changed
+}
+
+// lineChange replaces the lines [start, end) of the before text with the lines
+// lineChange 将修改前文本的 [start, end) 行替换为 lines
+type lineChange struct {
+	start int      // First replaced line // 第一个被替换的行
+	end   int      // Line after the last replaced one // 最后一个被替换行的下一行
+	lines []string // New lines with their line endings // 包含行尾的新行
+}
+
+// lineChanges lists the changes from the before text to the after text, in the order of the lines
+// Pure insertions and deletions slide down a run of equal lines as far as they go, as git does
+// So both sides of a merge place the same change within repeated lines at the same line
+//
+// lineChanges 按行的顺序列出从修改前文本到修改后文本的更改
+// 与 git 一样，纯插入和纯删除会在相同行的连续序列中尽量向下滑动
+// 这样合并的两边会把重复行中的相同更改放在同一行
+func lineChanges(before, after string) []*lineChange {
+	var changes []*lineChange
+	var change *lineChange
+	line := 0
+	for _, diff := range diffLines(before, after) {
+		if diff.Type == diffmatchpatch.DiffEqual {
+			change = nil
+			line += countLines(diff.Text)
+			continue
+		}
+		if change == nil {
+			change = &lineChange{start: line, end: line}
+			changes = append(changes, change)
+		}
+		if diff.Type == diffmatchpatch.DiffDelete {
+			line += countLines(diff.Text)
+			change.end = line
+		} else {
+			change.lines = append(change.lines, splitLines(diff.Text)...)
+		}
+	}
+
+	lines := splitLines(before)
+	for idx, change := range changes {
+		limit := len(lines)
+		if idx+1 < len(changes) {
+			limit = changes[idx+1].start
+		}
+		switch {
+		case len(change.lines) == 0:
+			for change.end < limit && lines[change.end] == lines[change.start] {
+				change.start, change.end = change.start+1, change.end+1
+			}
+		case change.start == change.end:
+			for change.end < limit && lines[change.end] == change.lines[0] {
+				change.lines = append(change.lines[1:], lines[change.end])
+				change.start, change.end = change.start+1, change.end+1
+			}
+		}
+	}
+	return changes
+}
+
+// splitLines splits the text into lines that keep their line endings
+// splitLines 将文本拆分为保留行尾的行
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// readBlob reads the content of the blob
+// readBlob 读取数据对象的内容
+func readBlob(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	return string(content), nil
+}
+
+// writeBlob stores the content as a blob
+// writeBlob 将内容存储为数据对象
+func writeBlob(repo *git.Repository, content string) (plumbing.Hash, error) {
+	encodedObject := repo.Storer.NewEncodedObject()
+	encodedObject.SetType(plumbing.BlobObject)
+	writer, err := encodedObject.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	return repo.Storer.SetEncodedObject(encodedObject)
+}
+
+// writeTree stores the flattened files as nested trees and returns the root tree hash
+// writeTree 将展开的文件存储为嵌套的文件树并返回根文件树哈希
+func writeTree(repo *git.Repository, files map[string]treeFile) (plumbing.Hash, error) {
+	subFiles := map[string]map[string]treeFile{}
+	var entries []object.TreeEntry
+	for name, file := range files {
+		if dir, rest, nested := strings.Cut(name, "/"); nested {
+			if subFiles[dir] == nil {
+				subFiles[dir] = map[string]treeFile{}
+			}
+			subFiles[dir][rest] = file
+		} else {
+			entries = append(entries, object.TreeEntry{Name: name, Mode: file.mode, Hash: file.hash})
+		}
+	}
+	for dir, children := range subFiles {
+		hash, err := writeTree(repo, children)
+		if err != nil {
+			return plumbing.ZeroHash, erero.Wro(err)
+		}
+		entries = append(entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git sorts entries as if DIR names end with "/"
+	// Git 排序条目时将 DIR 名称视为以 "/" 结尾
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortName(entries[i]) < sortName(entries[j])
+	})
+
+	encodedObject := repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(encodedObject); err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	return repo.Storer.SetEncodedObject(encodedObject)
+}
+
+// writeCommit stores the commit object and returns its hash
+// writeCommit 存储提交对象并返回其哈希
+func writeCommit(repo *git.Repository, commitObject *object.Commit) (plumbing.Hash, error) {
+	encodedObject := repo.Storer.NewEncodedObject()
+	if err := commitObject.Encode(encodedObject); err != nil {
+		return plumbing.ZeroHash, erero.Wro(err)
+	}
+	return repo.Storer.SetEncodedObject(encodedObject)
+}
+
+// commitSubject returns the first line of the commit message
+// commitSubject 返回提交消息的第一行
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
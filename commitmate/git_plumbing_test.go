@@ -0,0 +1,33 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMergeText validates replaying line changes onto a text with repeated lines
+// Tests each change lands on its own line, a change made on both sides applies once, and overlapping edits conflict
+//
+// TestMergeText 验证将逐行更改重放到包含重复行的文本上
+// 测试每个更改落在其对应的行上，两边都做出的更改只应用一次，重叠的编辑产生冲突
+func TestMergeText(t *testing.T) {
+	before := "func A() error {\n\treturn nil\n}\n\nfunc B() error {\n\treturn nil\n}\n"
+	after := "func A() error {\n\treturn nil\n}\n\nfunc B() error {\n\treturn errB\n}\n"
+	current := "func Z() error {\n\treturn nil\n}\n\n" + before
+	merged, ok := mergeText(before, after, current)
+	require.True(t, ok)
+	require.Equal(t, "func Z() error {\n\treturn nil\n}\n\n"+after, merged)
+
+	before = "a\nx\nx\nx\nb\n"
+	merged, ok = mergeText(before, "a\nx\nx\nb\n", "top\na\nx\nx\nb\n")
+	require.True(t, ok)
+	require.Equal(t, "top\na\nx\nx\nb\n", merged)
+
+	merged, ok = mergeText(before, "a\nx\nx\nx\nx\nb\n", "a\nx\nx\nx\nb\nc\n")
+	require.True(t, ok)
+	require.Equal(t, "a\nx\nx\nx\nx\nb\nc\n", merged)
+
+	_, ok = mergeText(before, "a\nx\ny\nx\nb\n", "a\nx\nz\nx\nb\n")
+	require.False(t, ok)
+}
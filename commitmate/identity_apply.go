@@ -0,0 +1,184 @@
+// Package commitmate provides writing the resolved identity into the repo git config
+// Makes plain git commit, IDE commits and rebase use the same identity as go-commit
+//
+// commitmate 包提供将解析出的身份写入仓库 git 配置的功能
+// 使普通 git commit、IDE 提交和 rebase 使用与 go-commit 相同的身份
+package commitmate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// IdentityItem compares one git config key with the value the signature wants
+// IdentityItem 比较一个 git 配置键与签名期望的值
+type IdentityItem struct {
+	Key  string // Git config key, e.g. user.name // Git 配置键，例如 user.name
+	Want string // Value from the resolved signature // 来自解析出的签名的值
+	Have string // Effective value in the repo, the local value when applying, blank when unset // 仓库中的生效值，应用时为本地值，未设置时为空
+}
+
+// Drifted tells whether the effective value differs from the wanted value
+// Drifted 表示生效值是否与期望值不同
+func (item *IdentityItem) Drifted() bool {
+	return item.Have != item.Want
+}
+
+// IdentityReport is the identity state of one project
+// IdentityReport 是一个项目的身份状态
+type IdentityReport struct {
+	ProjectRoot string           // Project path // 项目路径
+	Signature   *SignatureConfig // Resolved signature, nil when nothing matched // 解析出的签名，没有匹配时为 nil
+	Items       []*IdentityItem  // Keys set by the signature // 签名设置的键
+	Applied     bool             // Drifted keys were written into .git/config // 已将偏离的键写入 .git/config
+}
+
+// Drifted tells whether some key differs from the resolved signature
+// Drifted 表示是否有键与解析出的签名不同
+func (r *IdentityReport) Drifted() bool {
+	for _, item := range r.Items {
+		if item.Drifted() {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the report as one summary line followed by the drifted keys
+// String 将报告格式化为一行摘要，随后是偏离的键
+func (r *IdentityReport) String() string {
+	var builder strings.Builder
+	switch {
+	case r.Signature == nil:
+		builder.WriteString(fmt.Sprintf("%s: no matching signature", r.ProjectRoot))
+	case r.Applied:
+		builder.WriteString(fmt.Sprintf("%s: applied signature %s", r.ProjectRoot, r.Signature.Name))
+	case r.Drifted():
+		builder.WriteString(fmt.Sprintf("%s: drift from signature %s", r.ProjectRoot, r.Signature.Name))
+	default:
+		builder.WriteString(fmt.Sprintf("%s: in sync with signature %s", r.ProjectRoot, r.Signature.Name))
+	}
+	for _, item := range r.Items {
+		if item.Drifted() {
+			builder.WriteString(fmt.Sprintf("\n  %s: %q -> %q", item.Key, item.Have, item.Want))
+		}
+	}
+	return builder.String()
+}
+
+// CheckIdentity compares the effective git identity of the project with the resolved signature
+// Keys the signature leaves blank are not compared
+//
+// CheckIdentity 比较项目生效的 git 身份与解析出的签名
+// 签名中留空的键不参与比较
+func (config *CommitConfig) CheckIdentity(projectRoot string) *IdentityReport {
+	return config.compareIdentity(projectRoot, func(key string) string {
+		return getGitConfigValue(projectRoot, key)
+	})
+}
+
+// compareIdentity compares the values the lookup gives with the resolved signature
+// compareIdentity 比较查找函数给出的值与解析出的签名
+func (config *CommitConfig) compareIdentity(projectRoot string, lookup func(key string) string) *IdentityReport {
+	report := &IdentityReport{ProjectRoot: projectRoot, Signature: config.ResolveSignature(projectRoot)}
+	if report.Signature == nil {
+		return report
+	}
+	for _, item := range []*IdentityItem{
+		{Key: "user.name", Want: report.Signature.Username},
+		{Key: "user.email", Want: zerotern.VV(report.Signature.Mailbox, report.Signature.Eddress)},
+		{Key: "user.signingkey", Want: report.Signature.SigningKey},
+	} {
+		if item.Want == "" {
+			continue
+		}
+		item.Have = lookup(item.Key)
+		report.Items = append(report.Items, item)
+	}
+	return report
+}
+
+// ApplyIdentity writes the identity keys differing from the local .git/config of the project into it
+// Compares with the local values, so the identity is pinned even when the global config matches for now
+// Returns the report before writing, with Applied set when something was written
+//
+// ApplyIdentity 将与项目本地 .git/config 不同的身份键写入其中
+// 与本地值比较，因此即使全局配置暂时一致也会固定身份
+// 返回写入前的报告，有内容写入时设置 Applied
+func (config *CommitConfig) ApplyIdentity(projectRoot string) (*IdentityReport, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repoConfig, err := client.Repo().Config()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	localValues := map[string]string{
+		"user.name":       repoConfig.User.Name,
+		"user.email":      repoConfig.User.Email,
+		"user.signingkey": repoConfig.Raw.Section("user").Option("signingkey"),
+	}
+	report := config.compareIdentity(projectRoot, func(key string) string {
+		return localValues[key]
+	})
+	if !report.Drifted() {
+		return report, nil
+	}
+
+	for _, item := range report.Items {
+		if !item.Drifted() {
+			continue
+		}
+		switch item.Key {
+		case "user.name":
+			repoConfig.User.Name = item.Want
+		case "user.email":
+			repoConfig.User.Email = item.Want
+		case "user.signingkey":
+			repoConfig.Raw.Section("user").SetOption("signingkey", item.Want)
+		}
+	}
+	if err := client.Repo().SetConfig(repoConfig); err != nil {
+		return report, erero.Wro(err)
+	}
+	zaplog.SUG.Debugln("applied signature", report.Signature.Name, "to", projectRoot)
+	report.Applied = true
+	return report, nil
+}
+
+// FindProjectRoots walks the DIR tree and returns each DIR holding a ".git" entry
+// Nested repos and submodules are included, ".git" DIRs are not entered
+//
+// FindProjectRoots 遍历目录树并返回每个包含 ".git" 条目的 DIR
+// 包括嵌套仓库和子模块，不进入 ".git" 目录
+func FindProjectRoots(root string) ([]string, error) {
+	var projectRoots []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			projectRoots = append(projectRoots, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return projectRoots, nil
+}
@@ -0,0 +1,122 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/osexec"
+	"github.com/yyle88/rese"
+)
+
+// newIdentityTestConfig creates a signature with a signing key matching GitHub remotes
+// newIdentityTestConfig 创建匹配 GitHub 远程且带签名密钥的签名
+func newIdentityTestConfig() *CommitConfig {
+	return &CommitConfig{
+		Signatures: []*SignatureConfig{{
+			Name:           "github",
+			Username:       "github-user",
+			Mailbox:        "github@example.com",
+			SigningKey:     "ABCD1234",
+			RemotePatterns: []string{"git@github.com:*"},
+		}},
+	}
+}
+
+// TestApplyIdentity validates drift detection and writing into .git/config
+// Tests that a second check reports no drift and git reads the written values
+//
+// TestApplyIdentity 验证偏离检测以及写入 .git/config
+// 测试再次检查时不再报告偏离，且 git 能读取写入的值
+func TestApplyIdentity(t *testing.T) {
+	tempDIR, cleanup := setupTestRepoWithRemote("git@github.com:user/repo.git")
+	t.Cleanup(cleanup)
+	config := newIdentityTestConfig()
+
+	report := config.CheckIdentity(tempDIR)
+	require.Equal(t, "github", report.Signature.Name)
+	require.True(t, report.Drifted())
+	require.Len(t, report.Items, 3)
+	require.Equal(t, "Test Username", report.Items[0].Have)
+	require.Contains(t, report.String(), `user.name: "Test Username" -> "github-user"`)
+
+	report, err := config.ApplyIdentity(tempDIR)
+	require.NoError(t, err)
+	require.True(t, report.Applied)
+
+	for key, value := range map[string]string{
+		"user.name":       "github-user",
+		"user.email":      "github@example.com",
+		"user.signingkey": "ABCD1234",
+	} {
+		require.Equal(t, value, getGitConfigValue(tempDIR, key), key)
+	}
+
+	report = config.CheckIdentity(tempDIR)
+	require.False(t, report.Drifted())
+	require.Contains(t, report.String(), "in sync with signature github")
+
+	report, err = config.ApplyIdentity(tempDIR)
+	require.NoError(t, err)
+	require.False(t, report.Applied)
+}
+
+// TestApplyIdentity_GlobalMatches validates the identity is pinned locally when the global config matches
+// Tests that the effective check is in sync while apply writes the local keys
+//
+// TestApplyIdentity_GlobalMatches 验证全局配置一致时身份仍被固定到本地
+// 测试生效值检查保持一致，而应用会写入本地键
+func TestApplyIdentity_GlobalMatches(t *testing.T) {
+	tempDIR, cleanup := setupTestRepoWithRemote("git@github.com:user/repo.git")
+	t.Cleanup(cleanup)
+	config := newIdentityTestConfig()
+
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+	require.NoError(t, os.WriteFile(globalConfig, []byte("[user]\n\tname = github-user\n\temail = github@example.com\n\tsigningkey = ABCD1234\n"), 0644))
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+	rese.V1(osexec.NewExecConfig().WithPath(tempDIR).Exec("git", "config", "--unset", "user.name"))
+	rese.V1(osexec.NewExecConfig().WithPath(tempDIR).Exec("git", "config", "--unset", "user.email"))
+	require.False(t, config.CheckIdentity(tempDIR).Drifted())
+
+	report, err := config.ApplyIdentity(tempDIR)
+	require.NoError(t, err)
+	require.True(t, report.Applied)
+	repoConfig := rese.P1(rese.P1(git.PlainOpen(tempDIR)).Config())
+	require.Equal(t, "github-user", repoConfig.User.Name)
+	require.Equal(t, "github@example.com", repoConfig.User.Email)
+	require.Equal(t, "ABCD1234", repoConfig.Raw.Section("user").Option("signingkey"))
+
+	report, err = config.ApplyIdentity(tempDIR)
+	require.NoError(t, err)
+	require.False(t, report.Applied)
+}
+
+func TestApplyIdentity_NoMatch(t *testing.T) {
+	tempDIR, cleanup := setupTestRepoWithRemote("git@gitlab.com:user/repo.git")
+	t.Cleanup(cleanup)
+
+	report, err := newIdentityTestConfig().ApplyIdentity(tempDIR)
+	require.NoError(t, err)
+	require.Nil(t, report.Signature)
+	require.False(t, report.Drifted())
+	require.Contains(t, report.String(), "no matching signature")
+}
+
+func TestFindProjectRoots(t *testing.T) {
+	tempDIR := t.TempDir()
+	for _, path := range []string{"a/.git", "b/c/.git", "b/c/nested/.git", "d"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDIR, path), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "d", ".git"), []byte("gitdir: ../a/.git\n"), 0644))
+
+	projectRoots, err := FindProjectRoots(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(tempDIR, "a"),
+		filepath.Join(tempDIR, "b", "c"),
+		filepath.Join(tempDIR, "b", "c", "nested"),
+		filepath.Join(tempDIR, "d"),
+	}, projectRoots)
+}
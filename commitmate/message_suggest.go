@@ -0,0 +1,533 @@
+// Package commitmate suggests commit messages from the staged changes without network access
+// Compares exported Go declarations between HEAD and the index with go/parser, and reads go.mod requirements
+//
+// commitmate 包在不访问网络的情况下根据已暂存的更改建议提交消息
+// 使用 go/parser 比较 HEAD 与索引之间导出的 Go 声明，并读取 go.mod 依赖
+package commitmate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"golang.org/x/mod/modfile"
+)
+
+// apiChange is one added, removed or renamed exported declaration of a package
+// apiChange 是包中一个新增、删除或重命名的导出声明
+type apiChange struct {
+	action  string // "add", "remove" or "rename" // "add"、"remove" 或 "rename"
+	pkg     string // Package DIR as the display name // 作为显示名称的包 DIR
+	name    string // Declaration such as "func NewClient" // 声明，例如 "func NewClient"
+	newName string // New name when renamed // 重命名后的新名称
+}
+
+// String returns the change as a message line
+// String 将更改返回为消息行
+func (c *apiChange) String() string {
+	if c.action == "rename" {
+		return fmt.Sprintf("rename %s to %s (%s)", c.name, c.newName, c.pkg)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.action, c.name, c.pkg)
+}
+
+// stagedSummary collects what the staged changes do, used to build the suggestion
+// stagedSummary 汇总已暂存更改的内容，用于构建建议
+type stagedSummary struct {
+	rootName    string       // Project name shown as the root DIR // 作为根 DIR 显示的项目名称
+	paths       []string     // Changed paths // 已更改的路径
+	packages    []string     // Package DIRs with changed Go files // 包含已更改 Go 文件的包 DIR
+	newPackages []string     // Package DIRs without Go files in HEAD // HEAD 中没有 Go 文件的包 DIR
+	apiChanges  []*apiChange // Exported declaration changes // 导出声明的更改
+	depChanges  []string     // go.mod requirement changes // go.mod 依赖的更改
+	depBumps    []string     // Bumped modules as "path version" // 升级的模块，格式为 "path version"
+	testOnly    bool         // Only tests and testdata changed // 仅测试和 testdata 被更改
+	depsOnly    bool         // Only go.mod and go.sum changed // 仅 go.mod 和 go.sum 被更改
+}
+
+// SuggestMessage proposes a commit message from the staged changes of the project
+// Reports added, removed and renamed exported functions, types and methods, new packages,
+// test-only changes and go.mod dependency bumps, returns blank when nothing is staged
+//
+// SuggestMessage 根据项目已暂存的更改建议提交消息
+// 报告新增、删除和重命名的导出函数、类型和方法，新包，
+// 仅测试的更改以及 go.mod 依赖升级，没有暂存内容时返回空
+func SuggestMessage(projectRoot string) (string, error) {
+	summary, err := summarizeProject(projectRoot)
+	if err != nil || summary == nil {
+		return "", err
+	}
+	return summary.message(), nil
+}
+
+// SuggestConventionalMessage proposes a conventional commit message from the staged changes of the project
+// The type is feat for new packages and API changes, test for tests, build(deps) for go.mod and chore else
+// The header is marked with "!" when the API report of the staged changes holds breaking changes
+//
+// SuggestConventionalMessage 根据项目已暂存的更改建议约定式提交消息
+// 新包和 API 更改的类型为 feat，测试为 test，go.mod 为 build(deps)，其余为 chore
+// 当已暂存更改的 API 报告包含破坏性更改时，标题使用 "!" 标记
+func SuggestConventionalMessage(projectRoot string, report *APIReport) (string, error) {
+	summary, err := summarizeProject(projectRoot)
+	if err != nil || summary == nil {
+		return "", err
+	}
+	return summary.conventionalMessage(report != nil && len(report.BreakingChanges()) > 0), nil
+}
+
+// summarizeProject summarizes the staged changes of the project, nil when nothing is staged
+// summarizeProject 汇总项目已暂存的更改，没有暂存内容时返回 nil
+func summarizeProject(projectRoot string) (*stagedSummary, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedFiles, err := indexFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	paths := listStagedChanges(headFiles, stagedFiles)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	summary, err := summarizeStagedChanges(repo, filepath.Base(projectRoot), headFiles, stagedFiles, paths)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return summary, nil
+}
+
+// headCommitFiles flattens the tree of HEAD, an unborn branch gives an empty map
+// headCommitFiles 展开 HEAD 的文件树，未创建的分支返回空映射
+func headCommitFiles(repo *git.Repository) (map[string]treeFile, error) {
+	headReference, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return map[string]treeFile{}, nil
+	}
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	headCommit, err := repo.CommitObject(headReference.Hash())
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return commitFiles(headCommit)
+}
+
+// summarizeStagedChanges compares the changed Go files by package and the changed go.mod files
+// summarizeStagedChanges 按包比较已更改的 Go 文件以及已更改的 go.mod 文件
+func summarizeStagedChanges(repo *git.Repository, rootName string, headFiles, stagedFiles map[string]treeFile, paths []string) (*stagedSummary, error) {
+	summary := &stagedSummary{rootName: rootName, paths: paths, testOnly: true, depsOnly: true}
+	beforeDecls := map[string]map[string]string{}
+	afterDecls := map[string]map[string]string{}
+	for _, name := range paths {
+		base := path.Base(name)
+		isTest := strings.HasSuffix(base, "_test.go") || strings.HasPrefix(name, "testdata/") || strings.Contains(name, "/testdata/")
+		summary.testOnly = summary.testOnly && isTest
+		summary.depsOnly = summary.depsOnly && (base == "go.mod" || base == "go.sum")
+
+		if base == "go.mod" {
+			if err := summary.compareGoMod(repo, headFiles[name], stagedFiles[name]); err != nil {
+				return nil, erero.Wro(err)
+			}
+		}
+		if path.Ext(name) != ".go" || isTest {
+			continue
+		}
+		dir := path.Dir(name)
+		if beforeDecls[dir] == nil {
+			beforeDecls[dir], afterDecls[dir] = map[string]string{}, map[string]string{}
+			summary.packages = append(summary.packages, dir)
+			if !hasPackageFiles(headFiles, dir) {
+				summary.newPackages = append(summary.newPackages, dir)
+			}
+		}
+		if err := collectExportedDecls(repo, name, headFiles[name], beforeDecls[dir]); err != nil {
+			return nil, erero.Wro(err)
+		}
+		if err := collectExportedDecls(repo, name, stagedFiles[name], afterDecls[dir]); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	for _, dir := range summary.packages {
+		// New packages are reported as a whole instead of declaration by declaration
+		// 新包作为整体报告，而不是逐个声明报告
+		if !hasPackageFiles(headFiles, dir) {
+			continue
+		}
+		summary.apiChanges = append(summary.apiChanges, diffExportedDecls(summary.displayDIR(dir), beforeDecls[dir], afterDecls[dir])...)
+	}
+	return summary, nil
+}
+
+// hasPackageFiles reports whether the files hold non-test Go files directly in the DIR
+// hasPackageFiles 判断文件中是否在该 DIR 下直接包含非测试 Go 文件
+func hasPackageFiles(files map[string]treeFile, dir string) bool {
+	for name := range files {
+		if path.Dir(name) == dir && path.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectExportedDecls parses the blob and records each exported declaration with its shape
+// Missing blobs and files with syntax errors are skipped
+//
+// collectExportedDecls 解析数据对象并记录每个导出声明及其形态
+// 缺失的数据对象和存在语法错误的文件会被跳过
+func collectExportedDecls(repo *git.Repository, name string, file treeFile, decls map[string]string) error {
+	if file.hash.IsZero() {
+		return nil
+	}
+	content, err := readBlob(repo, file.hash)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, name, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	for _, decl := range astFile.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv == nil {
+				decls["func "+decl.Name.Name] = printNode(fset, decl.Type)
+			} else if recv := receiverName(decl.Recv); ast.IsExported(recv) {
+				decls["method "+recv+"."+decl.Name.Name] = printNode(fset, decl.Type)
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.IsExported() {
+					decls["type "+typeSpec.Name.Name] = printNode(fset, typeSpec.Type)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// receiverName returns the type name of the method receiver without pointer and type params
+// receiverName 返回方法接收者的类型名称，不含指针和类型参数
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch typed := expr.(type) {
+		case *ast.StarExpr:
+			expr = typed.X
+		case *ast.IndexExpr:
+			expr = typed.X
+		case *ast.IndexListExpr:
+			expr = typed.X
+		case *ast.Ident:
+			return typed.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// printNode prints the node as Go source, used as the shape of a declaration
+// printNode 将节点打印为 Go 源码，用作声明的形态
+func printNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// diffExportedDecls returns the added, removed and renamed declarations of the package
+// A removed and an added declaration of the same kind and shape count as a rename
+//
+// diffExportedDecls 返回包中新增、删除和重命名的声明
+// 同一种类且形态相同的一个删除声明和一个新增声明视为重命名
+func diffExportedDecls(pkg string, before, after map[string]string) []*apiChange {
+	var removed, added []string
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	for name := range after {
+		if _, exists := before[name]; !exists {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	var changes []*apiChange
+	for _, oldName := range removed {
+		kind, _, _ := strings.Cut(oldName, " ")
+		renamed := false
+		for idx, newName := range added {
+			if newKind, _, _ := strings.Cut(newName, " "); newKind == kind && after[newName] == before[oldName] {
+				changes = append(changes, &apiChange{action: "rename", pkg: pkg, name: oldName, newName: strings.TrimPrefix(newName, kind+" ")})
+				added = append(added[:idx], added[idx+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			changes = append(changes, &apiChange{action: "remove", pkg: pkg, name: oldName})
+		}
+	}
+	for _, newName := range added {
+		changes = append(changes, &apiChange{action: "add", pkg: pkg, name: newName})
+	}
+	return changes
+}
+
+// compareGoMod records added, removed and bumped requirements between the two go.mod blobs
+// compareGoMod 记录两个 go.mod 数据对象之间新增、删除和升级的依赖
+func (s *stagedSummary) compareGoMod(repo *git.Repository, before, after treeFile) error {
+	beforeRequires, err := readGoModRequires(repo, before)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	afterRequires, err := readGoModRequires(repo, after)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	modules := make([]string, 0, len(beforeRequires)+len(afterRequires))
+	for module := range afterRequires {
+		modules = append(modules, module)
+	}
+	for module := range beforeRequires {
+		if _, exists := afterRequires[module]; !exists {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		oldVersion, newVersion := beforeRequires[module], afterRequires[module]
+		switch {
+		case oldVersion == newVersion:
+		case oldVersion == "":
+			s.depChanges = append(s.depChanges, fmt.Sprintf("add dependency %s %s", module, newVersion))
+		case newVersion == "":
+			s.depChanges = append(s.depChanges, fmt.Sprintf("remove dependency %s", module))
+		default:
+			s.depChanges = append(s.depChanges, fmt.Sprintf("bump %s from %s to %s", module, oldVersion, newVersion))
+			s.depBumps = append(s.depBumps, module+" "+newVersion)
+		}
+	}
+	return nil
+}
+
+// readGoModRequires maps each required module of the go.mod blob to its version
+// readGoModRequires 将 go.mod 数据对象中的每个依赖模块映射到其版本
+func readGoModRequires(repo *git.Repository, file treeFile) (map[string]string, error) {
+	requires := map[string]string{}
+	if file.hash.IsZero() {
+		return requires, nil
+	}
+	content, err := readBlob(repo, file.hash)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	modFile, err := modfile.ParseLax("go.mod", []byte(content), nil)
+	if err != nil {
+		return requires, nil
+	}
+	for _, require := range modFile.Require {
+		requires[require.Mod.Path] = require.Mod.Version
+	}
+	return requires, nil
+}
+
+// message builds the subject and a body with one line per detected change
+// message 构建标题以及每个检测到的更改一行的正文
+func (s *stagedSummary) message() string {
+	subject := s.subject()
+	return s.withDetails(subject, subject)
+}
+
+// conventionalMessage builds the message with a "type(scope)!: description" header
+// The scope is the single package or DIR of the change, the description is the subject without it
+//
+// conventionalMessage 构建带有 "type(scope)!: description" 标题的消息
+// 范围是更改所在的单个包或 DIR，描述是去掉范围后的标题
+func (s *stagedSummary) conventionalMessage(breaking bool) string {
+	kind, dirs := s.conventionalType()
+	subject, scope := s.subject(), ""
+	if len(dirs) == 1 {
+		scope = "(" + dirs[0] + ")"
+		subject = strings.TrimSuffix(subject, " in "+dirs[0])
+	}
+	if breaking {
+		scope += "!"
+	}
+	description := strings.ToLower(subject[:1]) + subject[1:]
+	return s.withDetails(kind+scope+": "+description, description)
+}
+
+// conventionalType picks the conventional type of the subject and the packages or DIRs it covers
+// conventionalType 选择标题的约定式类型以及其涉及的包或 DIR
+func (s *stagedSummary) conventionalType() (string, []string) {
+	var dirs []string
+	switch {
+	case len(s.newPackages) > 0:
+		for _, pkg := range s.newPackages {
+			dirs = appendUnique(dirs, s.displayDIR(pkg))
+		}
+		return "feat", dirs
+	case len(s.apiChanges) > 0:
+		for _, change := range s.apiChanges {
+			dirs = appendUnique(dirs, change.pkg)
+		}
+		return "feat", dirs
+	case s.depsOnly:
+		return "build", []string{"deps"}
+	default:
+		for _, name := range s.paths {
+			dirs = appendUnique(dirs, s.displayDIR(path.Dir(name)))
+		}
+		if s.testOnly {
+			return "test", dirs
+		}
+		return "chore", dirs
+	}
+}
+
+// withDetails appends a body with one line per detected change to the header
+// The body is left out when its single line repeats the description of the header
+//
+// withDetails 在标题后追加每个检测到的更改一行的正文
+// 当正文仅有的一行与标题的描述重复时省略正文
+func (s *stagedSummary) withDetails(header string, description string) string {
+	var details []string
+	for _, pkg := range s.newPackages {
+		details = append(details, "add package "+s.displayDIR(pkg))
+	}
+	for _, change := range s.apiChanges {
+		details = append(details, change.String())
+	}
+	details = append(details, s.depChanges...)
+
+	if len(details) == 0 || (len(details) == 1 && strings.EqualFold(details[0], description)) {
+		return header
+	}
+	return header + "\n\n- " + strings.Join(details, "\n- ")
+}
+
+// subject picks the most telling change as the message subject
+// subject 选择最有代表性的更改作为消息标题
+func (s *stagedSummary) subject() string {
+	switch {
+	case len(s.newPackages) > 0:
+		if len(s.newPackages) == 1 {
+			return "Add package " + s.displayDIR(s.newPackages[0])
+		}
+		var packages []string
+		for _, pkg := range s.newPackages {
+			packages = append(packages, s.displayDIR(pkg))
+		}
+		if len(packages) > 3 {
+			return "Add " + joinNames(packages, "packages")
+		}
+		return "Add packages " + joinNames(packages, "packages")
+	case len(s.apiChanges) > 0:
+		verb := ""
+		var names, packages []string
+		for _, change := range s.apiChanges {
+			verb = mergeVerb(verb, change.action)
+			name := change.newName
+			if name == "" {
+				_, name, _ = strings.Cut(change.name, " ")
+			}
+			names = append(names, name)
+			packages = appendUnique(packages, change.pkg)
+		}
+		if len(packages) > 1 {
+			return verb + " exported API in " + joinNames(packages, "packages")
+		}
+		return verb + " " + joinNames(names, "exported identifiers") + " in " + packages[0]
+	case s.testOnly:
+		var packages []string
+		for _, name := range s.paths {
+			packages = appendUnique(packages, s.displayDIR(path.Dir(name)))
+		}
+		return "Update tests in " + joinNames(packages, "packages")
+	case s.depsOnly && len(s.depBumps) == 1 && len(s.depChanges) == 1:
+		return "Bump " + strings.Replace(s.depBumps[0], " ", " to ", 1)
+	case s.depsOnly:
+		return "Update dependencies"
+	default:
+		var dirs []string
+		for _, name := range s.paths {
+			dirs = appendUnique(dirs, s.displayDIR(path.Dir(name)))
+		}
+		return "Update " + joinNames(dirs, "DIRs")
+	}
+}
+
+// mergeVerb combines the subject verb of the changes seen so far with the next action
+// mergeVerb 将目前为止的标题动词与下一个更改动作合并
+func mergeVerb(verb string, action string) string {
+	next := strings.ToUpper(action[:1]) + action[1:]
+	if verb == "" || verb == next {
+		return next
+	}
+	return "Update"
+}
+
+// joinNames lists up to 3 names, else gives the count with the plural noun
+// joinNames 最多列出 3 个名称，否则给出数量和复数名词
+func joinNames(names []string, plural string) string {
+	if len(names) > 3 {
+		return fmt.Sprintf("%d %s", len(names), plural)
+	}
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// displayDIR shows the root DIR with the project name
+// displayDIR 使用项目名称显示根 DIR
+func (s *stagedSummary) displayDIR(dir string) string {
+	if dir == "." {
+		return s.rootName
+	}
+	return dir
+}
+
+// appendUnique appends the value when it is not in the slice yet
+// appendUnique 当值尚不在切片中时追加该值
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,158 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// stageSuggestTestFiles writes the files and stages them without committing
+// stageSuggestTestFiles 写入文件并暂存而不提交
+func stageSuggestTestFiles(t *testing.T, projectRoot string, files map[string]string) {
+	writeSplitTestFiles(t, projectRoot, files)
+	require.NoError(t, GitCommit(projectRoot, &CommitFlags{NoCommit: true}))
+}
+
+// TestSuggestMessage validates suggestions from exported API changes in one package
+// Tests that a rename keeps the shape and unexported changes are not reported
+//
+// TestSuggestMessage 验证根据单个包中导出 API 的更改生成建议
+// 测试重命名保持形态不变，且未导出的更改不被报告
+func TestSuggestMessage(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "go.mod", "module example.com/demo\n\ngo 1.22\n", &CommitFlags{Message: "add go.mod"})
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"client/client.go": "package client\n\ntype Client struct{}\n\nfunc Open(name string) (*Client, error) { return nil, nil }\n\nfunc (c *Client) Close() error { return nil }\n",
+	})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add client"}))
+
+	message, err := SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Empty(t, message)
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"client/client.go": "package client\n\ntype Client struct{}\n\nfunc Dial(name string) (*Client, error) { return nil, nil }\n\nfunc (c *Client) Close() error { return nil }\n\nfunc (c *Client) Ping() error { return nil }\n\nfunc helper() {}\n",
+	})
+	message, err = SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, "Update Dial and Client.Ping in client\n\n- rename func Open to Dial (client)\n- add method Client.Ping (client)", message)
+}
+
+func TestSuggestMessage_NewPackageAndTests(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store.go": "package store\n\nfunc Get() {}\n",
+	})
+	message, err := SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, "Add package store", message)
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: message}))
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store_test.go":     "package store\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) {}\n",
+		"store/testdata/data.txt": "data\n",
+	})
+	message, err = SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, "Update tests in store and store/testdata", message)
+}
+
+func TestSuggestMessage_GoModBump(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "go.mod", "module example.com/demo\n\nrequire golang.org/x/mod v0.30.0\n", &CommitFlags{Message: "add go.mod"})
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"go.mod": "module example.com/demo\n\nrequire golang.org/x/mod v0.31.0\n",
+	})
+	message, err := SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, "Bump golang.org/x/mod to v0.31.0\n\n- bump golang.org/x/mod from v0.30.0 to v0.31.0", message)
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"go.mod": "module example.com/demo\n\nrequire (\n\tgolang.org/x/mod v0.31.0\n\tgolang.org/x/sync v0.19.0\n)\n",
+	})
+	message, err = SuggestMessage(tempDIR)
+	require.NoError(t, err)
+	require.Equal(t, "Update dependencies\n\n- bump golang.org/x/mod from v0.30.0 to v0.31.0\n- add dependency golang.org/x/sync v0.19.0", message)
+}
+
+func TestGitCommit_Suggest(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{"api/api.go": "package api\n\nfunc Serve() {}\n"})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Suggest: true}))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "Add package api", headCommit.Message)
+}
+
+// TestSuggestConventionalMessage validates conventional headers with the type and scope of the staged changes
+// TestSuggestConventionalMessage 验证带有已暂存更改的类型和范围的约定式标题
+func TestSuggestConventionalMessage(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "go.mod", "module example.com/demo\n\nrequire golang.org/x/mod v0.30.0\n", &CommitFlags{Message: "add go.mod"})
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store.go": "package store\n\nfunc Get() {}\n",
+	})
+	message, err := SuggestConventionalMessage(tempDIR, nil)
+	require.NoError(t, err)
+	require.Equal(t, "feat(store): add package store", message)
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: message}))
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store.go": "package store\n\nfunc Get() {}\n\nfunc Put() {}\n",
+	})
+	message, err = SuggestConventionalMessage(tempDIR, &APIReport{Changes: []*APIChange{{Breaking: true}}})
+	require.NoError(t, err)
+	require.Equal(t, "feat(store)!: add Put\n\n- add func Put (store)", message)
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store.go":      "package store\n\nfunc Get() {}\n",
+		"store/store_test.go": "package store\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) {}\n",
+	})
+	rese.V1(rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree()).Add("store/store.go"))
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add test", StagedOnly: true}))
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"store/store_test.go": "package store\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) { t.Log() }\n",
+	})
+	message, err = SuggestConventionalMessage(tempDIR, nil)
+	require.NoError(t, err)
+	require.Equal(t, "test(store): update tests", message)
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: message}))
+
+	stageSuggestTestFiles(t, tempDIR, map[string]string{
+		"go.mod": "module example.com/demo\n\nrequire golang.org/x/mod v0.31.0\n",
+	})
+	message, err = SuggestConventionalMessage(tempDIR, nil)
+	require.NoError(t, err)
+	require.Equal(t, "build(deps): bump golang.org/x/mod to v0.31.0\n\n- bump golang.org/x/mod from v0.30.0 to v0.31.0", message)
+}
+
+// TestGitCommit_SuggestConventional validates --suggest with --conventional passes the conventional check
+// Tests that a breaking API change gets "!" in the suggested header
+//
+// TestGitCommit_SuggestConventional 验证 --suggest 与 --conventional 一起使用时能通过约定式检查
+// 测试破坏性 API 更改会在建议的标题中带有 "!"
+func TestGitCommit_SuggestConventional(t *testing.T) {
+	tempDIR := setupAPITestRepo(t, "example.com/demo")
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{"api/api.go": "package api\n\nfunc Serve() {}\n"})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Suggest: true, Conventional: true}))
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.Equal(t, "feat(api): add package api", rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash())).Message)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{"store/store.go": "package store\n"})
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Suggest: true, Conventional: true}))
+	require.Equal(t, "feat(store)!: remove Use\n\n- remove func Use (store)", rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash())).Message)
+}
@@ -0,0 +1,285 @@
+// Package commitmate canonicalizes go.mod and go.work files and checks their replace directives
+// Formats with golang.org/x/mod/modfile: merged require blocks, sorted blocks and normalized comments
+// Rejects replace directives pointing to local paths on protected branches when the module policy asks
+//
+// commitmate 包规范化 go.mod 和 go.work 文件并检查其 replace 指令
+// 使用 golang.org/x/mod/modfile 格式化：合并 require 块、排序块并规范化注释
+// 当模块策略要求时，在受保护分支上拒绝指向本地路径的 replace 指令
+package commitmate
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+	"golang.org/x/mod/modfile"
+)
+
+// ModulePolicy configures the checks of the staged go.mod and go.work files
+// ModulePolicy 配置已暂存 go.mod 和 go.work 文件的检查
+type ModulePolicy struct {
+	ProtectedBranches  []string `json:"protectedBranches,omitempty"`  // Branch patterns the checks apply on like "release/*", blank means each branch // 检查生效的分支模式，如 "release/*"，空表示所有分支
+	ForbidLocalReplace bool     `json:"forbidLocalReplace,omitempty"` // Reject replace directives pointing to local paths // 拒绝指向本地路径的 replace 指令
+}
+
+// checkModulePolicyProblems validates the branch patterns of the module policy
+// checkModulePolicyProblems 验证模块策略中的分支模式
+func checkModulePolicyProblems(policy *ModulePolicy) []*ConfigProblem {
+	if policy == nil {
+		return nil
+	}
+	var problems []*ConfigProblem
+	for idx, pattern := range policy.ProtectedBranches {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			problems = append(problems, &ConfigProblem{
+				Path:    fmt.Sprintf("$.modulePolicy.protectedBranches[%d]", idx),
+				Message: fmt.Sprintf("invalid branch pattern %q", pattern),
+			})
+		}
+	}
+	return problems
+}
+
+// isModFile reports whether the path names a go.mod or go.work file
+// isModFile 判断路径是否为 go.mod 或 go.work 文件
+func isModFile(name string) bool {
+	base := filepath.Base(name)
+	return base == "go.mod" || base == "go.work"
+}
+
+// isFormatTarget reports whether the path names a file the format step handles
+// isFormatTarget 判断路径是否为格式化步骤处理的文件
+func isFormatTarget(name string) bool {
+	return filepath.Ext(name) == ".go" || isModFile(name)
+}
+
+// formatFileSource formats the source by its file kind, Go sources in the scope and module files as a whole
+// formatFileSource 按文件类型格式化源码，Go 源码按范围格式化，模块文件整体格式化
+func formatFileSource(name string, source string, base *string, scope string) (string, error) {
+	if isModFile(name) {
+		return formatModSource(name, source)
+	}
+	var baseContent string
+	if base != nil {
+		baseContent = *base
+	}
+	return formatSource(source, baseContent, base == nil, scope)
+}
+
+// formatModSource canonicalizes the go.mod or go.work source with modfile formatting
+// formatModSource 使用 modfile 格式化规范化 go.mod 或 go.work 源码
+func formatModSource(name string, source string) (string, error) {
+	if filepath.Base(name) == "go.work" {
+		file, err := modfile.ParseWork(name, []byte(source), nil)
+		if err != nil {
+			return "", erero.Wro(err)
+		}
+		file.SortBlocks()
+		file.Cleanup()
+		return string(modfile.Format(file.Syntax)), nil
+	}
+	file, err := modfile.Parse(name, []byte(source), nil)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	file.Cleanup()
+	mergeRequireBlocks(file)
+	file.SortBlocks()
+	return string(modfile.Format(file.Syntax)), nil
+}
+
+// mergeRequireBlocks moves the require lines into one direct and one indirect block at the first require
+// Comments stay with their lines, blocks holding comments before the closing parenthesis are left alone
+//
+// mergeRequireBlocks 将 require 行移入位于第一个 require 处的一个直接依赖块和一个间接依赖块
+// 注释跟随其所在行，右括号前带有注释的块保持不变
+func mergeRequireBlocks(file *modfile.File) {
+	indirect := make(map[*modfile.Line]bool, len(file.Require))
+	for _, require := range file.Require {
+		indirect[require.Syntax] = require.Indirect
+	}
+
+	// Statements before the first require keep their place, the merged blocks take it
+	// 第一个 require 之前的语句保持原位，合并后的块占据其位置
+	first, count := -1, 0
+	for idx, stmt := range file.Syntax.Stmt {
+		if block, ok := stmt.(*modfile.LineBlock); ok && isRequireBlock(block) && len(block.RParen.Before) > 0 {
+			return
+		}
+		if isRequireStmt(stmt) {
+			if first < 0 {
+				first = idx
+			}
+			count++
+		}
+	}
+	if count < 2 {
+		return
+	}
+
+	var directLines, indirectLines []*modfile.Line
+	var stmts []modfile.Expr
+	for _, stmt := range file.Syntax.Stmt {
+		switch node := stmt.(type) {
+		case *modfile.Line:
+			if !isRequireStmt(node) {
+				stmts = append(stmts, node)
+				continue
+			}
+			line := &modfile.Line{Comments: node.Comments, Start: node.Start, Token: node.Token[1:], InBlock: true, End: node.End}
+			if indirect[node] {
+				indirectLines = append(indirectLines, line)
+			} else {
+				directLines = append(directLines, line)
+			}
+		case *modfile.LineBlock:
+			if !isRequireBlock(node) {
+				stmts = append(stmts, node)
+				continue
+			}
+			for lineIdx, line := range node.Line {
+				if lineIdx == 0 {
+					line.Before = append(slices.Clone(node.Before), line.Before...)
+				}
+				if indirect[line] {
+					indirectLines = append(indirectLines, line)
+				} else {
+					directLines = append(directLines, line)
+				}
+			}
+		default:
+			stmts = append(stmts, node)
+		}
+	}
+
+	var requires []modfile.Expr
+	for _, lines := range [][]*modfile.Line{directLines, indirectLines} {
+		switch len(lines) {
+		case 0:
+		case 1:
+			line := lines[0]
+			requires = append(requires, &modfile.Line{Comments: line.Comments, Start: line.Start, Token: append([]string{"require"}, line.Token...), End: line.End})
+		default:
+			requires = append(requires, &modfile.LineBlock{Token: []string{"require"}, Line: lines})
+		}
+	}
+	file.Syntax.Stmt = slices.Concat(stmts[:first], requires, stmts[first:])
+}
+
+// isRequireStmt reports whether the statement is a require line or block
+// isRequireStmt 判断语句是否为 require 行或块
+func isRequireStmt(stmt modfile.Expr) bool {
+	switch node := stmt.(type) {
+	case *modfile.Line:
+		return len(node.Token) > 0 && node.Token[0] == "require"
+	case *modfile.LineBlock:
+		return isRequireBlock(node)
+	}
+	return false
+}
+
+// isRequireBlock reports whether the block is a require block
+// isRequireBlock 判断块是否为 require 块
+func isRequireBlock(block *modfile.LineBlock) bool {
+	return len(block.Token) == 1 && block.Token[0] == "require"
+}
+
+// CheckModulePolicy checks the go.mod and go.work files changed between HEAD and the index
+// Applies when the current branch matches a protected pattern, a detached HEAD is checked too
+//
+// CheckModulePolicy 检查 HEAD 与索引之间已改变的 go.mod 和 go.work 文件
+// 当前分支匹配受保护模式时生效，分离的 HEAD 也会检查
+func CheckModulePolicy(repo *git.Repository, policy *ModulePolicy) error {
+	if policy == nil || !policy.ForbidLocalReplace {
+		return nil
+	}
+	headReference, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	branch := "detached HEAD"
+	if headReference.Type() == plumbing.SymbolicReference {
+		branch = headReference.Target().Short()
+		if !matchBranch(policy.ProtectedBranches, branch) {
+			zaplog.SUG.Debugln("module policy skips unprotected branch:", branch)
+			return nil
+		}
+	}
+
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	stagedFiles, err := indexFiles(repo)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	var problems []string
+	for name, stagedFile := range stagedFiles {
+		if !isModFile(name) || headFiles[name] == stagedFile {
+			continue
+		}
+		content, err := readBlob(repo, stagedFile.hash)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		replaces, err := localReplaces(name, content)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		for _, replace := range replaces {
+			problems = append(problems, fmt.Sprintf("%s: replace %s => %s", name, replace.Old.Path, replace.New.Path))
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return erero.Errorf("local replace directives are forbidden on branch %s:\n%s", branch, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// matchBranch reports whether the branch matches some pattern, no patterns match each branch
+// matchBranch 判断分支是否匹配某个模式，没有模式时匹配所有分支
+func matchBranch(patterns []string, branch string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// localReplaces returns the replace directives of the go.mod or go.work source pointing to local paths
+// localReplaces 返回 go.mod 或 go.work 源码中指向本地路径的 replace 指令
+func localReplaces(name string, source string) ([]*modfile.Replace, error) {
+	var replaces []*modfile.Replace
+	if filepath.Base(name) == "go.work" {
+		file, err := modfile.ParseWork(name, []byte(source), nil)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		replaces = file.Replace
+	} else {
+		file, err := modfile.Parse(name, []byte(source), nil)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		replaces = file.Replace
+	}
+	var results []*modfile.Replace
+	for _, replace := range replaces {
+		if replace.New.Version == "" {
+			results = append(results, replace)
+		}
+	}
+	return results, nil
+}
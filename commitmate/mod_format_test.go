@@ -0,0 +1,62 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+const messyModSource = "module example.com/demo\n\ngo 1.22\n\nrequire (\n  example.com/b v1.0.0 // pinned\n\texample.com/a v1.0.0\n)\n\nrequire example.com/c v1.0.0 // indirect\nrequire example.com/d v1.2.0\n\nreplace example.com/a => ../a\n"
+
+// TestFormatModSource validates merging the require blocks and sorting the lines with comments kept
+// TestFormatModSource 验证合并 require 块并在保留注释的同时排序各行
+func TestFormatModSource(t *testing.T) {
+	formatted, err := formatModSource("go.mod", messyModSource)
+	require.NoError(t, err)
+	require.Equal(t, "module example.com/demo\n\ngo 1.22\n\nrequire (\n\texample.com/a v1.0.0\n\texample.com/b v1.0.0 // pinned\n\texample.com/d v1.2.0\n)\n\nrequire example.com/c v1.0.0 // indirect\n\nreplace example.com/a => ../a\n", formatted)
+
+	again, err := formatModSource("go.mod", formatted)
+	require.NoError(t, err)
+	require.Equal(t, formatted, again)
+
+	formatted, err = formatModSource("go.work", "go 1.22\n\nuse (\n  ./b\n\t./a\n)\n")
+	require.NoError(t, err)
+	require.Equal(t, "go 1.22\n\nuse (\n\t./a\n\t./b\n)\n", formatted)
+
+	_, err = formatModSource("go.mod", "module\n")
+	require.Error(t, err)
+}
+
+// TestGitCommit_ModulePolicy validates formatting the go.mod and rejecting local replaces on protected branches
+// TestGitCommit_ModulePolicy 验证格式化 go.mod 并在受保护分支上拒绝本地 replace
+func TestGitCommit_ModulePolicy(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	policy := &ModulePolicy{ProtectedBranches: []string{"main", "master"}, ForbidLocalReplace: true}
+	commitFlags := &CommitFlags{Message: "add go.mod", FormatGo: true, ModulePolicy: policy}
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "go.mod"), []byte(messyModSource), 0644))
+	commitFlags.Username, commitFlags.Mailbox = "Test Username", "test@example.com"
+	err := GitCommit(tempDIR, commitFlags)
+	require.ErrorContains(t, err, "go.mod: replace example.com/a => ../a")
+
+	policy.ProtectedBranches = []string{"release/*"}
+	require.NoError(t, GitCommit(tempDIR, commitFlags))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	content := readTestFile(t, headCommit, "go.mod")
+	require.Contains(t, content, "require (\n\texample.com/a v1.0.0\n\texample.com/b v1.0.0 // pinned\n\texample.com/d v1.2.0\n)\n")
+}
+
+func TestCheckModulePolicyProblems(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"signatures": [], "modulePolicy": {"protectedBranches": ["release/["], "forbidLocalReplace": true}}`))
+	require.ErrorContains(t, err, "$.modulePolicy.protectedBranches[0]")
+
+	_, err = ParseConfig([]byte(`{"signatures": [], "modulePolicy": {"protectedBranches": ["release/*"], "forbidLocalReplace": true}}`))
+	require.NoError(t, err)
+}
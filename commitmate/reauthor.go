@@ -0,0 +1,246 @@
+// Package commitmate provides auditing and rewriting commits made with the wrong identity
+// Rewrites the author and committer of recent commits while keeping messages, dates and trees
+//
+// commitmate 包提供审计和重写使用错误身份的提交
+// 重写最近提交的作者和提交者，同时保留消息、日期和文件树
+package commitmate
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// ReauthorOptions configures the reauthor operation
+// ReauthorOptions 配置重写作者操作
+type ReauthorOptions struct {
+	Limit  int  // Commits to audit from HEAD, 0 means each unpushed commit // 从 HEAD 开始审计的提交数，0 表示每个未推送的提交
+	Force  bool // Rewrite pushed commits too, the same rule as AmendConfig.ForceAmend // 同时重写已推送的提交，与 AmendConfig.ForceAmend 规则相同
+	DryRun bool // Report the commits without rewriting // 仅报告提交而不重写
+}
+
+// ReauthorCommit is one audited commit
+// ReauthorCommit 是一个被审计的提交
+type ReauthorCommit struct {
+	Hash      plumbing.Hash    // Original commit hash // 原始提交哈希
+	NewHash   plumbing.Hash    // Rewritten commit hash, zero when not rewritten // 重写后的提交哈希，未重写时为零值
+	Author    object.Signature // Original author // 原始作者
+	Committer object.Signature // Original committer // 原始提交者
+	Subject   string           // First line of the message // 消息的第一行
+	Pushed    bool             // Reachable from a remote-tracking branch // 可从远程跟踪分支到达
+	Wrong     bool             // Author or committer differs from the signature // 作者或提交者与签名不同
+}
+
+// ReauthorReport is the result of auditing and rewriting the current branch
+// ReauthorReport 是审计并重写当前分支的结果
+type ReauthorReport struct {
+	Branch    string            // Current branch // 当前分支
+	Signature *SignatureConfig  // Signature resolved by ResolveSignature // 由 ResolveSignature 解析出的签名
+	Commits   []*ReauthorCommit // Audited commits, newest first // 被审计的提交，最新的在前
+	NewHead   plumbing.Hash     // Branch tip after rewriting, zero when nothing was rewritten // 重写后的分支顶端，未重写时为零值
+}
+
+// WrongCommits returns the audited commits with the wrong identity
+// WrongCommits 返回身份错误的被审计提交
+func (r *ReauthorReport) WrongCommits() []*ReauthorCommit {
+	var commits []*ReauthorCommit
+	for _, commit := range r.Commits {
+		if commit.Wrong {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+// Reauthor finds commits on the current branch whose author or committer differs from the resolved signature
+// Rewrites them and their descendants on the first-parent history, keeping messages, dates and trees
+// Refuses pushed commits unless Force is set, the original tip is kept in ORIG_HEAD
+//
+// Reauthor 查找当前分支上作者或提交者与解析出的签名不同的提交
+// 在第一父提交历史上重写这些提交及其后代，保留消息、日期和文件树
+// 除非设置 Force，否则拒绝已推送的提交，原始顶端保存在 ORIG_HEAD 中
+func (config *CommitConfig) Reauthor(projectRoot string, options *ReauthorOptions) (*ReauthorReport, error) {
+	signature := config.ResolveSignature(projectRoot)
+	if signature == nil {
+		return nil, erero.New("no matching signature, cannot decide the correct identity")
+	}
+	username := signature.Username
+	mailbox := zerotern.VV(signature.Mailbox, signature.Eddress)
+	if username == "" || mailbox == "" {
+		return nil, erero.Errorf("signature %q misses username or mailbox", signature.Name)
+	}
+
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headReference, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if headReference.Type() != plumbing.SymbolicReference {
+		return nil, erero.New("HEAD is detached, checkout a branch first")
+	}
+	branchReference, err := repo.Reference(headReference.Target(), true)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	pushedHashes, err := listPushedHashes(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	// Walk the first-parent history, stopping at the first pushed commit unless a limit is given
+	// 遍历第一父提交历史，未给定数量限制时在第一个已推送的提交处停止
+	report := &ReauthorReport{Branch: headReference.Target().Short(), Signature: signature}
+	var commitObjects []*object.Commit
+	for hash := branchReference.Hash(); !hash.IsZero(); {
+		if options.Limit > 0 && len(report.Commits) >= options.Limit {
+			break
+		}
+		pushed := pushedHashes[hash]
+		if pushed && options.Limit <= 0 {
+			break
+		}
+		commitObject, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		report.Commits = append(report.Commits, &ReauthorCommit{
+			Hash:      hash,
+			Author:    commitObject.Author,
+			Committer: commitObject.Committer,
+			Subject:   commitSubject(commitObject.Message),
+			Pushed:    pushed,
+			Wrong:     !sameIdentity(commitObject.Author, username, mailbox) || !sameIdentity(commitObject.Committer, username, mailbox),
+		})
+		commitObjects = append(commitObjects, commitObject)
+		hash = plumbing.ZeroHash
+		if len(commitObject.ParentHashes) > 0 {
+			hash = commitObject.ParentHashes[0]
+		}
+	}
+
+	// The oldest wrong commit decides what gets rewritten
+	// 最早的错误提交决定需要重写的范围
+	oldestIdx := -1
+	for idx, commit := range report.Commits {
+		if commit.Wrong {
+			oldestIdx = idx
+		}
+	}
+	if oldestIdx < 0 || options.DryRun {
+		return report, nil
+	}
+	for _, commit := range report.Commits[:oldestIdx+1] {
+		if commit.Pushed && !options.Force {
+			return report, erero.Errorf("cannot rewrite commit %s that has been pushed, use force to override", commit.Hash.String()[:7])
+		}
+	}
+
+	// Rewrite from the oldest wrong commit to the tip, mapping each parent to its rewritten hash
+	// 从最早的错误提交到顶端依次重写，将每个父提交映射为其重写后的哈希
+	rewritten := map[plumbing.Hash]plumbing.Hash{}
+	for idx := oldestIdx; idx >= 0; idx-- {
+		commit := report.Commits[idx]
+		newHash, err := rewriteCommit(repo, commitObjects[idx], commit.Wrong, username, mailbox, rewritten)
+		if err != nil {
+			return report, erero.Wro(err)
+		}
+		rewritten[commit.Hash] = newHash
+		commit.NewHash = newHash
+	}
+	report.NewHead = report.Commits[0].NewHash
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName("ORIG_HEAD"), branchReference.Hash())); err != nil {
+		return report, erero.Wro(err)
+	}
+	if err := repo.Storer.CheckAndSetReference(plumbing.NewHashReference(branchReference.Name(), report.NewHead), branchReference); err != nil {
+		return report, erero.Wro(err)
+	}
+	zaplog.SUG.Debugln("reauthored", oldestIdx+1, "commits on", report.Branch, "new head:", report.NewHead)
+	return report, nil
+}
+
+// sameIdentity tells whether the signature has the given name and mailbox
+// sameIdentity 表示签名是否具有给定的名称和邮箱
+func sameIdentity(signature object.Signature, username string, mailbox string) bool {
+	return signature.Name == username && signature.Email == mailbox
+}
+
+// listPushedHashes collects the commits reachable from remote-tracking branches
+// listPushedHashes 收集可从远程跟踪分支到达的提交
+func listPushedHashes(repo *git.Repository) (map[plumbing.Hash]bool, error) {
+	references, err := repo.References()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	pushedHashes := map[plumbing.Hash]bool{}
+	err = references.ForEach(func(reference *plumbing.Reference) error {
+		if !reference.Name().IsRemote() || reference.Type() != plumbing.HashReference {
+			return nil
+		}
+		commitObject, err := repo.CommitObject(reference.Hash())
+		if err != nil {
+			zaplog.SUG.Debugln("skip remote reference", reference.Name(), "error:", err)
+			return nil
+		}
+		return object.NewCommitPreorderIter(commitObject, pushedHashes, nil).ForEach(func(commit *object.Commit) error {
+			pushedHashes[commit.Hash] = true
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	return pushedHashes, nil
+}
+
+// rewriteCommit stores a copy of the commit with mapped parents, and the signature identity when wrong
+// The dates, message and tree are kept, a PGP signature is dropped since it no longer verifies
+//
+// rewriteCommit 存储提交的副本，父提交被映射，身份错误时替换为签名身份
+// 保留日期、消息和文件树，PGP 签名会被丢弃，因为它已无法通过验证
+func rewriteCommit(repo *git.Repository, commitObject *object.Commit, wrong bool, username string, mailbox string, rewritten map[plumbing.Hash]plumbing.Hash) (plumbing.Hash, error) {
+	newCommit := *commitObject
+	newCommit.Hash = plumbing.ZeroHash
+	newCommit.PGPSignature = ""
+	newCommit.ParentHashes = make([]plumbing.Hash, 0, len(commitObject.ParentHashes))
+	for _, parentHash := range commitObject.ParentHashes {
+		if newHash, exists := rewritten[parentHash]; exists {
+			parentHash = newHash
+		}
+		newCommit.ParentHashes = append(newCommit.ParentHashes, parentHash)
+	}
+	if wrong {
+		newCommit.Author.Name, newCommit.Author.Email = username, mailbox
+		newCommit.Committer.Name, newCommit.Committer.Email = username, mailbox
+	}
+
+	return writeCommit(repo, &newCommit)
+}
+
+// String formats one audited commit as a line
+// String 将一个被审计的提交格式化为一行
+func (c *ReauthorCommit) String() string {
+	mark := "  "
+	if c.Wrong {
+		mark = "!!"
+	}
+	line := fmt.Sprintf("%s %s %s <%s> %s", mark, c.Hash.String()[:7], c.Author.Name, c.Author.Email, c.Subject)
+	if c.Pushed {
+		line += " (pushed)"
+	}
+	if !c.NewHash.IsZero() {
+		line += " -> " + c.NewHash.String()[:7]
+	}
+	return line
+}
@@ -0,0 +1,96 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-xlan/gitgo"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/osexec"
+	"github.com/yyle88/rese"
+)
+
+// setupReauthorTestRepo creates three commits where the first two use the wrong identity
+// The first commit is marked as pushed with a remote-tracking branch
+//
+// setupReauthorTestRepo 创建三个提交，其中前两个使用错误的身份
+// 第一个提交通过远程跟踪分支标记为已推送
+func setupReauthorTestRepo(t *testing.T) (string, *git.Repository) {
+	tempDIR, cleanup := setupTestRepoWithRemote("git@github.com:user/repo.git")
+	t.Cleanup(cleanup)
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	head := rese.P1(repo.Head())
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), head.Hash())))
+
+	gcm := gitgo.New(tempDIR)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "a.txt"), []byte("a\n"), 0644))
+	gcm.Add().Commit("second commit").MustDone()
+
+	rese.V1(osexec.NewExecConfig().WithPath(tempDIR).Exec("git", "config", "user.name", "github-user"))
+	rese.V1(osexec.NewExecConfig().WithPath(tempDIR).Exec("git", "config", "user.email", "github@example.com"))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "b.txt"), []byte("b\n"), 0644))
+	gcm.Add().Commit("third commit\n\nwith body").MustDone()
+	return tempDIR, repo
+}
+
+// TestReauthor validates rewriting unpushed commits with the wrong identity
+// Tests that messages, dates and trees are kept and the pushed commit is untouched
+//
+// TestReauthor 验证重写身份错误的未推送提交
+// 测试消息、日期和文件树保持不变，且已推送的提交不受影响
+func TestReauthor(t *testing.T) {
+	tempDIR, repo := setupReauthorTestRepo(t)
+	config := newIdentityTestConfig()
+	oldHead := rese.P1(repo.Head())
+	oldTip := rese.P1(repo.CommitObject(oldHead.Hash()))
+
+	report, err := config.Reauthor(tempDIR, &ReauthorOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, report.Commits, 2)
+	require.False(t, report.Commits[0].Wrong)
+	require.True(t, report.Commits[1].Wrong)
+	require.Len(t, report.WrongCommits(), 1)
+	require.Equal(t, oldHead.Hash(), rese.P1(repo.Head()).Hash())
+
+	report, err = config.Reauthor(tempDIR, &ReauthorOptions{})
+	require.NoError(t, err)
+	require.Equal(t, report.NewHead, rese.P1(repo.Head()).Hash())
+	require.Equal(t, oldHead.Hash(), rese.P1(repo.Reference("ORIG_HEAD", false)).Hash())
+
+	newTip := rese.P1(repo.CommitObject(report.NewHead))
+	require.Equal(t, oldTip.Message, newTip.Message)
+	require.Equal(t, oldTip.TreeHash, newTip.TreeHash)
+	require.True(t, oldTip.Author.When.Equal(newTip.Author.When))
+
+	newSecond := rese.P1(newTip.Parent(0))
+	require.Equal(t, "github-user", newSecond.Author.Name)
+	require.Equal(t, "github@example.com", newSecond.Committer.Email)
+	require.Equal(t, "second commit", report.Commits[1].Subject)
+	require.Equal(t, rese.P1(rese.P1(oldTip.Parent(0)).Parent(0)).Hash, newSecond.ParentHashes[0])
+
+	report, err = config.Reauthor(tempDIR, &ReauthorOptions{})
+	require.NoError(t, err)
+	require.Empty(t, report.WrongCommits())
+	require.True(t, report.NewHead.IsZero())
+}
+
+func TestReauthor_PushedNeedsForce(t *testing.T) {
+	tempDIR, repo := setupReauthorTestRepo(t)
+	config := newIdentityTestConfig()
+	oldHead := rese.P1(repo.Head())
+
+	report, err := config.Reauthor(tempDIR, &ReauthorOptions{Limit: 3})
+	require.ErrorContains(t, err, "has been pushed")
+	require.True(t, report.Commits[2].Pushed)
+	require.Equal(t, oldHead.Hash(), rese.P1(repo.Head()).Hash())
+
+	report, err = config.Reauthor(tempDIR, &ReauthorOptions{Limit: 3, Force: true})
+	require.NoError(t, err)
+	root := rese.P1(repo.CommitObject(report.Commits[2].NewHash))
+	require.Equal(t, "github-user", root.Author.Name)
+	require.Empty(t, root.ParentHashes)
+}
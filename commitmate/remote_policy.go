@@ -0,0 +1,251 @@
+// Package commitmate provides the remote selection policy of signature resolution
+// Chooses which Git remote URL is matched: origin, a named remote, a prefer-list, or the best score across remotes
+//
+// commitmate 包提供签名解析的远程选择策略
+// 选择匹配哪个 Git 远程 URL：origin、指定名称的远程、优先列表，或所有远程中得分最高者
+package commitmate
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/rese"
+	"github.com/yyle88/zaplog"
+)
+
+// RemoteMode names how the remote used in signature resolution is chosen
+// RemoteMode 表示签名解析时如何选择远程
+type RemoteMode string
+
+const (
+	RemoteModeOrigin RemoteMode = "origin" // Use 'origin', else the first remote by name (default) // 使用 'origin'，否则使用按名称排序的第一个远程（默认）
+	RemoteModeNamed  RemoteMode = "named"  // Use the remote given by name // 使用指定名称的远程
+	RemoteModePrefer RemoteMode = "prefer" // Use the first existing remote of the prefer-list // 使用优先列表中第一个存在的远程
+	RemoteModeBest   RemoteMode = "best"   // Match each remote and use the best score // 匹配每个远程并使用得分最高者
+)
+
+// RemotePolicy configures which remote URL is matched against the signature patterns
+// RemotePolicy 配置使用哪个远程 URL 来匹配签名模式
+type RemotePolicy struct {
+	Mode        RemoteMode `json:"mode,omitempty"`        // Selection mode, blank means origin // 选择模式，空表示 origin
+	Remote      string     `json:"remote,omitempty"`      // Remote name in named mode // named 模式下的远程名称
+	Prefer      []string   `json:"prefer,omitempty"`      // Remote names in prefer mode // prefer 模式下的远程名称列表
+	UseUpstream bool       `json:"useUpstream,omitempty"` // Use the branch's configured upstream remote first // 优先使用分支配置的上游远程
+}
+
+// RemoteCandidate records the match result of one remote
+// RemoteCandidate 记录一个远程的匹配结果
+type RemoteCandidate struct {
+	RemoteName    string          // Remote name // 远程名称
+	ConfiguredURL string          // Remote URL before insteadOf rewrites // 应用 insteadOf 重写之前的远程 URL
+	Match         *SignatureMatch // Match details of the rewritten remote URL // 重写后远程 URL 的匹配详情
+}
+
+// checkRemotePolicyProblems validates the remote policy fields
+// checkRemotePolicyProblems 验证远程策略字段
+func checkRemotePolicyProblems(policy *RemotePolicy) []*ConfigProblem {
+	if policy == nil {
+		return nil
+	}
+	var problems []*ConfigProblem
+	switch policy.Mode {
+	case "", RemoteModeOrigin, RemoteModeBest:
+	case RemoteModeNamed:
+		if policy.Remote == "" {
+			problems = append(problems, &ConfigProblem{Path: "$.remotePolicy.remote", Message: "named mode requires a remote name"})
+		}
+	case RemoteModePrefer:
+		if len(policy.Prefer) == 0 {
+			problems = append(problems, &ConfigProblem{Path: "$.remotePolicy.prefer", Message: "prefer mode requires remote names"})
+		}
+	default:
+		problems = append(problems, &ConfigProblem{
+			Path:    "$.remotePolicy.mode",
+			Message: fmt.Sprintf("unknown mode %q, expect one of origin, named, prefer, best", policy.Mode),
+		})
+	}
+	return problems
+}
+
+// projectRemote is a remote name with its first URL
+// projectRemote 是远程名称及其第一个 URL
+type projectRemote struct {
+	name          string // Remote name // 远程名称
+	url           string // URL after insteadOf rewrites // 应用 insteadOf 重写之后的 URL
+	configuredURL string // URL as written in the config // 配置中书写的 URL
+}
+
+// listProjectRemotes lists the remotes having URLs, 'origin' first and the rest sorted by name
+// URLs are rewritten by "url.<base>.insteadOf" rules of both the repo and the global Git config
+//
+// listProjectRemotes 列出拥有 URL 的远程，'origin' 排在首位，其余按名称排序
+// URL 会按照仓库和全局 Git 配置中的 "url.<base>.insteadOf" 规则重写
+func listProjectRemotes(client *gogit.Client) []*projectRemote {
+	repoConfig, err := client.Repo().ConfigScoped(config.GlobalScope)
+	if err != nil {
+		zaplog.SUG.Debugln("cannot get global config, use repo config:", err)
+		if repoConfig, err = client.Repo().Config(); err != nil {
+			zaplog.SUG.Debugln("cannot get remotes:", err)
+			return nil
+		}
+	}
+	insteadOfRules := map[string]string{}
+	for _, rule := range repoConfig.URLs {
+		if rule.InsteadOf != "" {
+			insteadOfRules[rule.InsteadOf] = rule.Name
+		}
+	}
+
+	var results []*projectRemote
+	for name, remoteConfig := range repoConfig.Remotes {
+		// go-git rewrites remote URLs with repo rules alone, so read the raw URL and rewrite it here
+		// go-git 只用仓库规则重写远程 URL，因此在这里读取原始 URL 并重写
+		configuredURLs := repoConfig.Raw.Section("remote").Subsection(name).Options.GetAll("url")
+		if len(configuredURLs) == 0 {
+			configuredURLs = remoteConfig.URLs
+		}
+		if len(configuredURLs) > 0 {
+			results = append(results, &projectRemote{
+				name:          name,
+				url:           utils.RewriteRemoteURL(configuredURLs[0], insteadOfRules),
+				configuredURL: configuredURLs[0],
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if (results[i].name == "origin") != (results[j].name == "origin") {
+			return results[i].name == "origin"
+		}
+		return results[i].name < results[j].name
+	})
+	return results
+}
+
+// getUpstreamRemoteName returns the remote configured as upstream of the current branch
+// Works on unborn branches too, returns blank when the branch tracks no remote
+//
+// getUpstreamRemoteName 返回当前分支配置的上游远程
+// 在尚无提交的分支上同样可用，分支未跟踪远程时返回空字符串
+func getUpstreamRemoteName(client *gogit.Client) string {
+	headReference, err := client.Repo().Reference(plumbing.HEAD, false)
+	if err != nil || headReference.Type() != plumbing.SymbolicReference {
+		return ""
+	}
+	repoConfig, err := client.Repo().Config()
+	if err != nil {
+		return ""
+	}
+	branch, exists := repoConfig.Branches[headReference.Target().Short()]
+	if !exists || branch.Remote == "" || branch.Remote == "." {
+		return ""
+	}
+	return branch.Remote
+}
+
+// resolveWithPolicy selects remotes according to the policy and matches them
+// resolveWithPolicy 根据策略选择远程并进行匹配
+func (config *CommitConfig) resolveWithPolicy(projectRoot string) *SignatureResolution {
+	client := rese.P1(gogit.New(projectRoot))
+	ctx := NewMatchContext(projectRoot)
+	remotes := listProjectRemotes(client)
+	if len(remotes) == 0 {
+		// Conditions and the default signature still apply without a remote
+		// 没有远程时条件和默认签名仍然适用
+		return &SignatureResolution{RemoteNote: "no remote is configured", Match: config.MatchSignatureDetailedInContext("", ctx)}
+	}
+	policy := config.RemotePolicy
+	if policy == nil {
+		policy = &RemotePolicy{}
+	}
+
+	// The upstream remote of the branch comes first when enabled
+	// 启用时分支的上游远程优先
+	if policy.UseUpstream {
+		if upstreamName := getUpstreamRemoteName(client); upstreamName != "" {
+			if idx := slices.IndexFunc(remotes, func(remote *projectRemote) bool { return remote.name == upstreamName }); idx >= 0 {
+				return config.resolveRemote(ctx, remotes[idx], "upstream remote of the current branch")
+			}
+		}
+	}
+
+	switch policy.Mode {
+	case RemoteModeNamed:
+		if idx := slices.IndexFunc(remotes, func(remote *projectRemote) bool { return remote.name == policy.Remote }); idx >= 0 {
+			return config.resolveRemote(ctx, remotes[idx], fmt.Sprintf("remote %q is configured by name", policy.Remote))
+		}
+		// Conditions and the default signature still apply when the named remote is missing
+		// 指定的远程不存在时条件和默认签名仍然适用
+		return &SignatureResolution{RemoteNote: fmt.Sprintf("configured remote %q does not exist", policy.Remote), Match: config.MatchSignatureDetailedInContext("", ctx)}
+	case RemoteModePrefer:
+		for rank, name := range policy.Prefer {
+			if idx := slices.IndexFunc(remotes, func(remote *projectRemote) bool { return remote.name == name }); idx >= 0 {
+				return config.resolveRemote(ctx, remotes[idx], fmt.Sprintf("remote %q is preference #%d", name, rank+1))
+			}
+		}
+		zaplog.SUG.Debugln("none of the preferred remotes exist, fallback to origin mode")
+	case RemoteModeBest:
+		return config.resolveBestRemote(ctx, remotes)
+	}
+
+	if remotes[0].name == "origin" {
+		return config.resolveRemote(ctx, remotes[0], "'origin' remote is preferred")
+	}
+	return config.resolveRemote(ctx, remotes[0], "fallback to the first remote since 'origin' does not exist")
+}
+
+// resolveRemote matches the single selected remote
+// resolveRemote 匹配单个选中的远程
+func (config *CommitConfig) resolveRemote(ctx *MatchContext, remote *projectRemote, note string) *SignatureResolution {
+	zaplog.SUG.Debugln("remote:", remote.name, "URL:", remote.url, "note:", note)
+	candidate := config.matchRemote(ctx, remote)
+	return &SignatureResolution{
+		RemoteName: remote.name,
+		RemoteNote: note,
+		Match:      candidate.Match,
+		Candidates: []*RemoteCandidate{candidate},
+	}
+}
+
+// resolveBestRemote matches each remote and keeps the highest winning score
+// Ties keep the earlier remote, where 'origin' comes first
+//
+// resolveBestRemote 匹配每个远程并保留最高的胜出分数
+// 分数相同时保留靠前的远程，其中 'origin' 排在首位
+func (config *CommitConfig) resolveBestRemote(ctx *MatchContext, remotes []*projectRemote) *SignatureResolution {
+	resolution := &SignatureResolution{}
+	var best *RemoteCandidate
+	for _, remote := range remotes {
+		candidate := config.matchRemote(ctx, remote)
+		resolution.Candidates = append(resolution.Candidates, candidate)
+		if candidate.Match.Winner == nil {
+			continue
+		}
+		if best == nil || candidate.Match.Winner.Score > best.Match.Winner.Score {
+			best = candidate
+		}
+	}
+	if best == nil {
+		best = resolution.Candidates[0]
+		resolution.RemoteNote = fmt.Sprintf("none of the %d remotes matched", len(remotes))
+	} else {
+		resolution.RemoteNote = fmt.Sprintf("best score %d across %d remotes", best.Match.Winner.Score, len(remotes))
+	}
+	resolution.RemoteName = best.RemoteName
+	resolution.Match = best.Match
+	return resolution
+}
+
+// matchRemote matches the rewritten URL of the remote in the checkout context
+// matchRemote 在检出上下文中匹配远程重写后的 URL
+func (config *CommitConfig) matchRemote(ctx *MatchContext, remote *projectRemote) *RemoteCandidate {
+	return &RemoteCandidate{
+		RemoteName:    remote.name,
+		ConfiguredURL: remote.configuredURL,
+		Match:         config.MatchSignatureDetailedInContext(remote.url, ctx),
+	}
+}
@@ -0,0 +1,111 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPolicyTestRepo creates a repo with origin, fork and work remotes
+// setupPolicyTestRepo 创建包含 origin、fork 和 work 远程的仓库
+func setupPolicyTestRepo(t *testing.T) (string, *git.Repository) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	repo, err := git.PlainOpen(tempDIR)
+	require.NoError(t, err)
+	for name, url := range map[string]string{
+		"origin": "git@github.com:user/repo.git",
+		"fork":   "https://github.com/org/repo.git",
+		"work":   "git@gitlab.com:team/repo.git",
+	} {
+		_, err = repo.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{url}})
+		require.NoError(t, err)
+	}
+	return tempDIR, repo
+}
+
+// TestResolveSignatureDetailed_RemotePolicy validates each remote selection mode
+// Tests named, prefer and best modes against the same remotes
+//
+// TestResolveSignatureDetailed_RemotePolicy 验证每种远程选择模式
+// 针对相同的远程测试 named、prefer 和 best 模式
+func TestResolveSignatureDetailed_RemotePolicy(t *testing.T) {
+	tempDIR, _ := setupPolicyTestRepo(t)
+	commitConfig := newExplainTestConfig()
+
+	resolution := commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "origin", resolution.RemoteName)
+	require.Equal(t, "general", resolution.Signature().Name)
+	require.Len(t, resolution.Candidates, 1)
+
+	commitConfig.RemotePolicy = &RemotePolicy{Mode: RemoteModeNamed, Remote: "work"}
+	resolution = commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "work", resolution.RemoteName)
+	require.Nil(t, resolution.Signature())
+
+	commitConfig.RemotePolicy = &RemotePolicy{Mode: RemoteModeNamed, Remote: "missing"}
+	resolution = commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Nil(t, resolution.Signature())
+	require.Equal(t, `configured remote "missing" does not exist`, resolution.RemoteNote)
+
+	// Conditions and the default signature apply when the named remote is missing
+	// 指定的远程不存在时条件和默认签名仍然适用
+	commitConfig.Signatures = append(commitConfig.Signatures, &SignatureConfig{Name: "fallback", Conditions: &MatchCondition{Default: true}})
+	commitConfig.Compile()
+	resolution = commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "fallback", resolution.Signature().Name)
+	require.Empty(t, resolution.RemoteName)
+	commitConfig.Signatures = commitConfig.Signatures[:len(commitConfig.Signatures)-1]
+	commitConfig.Compile()
+
+	commitConfig.RemotePolicy = &RemotePolicy{Mode: RemoteModePrefer, Prefer: []string{"missing", "fork", "origin"}}
+	resolution = commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "fork", resolution.RemoteName)
+	require.Equal(t, "specific", resolution.Signature().Name)
+	require.Equal(t, `remote "fork" is preference #2`, resolution.RemoteNote)
+
+	commitConfig.RemotePolicy = &RemotePolicy{Mode: RemoteModeBest}
+	resolution = commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "fork", resolution.RemoteName)
+	require.Equal(t, "specific", resolution.Signature().Name)
+	require.Len(t, resolution.Candidates, 3)
+	require.Equal(t, "origin", resolution.Candidates[0].RemoteName)
+	require.Equal(t, "best score 23 across 3 remotes", resolution.RemoteNote)
+}
+
+func TestResolveSignatureDetailed_UseUpstream(t *testing.T) {
+	tempDIR, repo := setupPolicyTestRepo(t)
+
+	head, err := repo.Reference("HEAD", false)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateBranch(&config.Branch{
+		Name:   head.Target().Short(),
+		Remote: "fork",
+		Merge:  head.Target(),
+	}))
+
+	commitConfig := newExplainTestConfig()
+	commitConfig.RemotePolicy = &RemotePolicy{UseUpstream: true}
+	resolution := commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "fork", resolution.RemoteName)
+	require.Equal(t, "upstream remote of the current branch", resolution.RemoteNote)
+	require.Equal(t, "specific", resolution.Signature().Name)
+}
+
+func TestParseConfig_RemotePolicyProblems(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"signatures": [], "remotePolicy": {"mode": "named"}}`))
+	require.ErrorContains(t, err, "$.remotePolicy.remote")
+
+	_, err = ParseConfig([]byte(`{"signatures": [], "remotePolicy": {"mode": "random"}}`))
+	require.ErrorContains(t, err, "$.remotePolicy.mode")
+
+	_, err = ParseConfig([]byte(`{"signatures": [], "remotePolicy": {"mode": "best", "order": 1}}`))
+	require.ErrorContains(t, err, "$.remotePolicy.order")
+
+	commitConfig, err := ParseConfig([]byte(`{"signatures": [], "remotePolicy": {"mode": "prefer", "prefer": ["upstream"]}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"upstream"}, commitConfig.RemotePolicy.Prefer)
+}
@@ -0,0 +1,191 @@
+// Package commitmate provides remote URL normalization in signature matching
+// Matches patterns against both the remote URL and its "host/owner/repo" form, and supports structured targets
+//
+// commitmate 包提供签名匹配中的远程 URL 规范化
+// 模式同时匹配远程 URL 及其 "host/owner/repo" 形式，并支持结构化目标
+package commitmate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-mate/go-commit/internal/utils"
+)
+
+// RemoteTarget matches the normalized components of a remote URL
+// Each component is a pattern matched on its own, a blank component matches anything
+// The score equals the score of the equivalent "host/owner/repo" pattern
+//
+// RemoteTarget 匹配远程 URL 规范化后的各个组成部分
+// 每个部分都是单独匹配的模式，空白部分匹配任意内容
+// 分数等于等价的 "host/owner/repo" 模式的分数
+type RemoteTarget struct {
+	Host  string `json:"host,omitempty"`  // Host glob, compared in lowercase // 主机 glob，按小写比较
+	Owner string `json:"owner,omitempty"` // Owner glob, may contain nested groups // 所有者 glob，可包含嵌套分组
+	Repo  string `json:"repo,omitempty"`  // Repo glob without ".git" // 不含 ".git" 的仓库 glob
+}
+
+// Pattern returns the equivalent glob on the "host/owner/repo" form, used in display and analysis
+// Pattern 返回在 "host/owner/repo" 形式上等价的 glob，用于展示和分析
+func (t *RemoteTarget) Pattern() string {
+	return zeroToAny(t.hostPattern()) + "/" + zeroToAny(t.Owner) + "/" + zeroToAny(t.Repo)
+}
+
+// hostPattern returns the host in lowercase, regular expressions are kept as written
+// hostPattern 返回小写的主机，正则表达式保持原样
+func (t *RemoteTarget) hostPattern() string {
+	if strings.HasPrefix(t.Host, utils.RegexPrefix) {
+		return t.Host
+	}
+	return strings.ToLower(t.Host)
+}
+
+// isSimple reports whether each component uses "*" wildcards alone, which static analysis requires
+// isSimple 判断每个部分是否只使用 "*" 通配符，静态分析需要满足此条件
+func (t *RemoteTarget) isSimple() bool {
+	return utils.IsSimplePattern(t.Host) && utils.IsSimplePattern(t.Owner) && utils.IsSimplePattern(t.Repo)
+}
+
+// zeroToAny turns a blank component into the "*" wildcard
+// zeroToAny 将空白部分转换为 "*" 通配符
+func zeroToAny(component string) string {
+	if component == "" {
+		return "*"
+	}
+	return component
+}
+
+// checkRemoteTargetProblems validates one structured target
+// checkRemoteTargetProblems 验证一个结构化目标
+func checkRemoteTargetProblems(path string, target *RemoteTarget) []*ConfigProblem {
+	if target == nil {
+		return []*ConfigProblem{{Path: path, Message: "remote target is null"}}
+	}
+	if target.Host == "" && target.Owner == "" && target.Repo == "" {
+		return []*ConfigProblem{{Path: path, Message: "remote target needs host, owner or repo"}}
+	}
+	var problems []*ConfigProblem
+	for _, component := range []struct {
+		name     string
+		value    string
+		canSlash bool
+	}{
+		{name: "host", value: target.Host},
+		{name: "owner", value: target.Owner, canSlash: true},
+		{name: "repo", value: target.Repo},
+	} {
+		if component.value == "" {
+			continue
+		}
+		if err := utils.ValidatePattern(component.value); err != nil {
+			problems = append(problems, &ConfigProblem{Path: path + "." + component.name, Message: err.Error()})
+		} else if !component.canSlash && strings.Contains(component.value, "/") {
+			problems = append(problems, &ConfigProblem{Path: path + "." + component.name, Message: fmt.Sprintf("%s %q must not contain '/'", component.name, component.value)})
+		}
+	}
+	return problems
+}
+
+// remoteForms holds the forms of one remote URL that patterns are matched against
+// remoteForms 保存一个远程 URL 中用于匹配模式的各种形式
+type remoteForms struct {
+	remoteURL string           // Remote URL as given // 给定的远程 URL
+	canonical string           // "host/owner/repo" form, blank when not parseable // "host/owner/repo" 形式，无法解析时为空
+	remote    *utils.RemoteURL // Normalized components, nil when not parseable // 规范化后的各部分，无法解析时为 nil
+}
+
+// newRemoteForms normalizes the remote URL once to match many patterns
+// newRemoteForms 对远程 URL 规范化一次以便匹配多个模式
+func newRemoteForms(remoteURL string) *remoteForms {
+	forms := &remoteForms{remoteURL: remoteURL}
+	if remote, ok := utils.ParseRemoteURL(remoteURL); ok {
+		forms.canonical = remote.Canonical()
+		forms.remote = remote
+	}
+	return forms
+}
+
+// matchPattern scores the compiled pattern on the remote URL and the "host/owner/repo" form, keeping the higher score
+// matchPattern 在远程 URL 和 "host/owner/repo" 形式上为已编译模式评分，保留较高的分数
+func (f *remoteForms) matchPattern(pattern *utils.CompiledPattern) int {
+	score := pattern.Match(f.remoteURL)
+	if f.canonical == "" || f.canonical == f.remoteURL || score == pattern.MaxScore() {
+		return score
+	}
+	return max(score, pattern.Match(f.canonical))
+}
+
+// compiledTarget holds the compiled components of a structured target, nil for blank components
+// compiledTarget 保存结构化目标中已编译的各部分，空白部分为 nil
+type compiledTarget struct {
+	host  *utils.CompiledPattern // Compiled host // 已编译的主机
+	owner *utils.CompiledPattern // Compiled owner // 已编译的所有者
+	repo  *utils.CompiledPattern // Compiled repo // 已编译的仓库
+}
+
+// compileTarget compiles each set component of the target
+// compileTarget 编译目标中每个已设置的部分
+func compileTarget(target *RemoteTarget) (*compiledTarget, error) {
+	var components [3]*utils.CompiledPattern
+	for idx, pattern := range []string{target.hostPattern(), target.Owner, target.Repo} {
+		if pattern == "" {
+			continue
+		}
+		compiled, err := utils.CompilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		components[idx] = compiled
+	}
+	return &compiledTarget{host: components[0], owner: components[1], repo: components[2]}, nil
+}
+
+// maxScore returns the highest score the target can give, the two "/" separators included
+// maxScore 返回目标可能给出的最高分数，包括两个 "/" 分隔符
+func (t *compiledTarget) maxScore() int {
+	score := 2
+	for _, component := range []*utils.CompiledPattern{t.host, t.owner, t.repo} {
+		if component != nil {
+			score += component.MaxScore()
+		}
+	}
+	return score
+}
+
+// matchTarget scores the structured target component by component
+// The two "/" separators of the equivalent pattern count as well
+//
+// matchTarget 逐个部分为结构化目标评分
+// 等价模式中的两个 "/" 分隔符同样计分
+func (f *remoteForms) matchTarget(target *compiledTarget) int {
+	if f.remote == nil {
+		return -1
+	}
+	score := 2
+	for _, component := range []struct {
+		pattern *utils.CompiledPattern
+		value   string
+	}{
+		{pattern: target.host, value: f.remote.Host},
+		{pattern: target.owner, value: f.remote.Owner},
+		{pattern: target.repo, value: f.remote.Repo},
+	} {
+		if component.pattern == nil {
+			continue
+		}
+		componentScore := component.pattern.Match(component.value)
+		if componentScore < 0 {
+			return -1
+		}
+		score += componentScore
+	}
+	return score
+}
+
+// coversTarget reports whether the general target matches every remote the specific target matches
+// coversTarget 判断通用目标是否匹配具体目标能匹配的所有远程
+func coversTarget(general, specific *RemoteTarget) bool {
+	return utils.CoversPattern(zeroToAny(general.hostPattern()), zeroToAny(specific.hostPattern())) &&
+		utils.CoversPattern(zeroToAny(general.Owner), zeroToAny(specific.Owner)) &&
+		utils.CoversPattern(zeroToAny(general.Repo), zeroToAny(specific.Repo))
+}
@@ -0,0 +1,121 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchSignature_NormalizedPattern validates one canonical pattern matching each protocol
+// Tests that "host/owner/*" patterns match scp-like, ssh and https remotes of the same repo
+//
+// TestMatchSignature_NormalizedPattern 验证一个规范化模式匹配各种协议
+// 测试 "host/owner/*" 模式匹配同一仓库的类 scp、ssh 和 https 远程
+func TestMatchSignature_NormalizedPattern(t *testing.T) {
+	commitConfig := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "acme", RemotePatterns: []string{"github.com/acme/*"}},
+			{Name: "fallback", RemotePatterns: []string{"*"}},
+		},
+	}
+	for _, remoteURL := range []string{
+		"git@github.com:acme/repo.git",
+		"ssh://git@github.com/acme/repo",
+		"https://github.com/acme/repo",
+		"https://user@github.com/acme/repo.git",
+	} {
+		require.Equal(t, "acme", commitConfig.MatchSignature(remoteURL).Name, remoteURL)
+	}
+	require.Equal(t, "fallback", commitConfig.MatchSignature("git@github.com:other/repo.git").Name)
+
+	result := commitConfig.MatchSignatureDetailed("ssh://git@github.com/acme/repo")
+	require.Equal(t, "github.com/acme/repo", result.Canonical)
+	require.Equal(t, 16, result.Winner.Score)
+}
+
+func TestMatchSignature_RemoteTargets(t *testing.T) {
+	commitConfig := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "host", RemoteTargets: []*RemoteTarget{{Host: "GitHub.com"}}},
+			{Name: "owner", RemoteTargets: []*RemoteTarget{{Host: "github.com", Owner: "acme"}}},
+			{Name: "repo", RemoteTargets: []*RemoteTarget{{Owner: "acme", Repo: "tool*"}}},
+		},
+	}
+	require.Equal(t, "github.com/*/*", commitConfig.Signatures[0].RemoteTargets[0].Pattern())
+
+	require.Equal(t, "host", commitConfig.MatchSignature("https://github.com/other/repo").Name)
+	require.Equal(t, "owner", commitConfig.MatchSignature("git@github.com:acme/repo.git").Name)
+	require.Equal(t, "repo", commitConfig.MatchSignature("git@gitlab.com:acme/toolkit.git").Name)
+	require.Nil(t, commitConfig.MatchSignature("/srv/git/acme/repo.git"))
+
+	result := commitConfig.MatchSignatureDetailed("git@github.com:acme/repo.git")
+	require.NotNil(t, result.Winner.Target)
+	require.Equal(t, "github.com/acme/*", result.Winner.Pattern)
+}
+
+func TestParseConfig_RemoteTargetProblems(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"signatures": [{"name": "a", "remotePatterns": [], "remoteTargets": [{}]}]}`))
+	require.ErrorContains(t, err, "$.signatures[0].remoteTargets[0]: remote target needs host, owner or repo")
+
+	_, err = ParseConfig([]byte(`{"signatures": [{"name": "a", "remotePatterns": [], "remoteTargets": [{"host": "github.com/acme"}]}]}`))
+	require.ErrorContains(t, err, "$.signatures[0].remoteTargets[0].host")
+
+	// Targets match the canonical form alone, so a general target cannot shadow a pattern
+	// 目标只匹配规范形式，因此通用目标不会遮蔽模式
+	commitConfig, err := ParseConfig([]byte(`{"signatures": [
+		{"name": "a", "remotePatterns": [], "remoteTargets": [{"host": "github.com"}]},
+		{"name": "b", "remotePatterns": ["github.com/acme/*"], "remoteTargets": [{"host": "github.com", "owner": "acme"}]}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, commitConfig.Signatures[1].RemoteTargets, 1)
+
+	_, err = ParseConfig([]byte(`{"signatures": [
+		{"name": "a", "remotePatterns": [], "remoteTargets": [{"host": "github.com", "owner": "acme"}]},
+		{"name": "b", "remotePatterns": [], "remoteTargets": [{"host": "github.com", "owner": "acme", "repo": "*"}]}
+	]}`))
+	require.ErrorContains(t, err, "$.signatures[1].remoteTargets[0]: pattern \"github.com/acme/*\" is shadowed by $.signatures[0].remoteTargets[0]")
+}
+
+// TestResolveSignatureDetailed_InsteadOf validates insteadOf rewrites of repo and global config
+// Tests that the rewritten URL is matched and the configured URL is kept
+//
+// TestResolveSignatureDetailed_InsteadOf 验证仓库和全局配置中的 insteadOf 重写
+// 测试匹配的是重写后的 URL，并保留配置中的 URL
+func TestResolveSignatureDetailed_InsteadOf(t *testing.T) {
+	homeDIR := t.TempDir()
+	t.Setenv("HOME", homeDIR)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDIR, ".config"))
+	require.NoError(t, os.WriteFile(filepath.Join(homeDIR, ".gitconfig"), []byte("[url \"git@gitlab.com:\"]\n\tinsteadOf = gl:\n"), 0644))
+
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+	repo, err := git.PlainOpen(tempDIR)
+	require.NoError(t, err)
+
+	repoConfig, err := repo.Config()
+	require.NoError(t, err)
+	repoConfig.URLs["git@github.com:"] = &config.URL{Name: "git@github.com:", InsteadOf: "gh:"}
+	repoConfig.Remotes["origin"] = &config.RemoteConfig{Name: "origin", URLs: []string{"gh:org/repo.git"}}
+	repoConfig.Remotes["work"] = &config.RemoteConfig{Name: "work", URLs: []string{"gl:team/repo.git"}}
+	require.NoError(t, repo.SetConfig(repoConfig))
+
+	commitConfig := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "github", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "gitlab", RemoteTargets: []*RemoteTarget{{Host: "gitlab.com", Owner: "team"}}},
+		},
+		RemotePolicy: &RemotePolicy{Mode: RemoteModeBest},
+	}
+	resolution := commitConfig.ResolveSignatureDetailed(tempDIR)
+	require.Len(t, resolution.Candidates, 2)
+	require.Equal(t, "gh:org/repo.git", resolution.Candidates[0].ConfiguredURL)
+	require.Equal(t, "git@github.com:org/repo.git", resolution.Candidates[0].Match.RemoteURL)
+	require.Equal(t, "github", resolution.Candidates[0].Match.Signature.Name)
+	require.Equal(t, "git@gitlab.com:team/repo.git", resolution.Candidates[1].Match.RemoteURL)
+	require.Equal(t, "gitlab", resolution.Candidates[1].Match.Signature.Name)
+	require.Equal(t, "work", resolution.RemoteName)
+}
@@ -0,0 +1,367 @@
+// Package commitmate computes and creates semver tags of the Go modules in the repo
+// Bumps each module from conventional commits since its latest tag, following Go's "/vN" module path rule
+//
+// commitmate 包计算并创建仓库中 Go 模块的语义版本标签
+// 根据每个模块最新标签以来的约定式提交升级版本，遵循 Go 的 "/vN" 模块路径规则
+package commitmate
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/osexec"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Version bumps in increasing order
+// 按升序排列的版本升级
+const (
+	BumpNone  = ""      // No commit touches the module // 没有提交涉及该模块
+	BumpPatch = "patch" // Fixes and other changes // 修复和其他更改
+	BumpMinor = "minor" // New features // 新功能
+	BumpMajor = "major" // Breaking changes // 破坏性更改
+)
+
+// TagOptions configures TagModules
+// TagOptions 配置 TagModules
+type TagOptions struct {
+	Username   string // Tagger name, blank uses git config // 标签创建者名称，为空时使用 git 配置
+	Mailbox    string // Tagger mailbox, blank uses git config // 标签创建者邮箱，为空时使用 git 配置
+	Message    string // Annotation message, blank means "Release <tag>" // 注释消息，为空表示 "Release <tag>"
+	Sign       bool   // Sign the tags with git tag -s // 使用 git tag -s 签名标签
+	SigningKey string // Key passed to git tag -u when signing // 签名时传给 git tag -u 的密钥
+	DryRun     bool   // Compute versions without creating tags // 仅计算版本而不创建标签
+}
+
+// ModuleTag is the computed next version of one module
+// ModuleTag 是一个模块计算出的下一个版本
+type ModuleTag struct {
+	ModuleDIR  string // Module DIR relative to the repo root // 相对于仓库根目录的模块 DIR
+	ModulePath string // Module path from go.mod // 来自 go.mod 的模块路径
+	Previous   string // Latest tag of the module, blank when none // 模块的最新标签，没有时为空
+	Next       string // Next tag, blank when not tagged // 下一个标签，不打标签时为空
+	Bump       string // Bump from the commits // 由提交得出的升级
+	Commits    int    // Commits touching the module since the previous tag // 自上一个标签以来涉及该模块的提交数
+	Note       string // Why no tag is created // 不创建标签的原因
+	Created    bool   // Whether the tag was created // 标签是否已创建
+}
+
+// String returns the module line of the tag report
+// String 返回标签报告中的模块行
+func (t *ModuleTag) String() string {
+	line := fmt.Sprintf("%s (%s): %s -> %s", t.ModulePath, t.ModuleDIR, zerotern.VV(t.Previous, "none"), zerotern.VV(t.Next, "none"))
+	if t.Bump != BumpNone {
+		line += fmt.Sprintf(", %s bump from %d commit(s)", t.Bump, t.Commits)
+	}
+	if t.Note != "" {
+		line += ", " + t.Note
+	}
+	return line
+}
+
+// TagModules computes the next semver tag of each module at HEAD and creates annotated tags
+// Nested modules use "dir/vX.Y.Z" tags, a "/vN" module path keeps tags at major N,
+// breaking changes in v1+ modules need a new "/vN" module path first
+//
+// TagModules 计算 HEAD 处每个模块的下一个语义版本标签并创建附注标签
+// 嵌套模块使用 "dir/vX.Y.Z" 标签，"/vN" 模块路径的标签保持在主版本 N，
+// v1 及以上模块的破坏性更改需要先使用新的 "/vN" 模块路径
+func TagModules(projectRoot string, options *TagOptions) ([]*ModuleTag, error) {
+	client, err := gogit.New(projectRoot)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headReference, err := repo.Head()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	headCommit, err := repo.CommitObject(headReference.Hash())
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	files, err := commitFiles(headCommit)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	var tags []*ModuleTag
+	for name, file := range files {
+		if path.Base(name) != "go.mod" {
+			continue
+		}
+		content, err := readBlob(repo, file.hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if modulePath := modfile.ModulePath([]byte(content)); modulePath != "" {
+			tags = append(tags, &ModuleTag{ModuleDIR: path.Dir(name), ModulePath: modulePath})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].ModuleDIR < tags[j].ModuleDIR
+	})
+	if len(tags) == 0 {
+		return nil, erero.New("no go.mod found at HEAD")
+	}
+	moduleDIRs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		moduleDIRs = append(moduleDIRs, tag.ModuleDIR)
+	}
+
+	for _, tag := range tags {
+		if err := computeModuleTag(repo, headCommit, tag, moduleDIRs); err != nil {
+			return nil, erero.Wro(err)
+		}
+	}
+	if options.DryRun {
+		return tags, nil
+	}
+
+	commitInfo := &gogit.CommitInfo{Name: options.Username, Mailbox: options.Mailbox}
+	setFromGitConfig(projectRoot, commitInfo)
+	for _, tag := range tags {
+		if tag.Next == "" {
+			continue
+		}
+		message := zerotern.VV(options.Message, "Release "+tag.Next)
+		if err := createReleaseTag(projectRoot, repo, headCommit.Hash, tag.Next, message, commitInfo, options); err != nil {
+			return tags, erero.Wro(err)
+		}
+		tag.Created = true
+		zaplog.SUG.Debugln("created tag", tag.Next, "on", headCommit.Hash)
+	}
+	return tags, nil
+}
+
+// tagPrefix returns the tag prefix of the module DIR
+// A major version DIR matching the "/vN" module path suffix is left out, as the go command does
+//
+// tagPrefix 返回模块 DIR 的标签前缀
+// 与 "/vN" 模块路径后缀匹配的主版本 DIR 会被省略，与 go 命令的做法一致
+func tagPrefix(moduleDIR string, modulePath string) string {
+	dir := moduleDIR
+	if _, pathMajor, ok := module.SplitPathVersion(modulePath); ok && pathMajor != "" && path.Base(dir) == strings.TrimPrefix(pathMajor, "/") {
+		dir = path.Dir(dir)
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// pathMajorNumber returns N of a "/vN" module path, 0 when the path has no major suffix
+// pathMajorNumber 返回 "/vN" 模块路径中的 N，路径没有主版本后缀时返回 0
+func pathMajorNumber(modulePath string) int {
+	_, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok || !strings.HasPrefix(pathMajor, "/v") {
+		return 0
+	}
+	number, _ := strconv.Atoi(strings.TrimPrefix(pathMajor, "/v"))
+	return number
+}
+
+// computeModuleTag finds the previous tag, the bump from the commits touching the module and the next tag
+// computeModuleTag 查找上一个标签、涉及该模块的提交得出的升级以及下一个标签
+func computeModuleTag(repo *git.Repository, headCommit *object.Commit, tag *ModuleTag, moduleDIRs []string) error {
+	prefix := tagPrefix(tag.ModuleDIR, tag.ModulePath)
+	pathMajor := pathMajorNumber(tag.ModulePath)
+	acceptMajor := func(version string) bool {
+		major, _ := strconv.Atoi(strings.TrimPrefix(semver.Major(version), "v"))
+		if pathMajor == 0 {
+			return major <= 1
+		}
+		return major == pathMajor
+	}
+
+	previous, previousHash := findLatestTag(repo, headCommit.Hash, prefix, acceptMajor)
+	tag.Previous = previous
+	if previousHash == headCommit.Hash {
+		tag.Note = "HEAD is already tagged"
+		return nil
+	}
+	var fromHash *plumbing.Hash
+	if previous != "" {
+		fromHash = &previousHash
+	}
+	commits, err := listCommitsInRange(repo, fromHash, headCommit.Hash)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	for _, commitObject := range commits {
+		touched, err := touchesModule(commitObject, tag.ModuleDIR, moduleDIRs)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		if !touched {
+			continue
+		}
+		tag.Commits++
+		tag.Bump = maxBump(tag.Bump, commitBump(commitObject.Message))
+	}
+	if tag.Bump == BumpNone {
+		tag.Note = "no changes since the previous tag"
+		return nil
+	}
+
+	current := semver.Canonical(strings.TrimPrefix(previous, prefix))
+	next, note := nextVersion(current, tag.Bump, pathMajor)
+	if next == "" {
+		tag.Note = note
+		if tag.Bump == BumpMajor {
+			tag.Note += fmt.Sprintf(", change the module path to %s first", nextMajorModulePath(tag.ModulePath))
+		}
+		return nil
+	}
+	tag.Next = prefix + next
+	tag.Note = note
+	return nil
+}
+
+// nextMajorModulePath returns the module path with the next "/vN" suffix
+// nextMajorModulePath 返回带有下一个 "/vN" 后缀的模块路径
+func nextMajorModulePath(modulePath string) string {
+	prefix, _, _ := module.SplitPathVersion(modulePath)
+	return fmt.Sprintf("%s/v%d", prefix, max(pathMajorNumber(modulePath), 1)+1)
+}
+
+// nextVersion bumps the canonical version, blank starts at v0.1.0 or vN.0.0 for "/vN" modules
+// A prerelease becomes its release, breaking changes bump the minor version of v0 modules and are refused when the major is fixed
+//
+// nextVersion 升级规范版本，为空时从 v0.1.0 开始，"/vN" 模块从 vN.0.0 开始
+// 预发布版本转为正式版本，破坏性更改在 v0 模块中升级次版本，在主版本固定时被拒绝
+func nextVersion(current string, bump string, pathMajor int) (string, string) {
+	if current == "" {
+		if pathMajor >= 2 {
+			return fmt.Sprintf("v%d.0.0", pathMajor), "first release"
+		}
+		return "v0.1.0", "first release"
+	}
+	var major, minor, patch int
+	if _, err := fmt.Sscanf(current, "v%d.%d.%d", &major, &minor, &patch); err != nil {
+		return "", fmt.Sprintf("cannot bump %s", current)
+	}
+	switch {
+	case semver.Prerelease(current) != "" && (bump != BumpMajor || major == 0):
+		return fmt.Sprintf("v%d.%d.%d", major, minor, patch), "release of " + current
+	case bump == BumpMajor && major == 0:
+		return fmt.Sprintf("v0.%d.0", minor+1), "breaking change bumps minor in v0"
+	case bump == BumpMajor:
+		return "", fmt.Sprintf("breaking changes need v%d", major+1)
+	case bump == BumpMinor:
+		return fmt.Sprintf("v%d.%d.0", major, minor+1), ""
+	default:
+		return fmt.Sprintf("v%d.%d.%d", major, minor, patch+1), ""
+	}
+}
+
+// commitBump returns the bump of the commit message: breaking is major, feat is minor, else patch
+// commitBump 返回提交消息对应的升级：破坏性为主版本，feat 为次版本，其他为修订版本
+func commitBump(message string) string {
+	conventional, err := ParseConventionalMessage(message)
+	switch {
+	case err != nil:
+		return BumpPatch
+	case conventional.IsBreaking():
+		return BumpMajor
+	case conventional.Type == "feat":
+		return BumpMinor
+	default:
+		return BumpPatch
+	}
+}
+
+// maxBump returns the larger of the two bumps
+// maxBump 返回两个升级中较大的一个
+func maxBump(a string, b string) string {
+	order := map[string]int{BumpNone: 0, BumpPatch: 1, BumpMinor: 2, BumpMajor: 3}
+	if order[b] > order[a] {
+		return b
+	}
+	return a
+}
+
+// touchesModule reports whether the commit changes files of the module, not counting nested modules
+// Merge commits are skipped since their changes come with the merged commits
+//
+// touchesModule 判断提交是否更改了该模块的文件，不计嵌套模块
+// 跳过合并提交，因为其更改来自被合并的提交
+func touchesModule(commitObject *object.Commit, moduleDIR string, moduleDIRs []string) (bool, error) {
+	if len(commitObject.ParentHashes) > 1 {
+		return false, nil
+	}
+	tree, err := commitObject.Tree()
+	if err != nil {
+		return false, erero.Wro(err)
+	}
+	var parentTree *object.Tree
+	if len(commitObject.ParentHashes) == 1 {
+		parent, err := commitObject.Parent(0)
+		if err != nil {
+			return false, erero.Wro(err)
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return false, erero.Wro(err)
+		}
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return false, erero.Wro(err)
+	}
+	for _, change := range changes {
+		name := zerotern.VV(change.To.Name, change.From.Name)
+		if owningModuleDIR(name, moduleDIRs) == moduleDIR {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// owningModuleDIR returns the deepest module DIR holding the path, blank when none does
+// owningModuleDIR 返回包含该路径的最深模块 DIR，没有时返回空
+func owningModuleDIR(name string, moduleDIRs []string) string {
+	owner := ""
+	for _, dir := range moduleDIRs {
+		if (dir == "." || strings.HasPrefix(name, dir+"/")) && (owner == "" || len(dir) > len(owner)) {
+			owner = dir
+		}
+	}
+	return owner
+}
+
+// createReleaseTag creates the annotated tag with the tagger, signed tags go through git tag -s
+// createReleaseTag 使用标签创建者创建附注标签，签名标签通过 git tag -s 创建
+func createReleaseTag(projectRoot string, repo *git.Repository, hash plumbing.Hash, name string, message string, commitInfo *gogit.CommitInfo, options *TagOptions) error {
+	if !options.Sign {
+		_, err := repo.CreateTag(name, hash, &git.CreateTagOptions{
+			Tagger:  commitInfo.GetObjectSignature(),
+			Message: message,
+		})
+		if err != nil {
+			return erero.Wro(err)
+		}
+		return nil
+	}
+
+	args := []string{"-c", "user.name=" + commitInfo.Name, "-c", "user.email=" + commitInfo.Mailbox, "tag", "-s"}
+	if options.SigningKey != "" {
+		args = append(args, "-u", options.SigningKey)
+	}
+	args = append(args, "-m", message, name, hash.String())
+	if _, err := osexec.NewCommandConfig().WithPath(projectRoot).Exec("git", args...); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+// TestTagModules validates per-module versions in a repo with a root and a nested module
+// Tests the "dir/" tag prefix, commits counted by the module owning the files and created annotated tags
+//
+// TestTagModules 验证包含根模块和嵌套模块的仓库中每个模块的版本
+// 测试 "dir/" 标签前缀、按拥有文件的模块统计提交以及创建的附注标签
+func TestTagModules(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "go.mod", "module example.com/demo\n\ngo 1.22\n", &CommitFlags{Message: "feat: add root module"})
+	commitTestFile(t, tempDIR, "tools/go.mod", "module example.com/demo/tools\n\ngo 1.22\n", &CommitFlags{Message: "feat: add tools module"})
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headHash := rese.P1(repo.Head()).Hash()
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.2.0"), headHash)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("tools/v0.3.1"), headHash)))
+
+	commitTestFile(t, tempDIR, "main.go", "package main\n", &CommitFlags{Message: "fix: handle empty input"})
+	commitTestFile(t, tempDIR, "tools/tool.go", "package tools\n", &CommitFlags{Message: "feat(tools)!: drop Run"})
+
+	tags, err := TagModules(tempDIR, &TagOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	require.Equal(t, "v1.2.0", tags[0].Previous)
+	require.Equal(t, "v1.2.1", tags[0].Next)
+	require.Equal(t, BumpPatch, tags[0].Bump)
+	require.Equal(t, 1, tags[0].Commits)
+	require.Equal(t, "tools/v0.3.1", tags[1].Previous)
+	require.Equal(t, "tools/v0.4.0", tags[1].Next)
+	require.Equal(t, BumpMajor, tags[1].Bump)
+	require.False(t, tags[1].Created)
+	_, err = repo.Tag("v1.2.1")
+	require.ErrorIs(t, err, git.ErrTagNotFound)
+
+	tags, err = TagModules(tempDIR, &TagOptions{Username: "Tagger", Mailbox: "tagger@example.com"})
+	require.NoError(t, err)
+	require.True(t, tags[0].Created)
+	tagObject := rese.P1(repo.TagObject(rese.P1(repo.Tag("tools/v0.4.0")).Hash()))
+	require.Equal(t, "Tagger", tagObject.Tagger.Name)
+	require.Equal(t, "Release tools/v0.4.0\n", tagObject.Message)
+	require.Equal(t, rese.P1(repo.Head()).Hash(), tagObject.Target)
+
+	tags, err = TagModules(tempDIR, &TagOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, "HEAD is already tagged", tags[0].Note)
+	require.Empty(t, tags[0].Next)
+}
+
+func TestTagModules_MajorPath(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "go.mod", "module example.com/demo\n\ngo 1.22\n", &CommitFlags{Message: "add go.mod"})
+	commitTestFile(t, tempDIR, "v3/go.mod", "module example.com/demo/v3\n\ngo 1.22\n", &CommitFlags{Message: "feat: add v3"})
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), rese.P1(repo.Head()).Hash())))
+	commitTestFile(t, tempDIR, "main.go", "package main\n", &CommitFlags{Message: "feat!: drop Open"})
+	commitTestFile(t, tempDIR, "v3/demo.go", "package demo\n", &CommitFlags{Message: "fix: handle nil"})
+
+	tags, err := TagModules(tempDIR, &TagOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	require.Empty(t, tags[0].Next)
+	require.Equal(t, BumpMajor, tags[0].Bump)
+	require.Contains(t, tags[0].Note, "example.com/demo/v2")
+	require.Equal(t, "v3", tags[1].ModuleDIR)
+	require.Empty(t, tags[1].Previous)
+	require.Equal(t, "v3.0.0", tags[1].Next)
+}
+
+func TestNextVersion(t *testing.T) {
+	for _, item := range []struct {
+		current, bump, next string
+		pathMajor           int
+	}{
+		{"", BumpPatch, "v0.1.0", 0},
+		{"", BumpMinor, "v2.0.0", 2},
+		{"v0.3.4", BumpMajor, "v0.4.0", 0},
+		{"v1.3.4", BumpMinor, "v1.4.0", 0},
+		{"v1.3.4", BumpPatch, "v1.3.5", 0},
+		{"v1.3.4", BumpMajor, "", 0},
+		{"v2.0.0-rc.1", BumpMinor, "v2.0.0", 2},
+	} {
+		next, _ := nextVersion(item.current, item.bump, item.pathMajor)
+		require.Equal(t, item.next, next, item)
+	}
+	require.Equal(t, "", tagPrefix(".", "example.com/demo"))
+	require.Equal(t, "", tagPrefix("v2", "example.com/demo/v2"))
+	require.Equal(t, "sub/mod/", tagPrefix("sub/mod/v3", "example.com/sub/mod/v3"))
+	require.Equal(t, "sub/mod/", tagPrefix("sub/mod", "example.com/sub/mod/v3"))
+}
@@ -0,0 +1,308 @@
+// Package commitmate provides signature match conditions beyond remote URLs
+// Selects signatures by repo path, branch, hostname and environment, combinable with all/any
+//
+// commitmate 包提供远程 URL 之外的签名匹配条件
+// 按仓库路径、分支、主机名和环境变量选择签名，可通过 all/any 组合
+package commitmate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/zaplog"
+)
+
+// MatchCondition restricts where a signature applies
+// Set fields must all hold, a list field holds when any element matches
+// Default marks a fallback signature, allowed at the top level alone
+//
+// MatchCondition 限制签名适用的位置
+// 已设置的字段必须全部成立，列表字段在任一元素匹配时成立
+// Default 标记回退签名，仅允许出现在顶层
+type MatchCondition struct {
+	Paths     []string          `json:"paths,omitempty"`     // Repo path globs, "~/" is the home DIR, a trailing "/" means the whole tree // 仓库路径 glob，"~/" 表示主目录，末尾的 "/" 表示整个目录树
+	Branches  []string          `json:"branches,omitempty"`  // Current branch globs // 当前分支 glob
+	Hostnames []string          `json:"hostnames,omitempty"` // Machine hostname globs, compared in lowercase // 机器主机名 glob，按小写比较
+	Env       map[string]string `json:"env,omitempty"`       // Environment variable name to value glob, "*" means set // 环境变量名到值 glob 的映射，"*" 表示已设置
+	All       []*MatchCondition `json:"all,omitempty"`       // Each sub-condition holds // 每个子条件都成立
+	Any       []*MatchCondition `json:"any,omitempty"`       // Some sub-condition holds // 某个子条件成立
+	Default   bool              `json:"default,omitempty"`   // Fallback when no other signature matches // 没有其他签名匹配时作为回退
+}
+
+// MatchContext is the checkout information conditions are evaluated against
+// MatchContext 是评估条件时所依据的检出信息
+type MatchContext struct {
+	ProjectRoot string                          // Absolute project path // 项目绝对路径
+	Branch      string                          // Current branch, blank on detached HEAD // 当前分支，HEAD 分离时为空
+	Hostname    string                          // Machine hostname // 机器主机名
+	LookupEnv   func(key string) (string, bool) // Environment lookup, nil means no variables // 环境变量查找，nil 表示没有变量
+	HomeDIR     string                          // Home DIR used in "~/" expansion // 用于 "~/" 展开的主目录
+}
+
+// NewMatchContext collects the project path, current branch, hostname and environment
+// NewMatchContext 收集项目路径、当前分支、主机名和环境变量
+func NewMatchContext(projectRoot string) *MatchContext {
+	ctx := &MatchContext{ProjectRoot: projectRoot, LookupEnv: os.LookupEnv}
+	if absRoot, err := filepath.Abs(projectRoot); err == nil {
+		ctx.ProjectRoot = absRoot
+	}
+	if client, err := gogit.New(projectRoot); err == nil {
+		ctx.Branch = getCurrentBranchName(client)
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		ctx.Hostname = hostname
+	}
+	if homeDIR, err := os.UserHomeDir(); err == nil {
+		ctx.HomeDIR = homeDIR
+	}
+	return ctx
+}
+
+// getCurrentBranchName returns the branch HEAD points to, unborn branches included
+// getCurrentBranchName 返回 HEAD 指向的分支，包括尚无提交的分支
+func getCurrentBranchName(client *gogit.Client) string {
+	headReference, err := client.Repo().Reference(plumbing.HEAD, false)
+	if err != nil || headReference.Type() != plumbing.SymbolicReference {
+		return ""
+	}
+	return headReference.Target().Short()
+}
+
+// hasConstraints reports whether the condition restricts anything besides Default
+// hasConstraints 判断条件在 Default 之外是否有任何限制
+func (c *MatchCondition) hasConstraints() bool {
+	return len(c.Paths) > 0 || len(c.Branches) > 0 || len(c.Hostnames) > 0 || len(c.Env) > 0 || len(c.All) > 0 || len(c.Any) > 0
+}
+
+// Evaluate checks the condition in the context, Default is not considered
+// Returns whether it holds and a description of what matched or failed
+//
+// Evaluate 在上下文中检查条件，不考虑 Default
+// 返回条件是否成立，以及匹配或失败内容的描述
+func (c *MatchCondition) Evaluate(ctx *MatchContext) (bool, string) {
+	if ctx == nil {
+		ctx = &MatchContext{}
+	}
+	var notes []string
+	for _, check := range []func(*MatchContext) (bool, string){c.evaluatePaths, c.evaluateBranches, c.evaluateHostnames, c.evaluateEnv, c.evaluateAll, c.evaluateAny} {
+		holds, note := check(ctx)
+		if !holds {
+			return false, note
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+	}
+	return true, strings.Join(notes, ", ")
+}
+
+// evaluatePaths matches the project path against the path globs
+// evaluatePaths 将项目路径与路径 glob 进行匹配
+func (c *MatchCondition) evaluatePaths(ctx *MatchContext) (bool, string) {
+	if len(c.Paths) == 0 {
+		return true, ""
+	}
+	for _, path := range c.Paths {
+		if ctx.ProjectRoot != "" && utils.MatchRemotePattern(expandPathGlob(path, ctx.HomeDIR), filepath.ToSlash(ctx.ProjectRoot)) >= 0 {
+			return true, fmt.Sprintf("path %q matches %s", path, ctx.ProjectRoot)
+		}
+	}
+	return false, fmt.Sprintf("path %s matches none of %q", zeroAsNone(ctx.ProjectRoot), c.Paths)
+}
+
+// expandPathGlob expands "~/" and turns a trailing "/" into "/**" as git includeIf gitdir does
+// expandPathGlob 展开 "~/"，并像 git includeIf gitdir 一样将末尾的 "/" 变为 "/**"
+func expandPathGlob(path string, homeDIR string) string {
+	if strings.HasPrefix(path, "~/") && homeDIR != "" {
+		path = filepath.ToSlash(homeDIR) + path[1:]
+	}
+	if strings.HasSuffix(path, "/") {
+		path += "**"
+	}
+	return path
+}
+
+// evaluateBranches matches the current branch against the branch globs
+// evaluateBranches 将当前分支与分支 glob 进行匹配
+func (c *MatchCondition) evaluateBranches(ctx *MatchContext) (bool, string) {
+	if len(c.Branches) == 0 {
+		return true, ""
+	}
+	for _, branch := range c.Branches {
+		if ctx.Branch != "" && utils.MatchRemotePattern(branch, ctx.Branch) >= 0 {
+			return true, fmt.Sprintf("branch %q matches %s", branch, ctx.Branch)
+		}
+	}
+	return false, fmt.Sprintf("branch %s matches none of %q", zeroAsNone(ctx.Branch), c.Branches)
+}
+
+// evaluateHostnames matches the machine hostname against the hostname globs
+// evaluateHostnames 将机器主机名与主机名 glob 进行匹配
+func (c *MatchCondition) evaluateHostnames(ctx *MatchContext) (bool, string) {
+	if len(c.Hostnames) == 0 {
+		return true, ""
+	}
+	for _, hostname := range c.Hostnames {
+		if ctx.Hostname != "" && utils.MatchRemotePattern(strings.ToLower(hostname), strings.ToLower(ctx.Hostname)) >= 0 {
+			return true, fmt.Sprintf("hostname %q matches %s", hostname, ctx.Hostname)
+		}
+	}
+	return false, fmt.Sprintf("hostname %s matches none of %q", zeroAsNone(ctx.Hostname), c.Hostnames)
+}
+
+// evaluateEnv requires each environment variable to be set with a matching value
+// evaluateEnv 要求每个环境变量都已设置且值匹配
+func (c *MatchCondition) evaluateEnv(ctx *MatchContext) (bool, string) {
+	if len(c.Env) == 0 {
+		return true, ""
+	}
+	keys := make([]string, 0, len(c.Env))
+	for key := range c.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var value string
+		var exists bool
+		if ctx.LookupEnv != nil {
+			value, exists = ctx.LookupEnv(key)
+		}
+		if !exists {
+			return false, fmt.Sprintf("env %s is not set", key)
+		}
+		if utils.MatchRemotePattern(c.Env[key], value) < 0 {
+			return false, fmt.Sprintf("env %s=%q does not match %q", key, value, c.Env[key])
+		}
+	}
+	return true, fmt.Sprintf("env %s match", strings.Join(keys, ","))
+}
+
+// evaluateAll requires each sub-condition to hold
+// evaluateAll 要求每个子条件都成立
+func (c *MatchCondition) evaluateAll(ctx *MatchContext) (bool, string) {
+	if len(c.All) == 0 {
+		return true, ""
+	}
+	var notes []string
+	for idx, sub := range c.All {
+		holds, note := sub.Evaluate(ctx)
+		if !holds {
+			return false, fmt.Sprintf("all[%d]: %s", idx, note)
+		}
+		notes = append(notes, note)
+	}
+	return true, "all(" + strings.Join(notes, "; ") + ")"
+}
+
+// evaluateAny requires some sub-condition to hold, the first one holding is reported
+// evaluateAny 要求某个子条件成立，报告第一个成立的子条件
+func (c *MatchCondition) evaluateAny(ctx *MatchContext) (bool, string) {
+	if len(c.Any) == 0 {
+		return true, ""
+	}
+	for idx, sub := range c.Any {
+		if holds, note := sub.Evaluate(ctx); holds {
+			return true, fmt.Sprintf("any[%d](%s)", idx, note)
+		}
+	}
+	return false, fmt.Sprintf("none of the %d any-conditions hold", len(c.Any))
+}
+
+// zeroAsNone shows blank context values as "<none>"
+// zeroAsNone 将空白的上下文值显示为 "<none>"
+func zeroAsNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// checkConditionProblems validates the condition tree of one signature
+// checkConditionProblems 验证一个签名的条件树
+func checkConditionProblems(path string, condition *MatchCondition, topLevel bool) []*ConfigProblem {
+	if condition == nil {
+		return []*ConfigProblem{{Path: path, Message: "condition is null"}}
+	}
+	var problems []*ConfigProblem
+	if condition.Default && !topLevel {
+		problems = append(problems, &ConfigProblem{Path: path + ".default", Message: "default is allowed in the top-level conditions alone"})
+	}
+	if !condition.Default && !condition.hasConstraints() {
+		problems = append(problems, &ConfigProblem{Path: path, Message: "condition is empty"})
+	}
+	for idx, pattern := range condition.Paths {
+		itemPath := fmt.Sprintf("%s.paths[%d]", path, idx)
+		if err := utils.ValidatePattern(pattern); err != nil {
+			problems = append(problems, &ConfigProblem{Path: itemPath, Message: err.Error()})
+		} else if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "~/") && !strings.HasPrefix(pattern, "*") && filepath.VolumeName(pattern) == "" {
+			problems = append(problems, &ConfigProblem{Path: itemPath, Message: fmt.Sprintf("path %q must be absolute, start with \"~/\" or start with \"*\"", pattern)})
+		}
+	}
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{
+		{name: "branches", patterns: condition.Branches},
+		{name: "hostnames", patterns: condition.Hostnames},
+	} {
+		for idx, pattern := range list.patterns {
+			if err := utils.ValidatePattern(pattern); err != nil {
+				problems = append(problems, &ConfigProblem{Path: fmt.Sprintf("%s.%s[%d]", path, list.name, idx), Message: err.Error()})
+			}
+		}
+	}
+	for key, pattern := range condition.Env {
+		if key == "" {
+			problems = append(problems, &ConfigProblem{Path: path + ".env", Message: "environment variable name is blank"})
+		} else if pattern != "" && utils.ValidatePattern(pattern) != nil {
+			problems = append(problems, &ConfigProblem{Path: path + ".env." + key, Message: utils.ValidatePattern(pattern).Error()})
+		}
+	}
+	for idx, sub := range condition.All {
+		problems = append(problems, checkConditionProblems(fmt.Sprintf("%s.all[%d]", path, idx), sub, false)...)
+	}
+	for idx, sub := range condition.Any {
+		problems = append(problems, checkConditionProblems(fmt.Sprintf("%s.any[%d]", path, idx), sub, false)...)
+	}
+	return problems
+}
+
+// conditionDecision tells how the conditions of one signature apply in a context
+// conditionDecision 表示一个签名的条件在某个上下文中如何适用
+type conditionDecision struct {
+	eligible bool   // Remote rules of the signature take part in matching // 签名的远程规则参与匹配
+	selects  bool   // Conditions select the signature regardless of remotes // 条件可以不依赖远程直接选中签名
+	fallback bool   // Signature is a default fallback // 签名是默认回退
+	note     string // What matched or failed // 匹配或失败的内容
+}
+
+// decideConditions evaluates the conditions of the signature
+// Signatures without conditions are always eligible, signatures without remote rules are selected by their conditions
+//
+// decideConditions 评估签名的条件
+// 没有条件的签名始终参与匹配，没有远程规则的签名由其条件选中
+func decideConditions(signature *SignatureConfig, ctx *MatchContext) *conditionDecision {
+	condition := signature.Conditions
+	if condition == nil {
+		return &conditionDecision{eligible: true}
+	}
+	holds, note := condition.Evaluate(ctx)
+	if !holds {
+		zaplog.SUG.Debugln("signature", signature.Name, "conditions do not hold:", note)
+		return &conditionDecision{note: note}
+	}
+	hasRemoteRules := len(signature.RemotePatterns) > 0 || len(signature.RemoteTargets) > 0
+	return &conditionDecision{
+		eligible: true,
+		selects:  !hasRemoteRules && condition.hasConstraints() && !condition.Default,
+		fallback: condition.Default,
+		note:     note,
+	}
+}
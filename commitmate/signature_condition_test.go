@@ -0,0 +1,174 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newConditionTestContext creates a fixed context without touching the machine
+// newConditionTestContext 创建不依赖本机环境的固定上下文
+func newConditionTestContext() *MatchContext {
+	env := map[string]string{"GO_COMMIT_PROFILE": "work", "CI": ""}
+	return &MatchContext{
+		ProjectRoot: "/home/user/work/service",
+		Branch:      "feature/login",
+		Hostname:    "Work-Laptop",
+		HomeDIR:     "/home/user",
+		LookupEnv: func(key string) (string, bool) {
+			value, exists := env[key]
+			return value, exists
+		},
+	}
+}
+
+func TestMatchCondition_Evaluate(t *testing.T) {
+	ctx := newConditionTestContext()
+	for _, item := range []struct {
+		name      string
+		condition *MatchCondition
+		holds     bool
+	}{
+		{"home path tree", &MatchCondition{Paths: []string{"~/work/"}}, true},
+		{"other path tree", &MatchCondition{Paths: []string{"~/personal/"}}, false},
+		{"absolute path glob", &MatchCondition{Paths: []string{"/home/*/work/*"}}, true},
+		{"branch glob", &MatchCondition{Branches: []string{"main", "feature/*"}}, true},
+		{"branch mismatch", &MatchCondition{Branches: []string{"release/*"}}, false},
+		{"hostname ignores case", &MatchCondition{Hostnames: []string{"work-*"}}, true},
+		{"env value", &MatchCondition{Env: map[string]string{"GO_COMMIT_PROFILE": "work"}}, true},
+		{"env set", &MatchCondition{Env: map[string]string{"CI": "*"}}, true},
+		{"env unset", &MatchCondition{Env: map[string]string{"HOME_PROFILE": "*"}}, false},
+		{"fields combine as all", &MatchCondition{Paths: []string{"~/work/"}, Branches: []string{"release/*"}}, false},
+		{"all", &MatchCondition{All: []*MatchCondition{{Paths: []string{"~/work/"}}, {Hostnames: []string{"work-laptop"}}}}, true},
+		{"any", &MatchCondition{Any: []*MatchCondition{{Paths: []string{"~/personal/"}}, {Branches: []string{"feature/*"}}}}, true},
+		{"any none", &MatchCondition{Any: []*MatchCondition{{Paths: []string{"~/personal/"}}, {Branches: []string{"main"}}}}, false},
+	} {
+		holds, note := item.condition.Evaluate(ctx)
+		require.Equal(t, item.holds, holds, item.name+": "+note)
+		require.NotEmpty(t, note, item.name)
+	}
+}
+
+func TestMatchCondition_Evaluate_NilContext(t *testing.T) {
+	holds, note := (&MatchCondition{Paths: []string{"/"}}).Evaluate(nil)
+	require.False(t, holds)
+	require.Contains(t, note, "<none>")
+}
+
+// newConditionTestConfig creates signatures selected by location, remote and default
+// newConditionTestConfig 创建按位置、远程和默认选择的签名
+func newConditionTestConfig() *CommitConfig {
+	return &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "work-github", RemotePatterns: []string{"git@github.com:*"}, Conditions: &MatchCondition{Paths: []string{"~/work/"}}},
+			{Name: "personal-github", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "release-host", Conditions: &MatchCondition{All: []*MatchCondition{{Branches: []string{"release/*"}}, {Hostnames: []string{"build-*"}}}}},
+			{Name: "fallback", Conditions: &MatchCondition{Default: true}},
+		},
+	}
+}
+
+// TestMatchSignatureInContext validates the precedence of conditions, remote matches and the default
+// Tests that one remote maps to different identities by location
+//
+// TestMatchSignatureInContext 验证条件、远程匹配和默认签名的优先级
+// 测试同一远程按位置映射到不同身份
+func TestMatchSignatureInContext(t *testing.T) {
+	config := newConditionTestConfig()
+	ctx := newConditionTestContext()
+
+	require.Equal(t, "work-github", config.MatchSignatureInContext("git@github.com:org/repo.git", ctx).Name)
+	require.Equal(t, "personal-github", config.MatchSignature("git@github.com:org/repo.git").Name)
+	require.Equal(t, "fallback", config.MatchSignatureInContext("git@gitlab.com:org/repo.git", ctx).Name)
+	require.Equal(t, "fallback", config.MatchSignatureInContext("", ctx).Name)
+
+	ctx.Branch = "release/v1"
+	ctx.Hostname = "build-01"
+	require.Equal(t, "release-host", config.MatchSignatureInContext("git@github.com:org/repo.git", ctx).Name)
+}
+
+func TestMatchSignatureDetailedInContext(t *testing.T) {
+	config := newConditionTestConfig()
+	ctx := newConditionTestContext()
+
+	match := config.MatchSignatureDetailedInContext("git@github.com:org/repo.git", ctx)
+	require.Equal(t, "work-github", match.Signature.Name)
+	require.Equal(t, `path "~/work/" matches /home/user/work/service`, match.Condition)
+	require.Contains(t, match.Reason, "the earlier signature wins")
+
+	match = config.MatchSignatureDetailed("git@github.com:org/repo.git")
+	require.Equal(t, "personal-github", match.Signature.Name)
+	require.Contains(t, match.Patterns[0].Excluded, "conditions do not hold")
+	require.Empty(t, match.Condition)
+
+	match = config.MatchSignatureDetailedInContext("", ctx)
+	require.Nil(t, match.Winner)
+	require.Equal(t, "fallback", match.Signature.Name)
+	require.Equal(t, "default", match.Condition)
+	require.Contains(t, match.Reason, `signature "fallback" is the default`)
+
+	ctx.Branch = "release/v1"
+	ctx.Hostname = "build-01"
+	match = config.MatchSignatureDetailedInContext("git@github.com:org/repo.git", ctx)
+	require.Nil(t, match.Winner)
+	require.Equal(t, "release-host", match.Signature.Name)
+	require.Contains(t, match.Condition, `branch "release/*" matches release/v1`)
+
+	for _, remoteURL := range []string{"git@github.com:org/repo.git", "git@gitlab.com:org/repo.git", ""} {
+		require.Same(t, config.MatchSignatureDetailedInContext(remoteURL, ctx).Signature, config.MatchSignatureInContext(remoteURL, ctx), remoteURL)
+	}
+}
+
+// TestResolveSignatureDetailed_NoRemote tests that conditions resolve a signature in repos without remotes
+// TestResolveSignatureDetailed_NoRemote 测试在没有远程的仓库中由条件解析签名
+func TestResolveSignatureDetailed_NoRemote(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "github", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "local", Conditions: &MatchCondition{Paths: []string{tempDIR + "/"}}},
+		},
+	}
+	require.Nil(t, config.ResolveSignature(tempDIR))
+
+	config.Signatures[1].Conditions.Paths = []string{tempDIR}
+	config.Compile()
+	resolution := config.ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "no remote is configured", resolution.RemoteNote)
+	require.Equal(t, "local", resolution.Signature().Name)
+	require.Contains(t, resolution.Match.Condition, tempDIR)
+}
+
+func TestCheckConfig_Conditions(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "a", Username: "a", Mailbox: "a@example.com", Conditions: &MatchCondition{Paths: []string{"work/"}, Any: []*MatchCondition{{Default: true}}}},
+			{Name: "b", Username: "b", Mailbox: "b@example.com", Conditions: &MatchCondition{Default: true}},
+			{Name: "c", Username: "c", Mailbox: "c@example.com", Conditions: &MatchCondition{Default: true, Branches: []string{"[main"}}},
+			{Name: "d", Username: "d", Mailbox: "d@example.com", Conditions: &MatchCondition{}},
+		},
+	}
+	var paths []string
+	for _, problem := range config.checkFieldProblems() {
+		paths = append(paths, problem.Path)
+	}
+	require.ElementsMatch(t, []string{
+		"$.signatures[0].conditions.paths[0]",
+		"$.signatures[0].conditions.any[0].default",
+		"$.signatures[2].conditions.branches[0]",
+		"$.signatures[2].conditions.default",
+		"$.signatures[3].conditions",
+	}, paths)
+}
+
+// TestCheckShadowedPatterns_Conditions tests that conditional signatures shadow no other pattern
+// TestCheckShadowedPatterns_Conditions 测试带条件的签名不会遮蔽其他模式
+func TestCheckShadowedPatterns_Conditions(t *testing.T) {
+	config := newConditionTestConfig()
+	require.Empty(t, config.checkShadowedPatterns())
+
+	config.Signatures[0].Conditions = nil
+	require.Len(t, config.checkShadowedPatterns(), 1)
+}
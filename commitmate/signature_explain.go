@@ -0,0 +1,194 @@
+// Package commitmate provides detailed signature resolution results
+// Records the score of each pattern, the remote used, and why the winning signature won
+//
+// commitmate 包提供详细的签名解析结果
+// 记录每个模式的分数、使用的远程以及获胜签名胜出的原因
+package commitmate
+
+import (
+	"fmt"
+
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+)
+
+// PatternMatch records how one remote pattern scored against the remote URL
+// PatternMatch 记录一个远程模式针对远程 URL 的得分情况
+type PatternMatch struct {
+	Signature    *SignatureConfig // Signature owning the pattern // 拥有该模式的签名
+	SignatureIdx int              // Index of the signature in the config // 签名在配置中的索引
+	Pattern      string           // Pattern content, the equivalent glob of a target // 模式内容，目标则为等价的 glob
+	PatternIdx   int              // Index of the pattern or target in the signature // 模式或目标在签名中的索引
+	Target       *RemoteTarget    // Structured target, nil for remote patterns // 结构化目标，远程模式时为 nil
+	Score        int              // Score from utils.MatchRemotePattern, -1 when not matched // 来自 utils.MatchRemotePattern 的分数，不匹配时为 -1
+	Excluded     string           // Why the signature conditions exclude the pattern, blank when eligible // 签名条件排除该模式的原因，可参与时为空
+}
+
+// Matched tells whether the pattern matched the remote URL
+// Matched 表示模式是否匹配远程 URL
+func (m *PatternMatch) Matched() bool {
+	return m.Score >= 0
+}
+
+// competes tells whether the pattern matched and its signature conditions allow it to win
+// competes 表示模式是否匹配且其签名条件允许其胜出
+func (m *PatternMatch) competes() bool {
+	return m.Matched() && m.Excluded == ""
+}
+
+// SignatureMatch is the detailed result of matching one remote URL
+// Lists every pattern of every signature with its score, and explains the winner
+//
+// SignatureMatch 是匹配一个远程 URL 的详细结果
+// 列出每个签名的每个模式及其分数，并解释胜出者
+type SignatureMatch struct {
+	RemoteURL string           // Remote URL being matched // 被匹配的远程 URL
+	Canonical string           // Normalized "host/owner/repo" form, blank when not parseable // 规范化的 "host/owner/repo" 形式，无法解析时为空
+	Signature *SignatureConfig // Winning signature, nil when no pattern matched // 胜出的签名，没有模式匹配时为 nil
+	Winner    *PatternMatch    // Winning pattern, nil when no pattern matched // 胜出的模式，没有模式匹配时为 nil
+	Patterns  []*PatternMatch  // Every pattern evaluated in config sequence // 按配置顺序评估的每个模式
+	Reason    string           // Why the winner won // 胜出原因
+	Condition string           // What the conditions of the winning signature matched, blank without conditions // 胜出签名的条件匹配了什么，无条件时为空
+}
+
+// MatchSignatureDetailed matches the remote URL and keeps the score of each pattern
+// Patterns match the remote URL or its "host/owner/repo" form, targets match the "host/owner/repo" form
+// Selects the same signature as MatchSignature: highest score wins, the earlier pattern wins ties
+//
+// MatchSignatureDetailed 匹配远程 URL 并保留每个模式的分数
+// 模式匹配远程 URL 或其 "host/owner/repo" 形式，目标匹配 "host/owner/repo" 形式
+// 与 MatchSignature 选择相同的签名：分数最高者胜出，分数相同时靠前的模式胜出
+func (config *CommitConfig) MatchSignatureDetailed(remoteURL string) *SignatureMatch {
+	return config.MatchSignatureDetailedInContext(remoteURL, nil)
+}
+
+// MatchSignatureDetailedInContext matches the remote URL in the checkout context and keeps the details
+// Selects the same signature as MatchSignatureInContext and reports which condition matched
+//
+// MatchSignatureDetailedInContext 在检出上下文中匹配远程 URL 并保留详情
+// 与 MatchSignatureInContext 选择相同的签名，并报告匹配的条件
+func (config *CommitConfig) MatchSignatureDetailedInContext(remoteURL string, ctx *MatchContext) *SignatureMatch {
+	forms := newRemoteForms(remoteURL)
+	result := &SignatureMatch{RemoteURL: remoteURL, Canonical: forms.canonical}
+
+	matcher := config.compiledMatcher()
+	decisions := matcher.decide(ctx)
+	for _, rule := range matcher.rules {
+		patternMatch := &PatternMatch{
+			Signature:    rule.signature,
+			SignatureIdx: rule.signatureIdx,
+			Pattern:      rule.pattern,
+			PatternIdx:   rule.patternIdx,
+			Target:       rule.target,
+			Score:        rule.score(forms),
+		}
+		if !rule.eligible(decisions) {
+			patternMatch.Excluded = "conditions do not hold: " + decisions[rule.signatureIdx].note
+		}
+		result.addPatternMatch(patternMatch)
+	}
+
+	if idx := selectedBy(decisions, func(decision *conditionDecision) bool { return decision.selects }); idx >= 0 {
+		result.Winner = nil
+		result.Signature = matcher.signatures[idx]
+		result.Condition = decisions[idx].note
+		result.Reason = fmt.Sprintf("conditions of signature %q hold, which takes precedence over remote patterns", result.Signature.Name)
+		return result
+	}
+	if result.Winner != nil {
+		result.Signature = result.Winner.Signature
+		result.Reason = result.explainWinner()
+		if decisions != nil {
+			result.Condition = decisions[result.Winner.SignatureIdx].note
+		}
+		return result
+	}
+	result.Reason = result.explainWinner()
+	if idx := selectedBy(decisions, func(decision *conditionDecision) bool { return decision.fallback }); idx >= 0 {
+		result.Signature = matcher.signatures[idx]
+		result.Condition = zerotern.VV(decisions[idx].note, "default")
+		result.Reason += fmt.Sprintf(", signature %q is the default", result.Signature.Name)
+	}
+	return result
+}
+
+// addPatternMatch records the pattern match and keeps the first one with the highest score
+// addPatternMatch 记录模式匹配结果并保留分数最高的第一个
+func (m *SignatureMatch) addPatternMatch(patternMatch *PatternMatch) {
+	m.Patterns = append(m.Patterns, patternMatch)
+	if patternMatch.competes() && (m.Winner == nil || patternMatch.Score > m.Winner.Score) {
+		m.Winner = patternMatch
+	}
+}
+
+// explainWinner describes why the winning pattern was selected
+// explainWinner 描述胜出模式被选中的原因
+func (m *SignatureMatch) explainWinner() string {
+	if m.Winner == nil {
+		return fmt.Sprintf("none of the %d patterns matched", len(m.Patterns))
+	}
+
+	var matchedCount int
+	var runnerUp *PatternMatch
+	var tiedOthers []*PatternMatch
+	for _, patternMatch := range m.Patterns {
+		if !patternMatch.competes() {
+			continue
+		}
+		matchedCount++
+		if patternMatch == m.Winner || patternMatch.Signature == m.Winner.Signature {
+			continue
+		}
+		if patternMatch.Score == m.Winner.Score {
+			tiedOthers = append(tiedOthers, patternMatch)
+		} else if runnerUp == nil || patternMatch.Score > runnerUp.Score {
+			runnerUp = patternMatch
+		}
+	}
+
+	switch {
+	case len(tiedOthers) > 0:
+		return fmt.Sprintf("pattern %q ties with %q of signature %q at score %d, the earlier signature wins",
+			m.Winner.Pattern, tiedOthers[0].Pattern, tiedOthers[0].Signature.Name, m.Winner.Score)
+	case runnerUp != nil:
+		return fmt.Sprintf("pattern %q scored %d, higher than %q of signature %q at %d",
+			m.Winner.Pattern, m.Winner.Score, runnerUp.Pattern, runnerUp.Signature.Name, runnerUp.Score)
+	default:
+		return fmt.Sprintf("pattern %q scored %d, the only signature among %d matching patterns",
+			m.Winner.Pattern, m.Winner.Score, matchedCount)
+	}
+}
+
+// SignatureResolution is the detailed result of resolving the signature of a project
+// Reports which remote was used and how the remote URL matched
+//
+// SignatureResolution 是解析项目签名的详细结果
+// 报告使用了哪个远程以及远程 URL 的匹配情况
+type SignatureResolution struct {
+	RemoteName string             // Remote used, blank when no remote is selected // 使用的远程，未选中远程时为空
+	RemoteNote string             // Why this remote was used // 使用该远程的原因
+	Match      *SignatureMatch    // Match details, with a blank remote URL when the repo has no remote // 匹配详情，仓库没有远程时远程 URL 为空
+	Candidates []*RemoteCandidate // Remotes matched, each remote in best mode // 被匹配的远程，best 模式下包含每个远程
+}
+
+// Signature returns the resolved signature, nil when nothing matched
+// Signature 返回解析出的签名，没有匹配时返回 nil
+func (r *SignatureResolution) Signature() *SignatureConfig {
+	if r.Match == nil {
+		return nil
+	}
+	return r.Match.Signature
+}
+
+// ResolveSignatureDetailed resolves the signature of the project and keeps the details
+// Selects the remote by the configured remote policy, 'origin' first when no policy is set
+//
+// ResolveSignatureDetailed 解析项目的签名并保留详情
+// 根据配置的远程策略选择远程，未设置策略时优先 'origin'
+func (config *CommitConfig) ResolveSignatureDetailed(projectRoot string) *SignatureResolution {
+	resolution := config.resolveWithPolicy(projectRoot)
+	if resolution.Signature() == nil {
+		zaplog.SUG.Debugln("no signature resolved:", resolution.RemoteNote)
+	}
+	return resolution
+}
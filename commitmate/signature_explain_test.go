@@ -0,0 +1,93 @@
+package commitmate
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/require"
+)
+
+// newExplainTestConfig creates a config with general, specific and tied patterns
+// newExplainTestConfig 创建包含通用、具体和平局模式的配置
+func newExplainTestConfig() *CommitConfig {
+	return &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "general", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "specific", RemotePatterns: []string{"https://github.com/org/*", "git@github.com:org/*"}},
+			{Name: "tied", RemotePatterns: []string{"*@github.com:org/*"}},
+		},
+	}
+}
+
+// TestMatchSignatureDetailed validates pattern scores and winner explanation
+// Tests that every pattern is listed and the reason mentions the runner-up
+//
+// TestMatchSignatureDetailed 验证模式分数和胜出者说明
+// 测试每个模式都被列出，且原因中提到了第二名
+func TestMatchSignatureDetailed(t *testing.T) {
+	result := newExplainTestConfig().MatchSignatureDetailed("git@github.com:org/repo.git")
+
+	require.Len(t, result.Patterns, 4)
+	require.Equal(t, 15, result.Patterns[0].Score)
+	require.Equal(t, -1, result.Patterns[1].Score)
+	require.False(t, result.Patterns[1].Matched())
+	require.Equal(t, 19, result.Patterns[2].Score)
+	require.Equal(t, 16, result.Patterns[3].Score)
+
+	require.Equal(t, "specific", result.Signature.Name)
+	require.Equal(t, 1, result.Winner.PatternIdx)
+	require.Contains(t, result.Reason, `higher than "*@github.com:org/*" of signature "tied" at 16`)
+}
+
+func TestMatchSignatureDetailed_Tie(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "first", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "second", RemotePatterns: []string{"git@github.com*o*"}},
+			{Name: "third", RemotePatterns: []string{"git*github.com:*"}},
+		},
+	}
+	result := config.MatchSignatureDetailed("git@github.com:org/repo.git")
+	require.Equal(t, "first", result.Signature.Name)
+	require.Contains(t, result.Reason, "ties with")
+	require.Contains(t, result.Reason, "the earlier signature wins")
+}
+
+func TestMatchSignatureDetailed_NoMatch(t *testing.T) {
+	result := newExplainTestConfig().MatchSignatureDetailed("git@gitlab.com:org/repo.git")
+	require.Nil(t, result.Signature)
+	require.Nil(t, result.Winner)
+	require.Equal(t, "none of the 4 patterns matched", result.Reason)
+	require.Nil(t, newExplainTestConfig().MatchSignature("git@gitlab.com:org/repo.git"))
+}
+
+func TestResolveSignatureDetailed(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	resolution := newExplainTestConfig().ResolveSignatureDetailed(tempDIR)
+	require.Nil(t, resolution.Signature())
+	require.Equal(t, "no remote is configured", resolution.RemoteNote)
+
+	// Without origin the remote first in name sequence is used
+	// 没有 origin 时使用名称排序第一的远程
+	repo, err := git.PlainOpen(tempDIR)
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "upstream", URLs: []string{"git@gitlab.com:org/repo.git"}})
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "fork", URLs: []string{"https://github.com/org/repo.git"}})
+	require.NoError(t, err)
+
+	resolution = newExplainTestConfig().ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "fork", resolution.RemoteName)
+	require.Equal(t, "specific", resolution.Signature().Name)
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:user/repo.git"}})
+	require.NoError(t, err)
+
+	resolution = newExplainTestConfig().ResolveSignatureDetailed(tempDIR)
+	require.Equal(t, "origin", resolution.RemoteName)
+	require.Equal(t, "'origin' remote is preferred", resolution.RemoteNote)
+	require.Equal(t, "general", resolution.Signature().Name)
+}
@@ -0,0 +1,191 @@
+// Package commitmate provides the compiled signature matcher
+// Compiles remote patterns and targets once, then ranks them so that matching stops at the first unbeatable score
+//
+// commitmate 包提供已编译的签名匹配器
+// 一次性编译远程模式和目标，然后对其排序，使匹配在遇到无法被超越的分数时停止
+package commitmate
+
+import (
+	"sort"
+
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/yyle88/zaplog"
+)
+
+// matcherRule is one compiled remote pattern or structured target
+// matcherRule 是一个已编译的远程模式或结构化目标
+type matcherRule struct {
+	order        int                    // Position in config sequence, earlier wins ties // 配置顺序中的位置，靠前者赢得平局
+	signatureIdx int                    // Index of the signature // 签名的索引
+	signature    *SignatureConfig       // Signature owning the rule // 拥有该规则的签名
+	patternIdx   int                    // Index of the pattern or target in the signature // 模式或目标在签名中的索引
+	pattern      string                 // Pattern content, the equivalent glob of a target // 模式内容，目标则为等价的 glob
+	target       *RemoteTarget          // Structured target, nil for remote patterns // 结构化目标，远程模式时为 nil
+	compiled     *utils.CompiledPattern // Compiled pattern, nil on targets and invalid patterns // 已编译的模式，目标和无效模式时为 nil
+	compiledT    *compiledTarget        // Compiled target, nil on patterns and invalid targets // 已编译的目标，模式和无效目标时为 nil
+	maxScore     int                    // Highest score the rule can give, -1 when it never matches // 规则可能给出的最高分数，永不匹配时为 -1
+}
+
+// newPatternRule compiles one remote pattern, invalid patterns never match
+// newPatternRule 编译一个远程模式，无效模式永不匹配
+func newPatternRule(signatureIdx int, signature *SignatureConfig, patternIdx int, pattern string) *matcherRule {
+	rule := &matcherRule{signatureIdx: signatureIdx, signature: signature, patternIdx: patternIdx, pattern: pattern, maxScore: -1}
+	if compiled, err := utils.CompilePattern(pattern); err != nil {
+		zaplog.SUG.Debugln("skip invalid pattern:", pattern, "error:", err)
+	} else {
+		rule.compiled = compiled
+		rule.maxScore = compiled.MaxScore()
+	}
+	return rule
+}
+
+// newTargetRule compiles one structured target, invalid targets never match
+// newTargetRule 编译一个结构化目标，无效目标永不匹配
+func newTargetRule(signatureIdx int, signature *SignatureConfig, targetIdx int, target *RemoteTarget) *matcherRule {
+	rule := &matcherRule{signatureIdx: signatureIdx, signature: signature, patternIdx: targetIdx, pattern: target.Pattern(), target: target, maxScore: -1}
+	if compiled, err := compileTarget(target); err != nil {
+		zaplog.SUG.Debugln("skip invalid target:", target.Pattern(), "error:", err)
+	} else {
+		rule.compiledT = compiled
+		rule.maxScore = compiled.maxScore()
+	}
+	return rule
+}
+
+// score scores the rule against the forms of one remote URL, -1 when not matched
+// score 针对一个远程 URL 的各种形式为规则评分，不匹配时返回 -1
+func (r *matcherRule) score(forms *remoteForms) int {
+	switch {
+	case r.compiled != nil:
+		return forms.matchPattern(r.compiled)
+	case r.compiledT != nil:
+		return forms.matchTarget(r.compiledT)
+	default:
+		return -1
+	}
+}
+
+// signatureMatcher holds the compiled rules of all signatures
+// signatureMatcher 保存所有签名的已编译规则
+type signatureMatcher struct {
+	signatures    []*SignatureConfig // Signatures the rules were compiled from // 编译规则所用的签名
+	rules         []*matcherRule     // Rules in config sequence // 按配置顺序排列的规则
+	ranked        []*matcherRule     // Rules by highest possible score, then config sequence // 按最高可能分数、再按配置顺序排列的规则
+	hasConditions bool               // Some signature has conditions // 存在带条件的签名
+}
+
+// newSignatureMatcher compiles the patterns and targets of each signature
+// Within a signature, remote patterns come before remote targets
+//
+// newSignatureMatcher 编译每个签名的模式和目标
+// 在同一签名内，远程模式排在远程目标之前
+func newSignatureMatcher(signatures []*SignatureConfig) *signatureMatcher {
+	matcher := &signatureMatcher{signatures: signatures}
+	for signatureIdx, signature := range signatures {
+		if signature == nil {
+			continue
+		}
+		if signature.Conditions != nil {
+			matcher.hasConditions = true
+		}
+		for patternIdx, pattern := range signature.RemotePatterns {
+			matcher.rules = append(matcher.rules, newPatternRule(signatureIdx, signature, patternIdx, pattern))
+		}
+		for targetIdx, target := range signature.RemoteTargets {
+			if target != nil {
+				matcher.rules = append(matcher.rules, newTargetRule(signatureIdx, signature, targetIdx, target))
+			}
+		}
+	}
+	for idx, rule := range matcher.rules {
+		rule.order = idx
+	}
+
+	matcher.ranked = append([]*matcherRule(nil), matcher.rules...)
+	sort.SliceStable(matcher.ranked, func(i, j int) bool {
+		return matcher.ranked[i].maxScore > matcher.ranked[j].maxScore
+	})
+	return matcher
+}
+
+// decide evaluates the conditions of each signature, nil when no signature has conditions
+// decide 评估每个签名的条件，没有签名带条件时返回 nil
+func (m *signatureMatcher) decide(ctx *MatchContext) []*conditionDecision {
+	if !m.hasConditions {
+		return nil
+	}
+	decisions := make([]*conditionDecision, len(m.signatures))
+	for idx, signature := range m.signatures {
+		if signature == nil {
+			decisions[idx] = &conditionDecision{}
+		} else {
+			decisions[idx] = decideConditions(signature, ctx)
+		}
+	}
+	return decisions
+}
+
+// eligible reports whether the rule takes part in matching under the decisions
+// eligible 判断在给定决策下规则是否参与匹配
+func (r *matcherRule) eligible(decisions []*conditionDecision) bool {
+	return decisions == nil || decisions[r.signatureIdx].eligible
+}
+
+// selectedBy returns the first signature whose decision passes the check, -1 when none
+// selectedBy 返回决策通过检查的第一个签名，没有时返回 -1
+func selectedBy(decisions []*conditionDecision, check func(*conditionDecision) bool) int {
+	for idx, decision := range decisions {
+		if check(decision) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// best returns the rule with the highest score, the earliest rule on ties, nil when nothing matches
+// Walks rules by highest possible score and stops once no remaining rule can beat the best found
+// Rules of signatures whose conditions do not hold are skipped
+//
+// best 返回分数最高的规则，平局时返回最靠前的规则，没有匹配时返回 nil
+// 按最高可能分数遍历规则，一旦剩余规则都无法超过已找到的最佳者就停止
+// 跳过条件不成立的签名的规则
+func (m *signatureMatcher) best(forms *remoteForms, decisions []*conditionDecision) (*matcherRule, int) {
+	var bestRule *matcherRule
+	bestScore := -1
+	for _, rule := range m.ranked {
+		if rule.maxScore < 0 || rule.maxScore < bestScore {
+			break
+		}
+		if !rule.eligible(decisions) {
+			continue
+		}
+		score := rule.score(forms)
+		if score < 0 {
+			continue
+		}
+		if score > bestScore || (score == bestScore && rule.order < bestRule.order) {
+			bestRule, bestScore = rule, score
+		}
+	}
+	return bestRule, bestScore
+}
+
+// Compile compiles the remote patterns and targets into the matcher used by MatchSignature
+// LoadConfig and ParseConfig call it, callers changing Signatures afterwards call it again
+//
+// Compile 将远程模式和目标编译为 MatchSignature 使用的匹配器
+// LoadConfig 和 ParseConfig 会调用它，之后修改 Signatures 的调用方需要再次调用
+func (config *CommitConfig) Compile() {
+	config.matcher.Store(newSignatureMatcher(config.Signatures))
+}
+
+// compiledMatcher returns the compiled matcher, compiling it on first use
+// compiledMatcher 返回已编译的匹配器，首次使用时进行编译
+func (config *CommitConfig) compiledMatcher() *signatureMatcher {
+	if matcher := config.matcher.Load(); matcher != nil {
+		return matcher
+	}
+	matcher := newSignatureMatcher(config.Signatures)
+	config.matcher.CompareAndSwap(nil, matcher)
+	return config.matcher.Load()
+}
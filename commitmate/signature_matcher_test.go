@@ -0,0 +1,105 @@
+package commitmate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newLargeTestConfig creates a config with many signatures of mixed pattern kinds
+// newLargeTestConfig 创建包含大量混合模式种类签名的配置
+func newLargeTestConfig(count int) *CommitConfig {
+	config := &CommitConfig{}
+	for idx := 0; idx < count; idx++ {
+		config.Signatures = append(config.Signatures, &SignatureConfig{
+			Name: fmt.Sprintf("team-%d", idx),
+			RemotePatterns: []string{
+				fmt.Sprintf("git@github.com:team-%d/*", idx),
+				fmt.Sprintf("https://{github,gitlab}.com/team-%d/*", idx),
+			},
+			RemoteTargets: []*RemoteTarget{{Host: "gitlab.corp.com", Owner: fmt.Sprintf("team-%d", idx)}},
+		})
+	}
+	config.Signatures = append(config.Signatures,
+		&SignatureConfig{Name: "regex", RemotePatterns: []string{`re:git@github\.com:team-[0-9]+/special-.*`}},
+		&SignatureConfig{Name: "fallback", RemotePatterns: []string{"*"}},
+	)
+	return config
+}
+
+// TestMatchSignature_CompiledAgreesWithDetailed validates the ranked matcher against the full scan
+// Tests that MatchSignature selects the same signature as MatchSignatureDetailed
+//
+// TestMatchSignature_CompiledAgreesWithDetailed 验证排序匹配器与完整扫描结果一致
+// 测试 MatchSignature 与 MatchSignatureDetailed 选择相同的签名
+func TestMatchSignature_CompiledAgreesWithDetailed(t *testing.T) {
+	config := newLargeTestConfig(50)
+	for _, remoteURL := range []string{
+		"git@github.com:team-7/repo.git",
+		"https://gitlab.com/team-12/repo",
+		"ssh://git@gitlab.corp.com/team-49/repo.git",
+		"git@github.com:team-3/special-repo.git",
+		"git@github.com:team-3/special-repo-with-a-very-long-name-that-beats-the-glob.git",
+		"git@bitbucket.org:someone/repo.git",
+		"/srv/git/repo.git",
+	} {
+		require.Same(t, config.MatchSignatureDetailed(remoteURL).Signature, config.MatchSignature(remoteURL), remoteURL)
+	}
+	require.Equal(t, "team-7", config.MatchSignature("git@github.com:team-7/repo.git").Name)
+	require.Equal(t, "team-49", config.MatchSignature("ssh://git@gitlab.corp.com/team-49/repo.git").Name)
+	require.Equal(t, "fallback", config.MatchSignature("git@bitbucket.org:someone/repo.git").Name)
+}
+
+func TestMatchSignature_TieKeepsEarlier(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{
+			{Name: "first", RemotePatterns: []string{"git@github.com:*"}},
+			{Name: "second", RemotePatterns: []string{"git@github.com*o*"}},
+		},
+	}
+	require.Equal(t, "first", config.MatchSignature("git@github.com:org/repo.git").Name)
+}
+
+func TestCommitConfig_Compile(t *testing.T) {
+	config := &CommitConfig{
+		Signatures: []*SignatureConfig{{Name: "github", RemotePatterns: []string{"git@github.com:*"}}},
+	}
+	require.Nil(t, config.MatchSignature("git@gitlab.com:org/repo.git"))
+
+	// Changes to Signatures take effect after Compile
+	// 对 Signatures 的修改在调用 Compile 之后生效
+	config.Signatures = append(config.Signatures, &SignatureConfig{Name: "gitlab", RemotePatterns: []string{"git@gitlab.com:*"}})
+	require.Nil(t, config.MatchSignature("git@gitlab.com:org/repo.git"))
+	config.Compile()
+	require.Equal(t, "gitlab", config.MatchSignature("git@gitlab.com:org/repo.git").Name)
+
+	// In-place edits of patterns and targets take effect after Compile as well
+	// 对模式和目标的原地修改同样在调用 Compile 之后生效
+	config.Signatures[1].RemotePatterns[0] = "git@gitlab.corp.com:*"
+	config.Signatures[0].RemoteTargets = []*RemoteTarget{{Host: "gitlab.com", Owner: "org"}}
+	require.Equal(t, "gitlab", config.MatchSignature("git@gitlab.com:org/repo.git").Name)
+	config.Compile()
+	require.Equal(t, "github", config.MatchSignature("git@gitlab.com:org/repo.git").Name)
+
+	// The matcher is reused between Compile calls
+	// 两次调用 Compile 之间复用匹配器
+	matcher := config.compiledMatcher()
+	require.Same(t, matcher, config.compiledMatcher())
+}
+
+func BenchmarkMatchSignature_LargeConfig(b *testing.B) {
+	config := newLargeTestConfig(500)
+	config.Compile()
+	for b.Loop() {
+		config.MatchSignature("git@github.com:team-250/repo.git")
+	}
+}
+
+func BenchmarkMatchSignatureDetailed_LargeConfig(b *testing.B) {
+	config := newLargeTestConfig(500)
+	config.Compile()
+	for b.Loop() {
+		config.MatchSignatureDetailed("git@github.com:team-250/repo.git")
+	}
+}
@@ -0,0 +1,331 @@
+// Package commitmate splits staged changes into one commit per Go package, module or DIR
+// Builds the commits on top of HEAD with plumbing and moves the branch once at the end
+//
+// commitmate 包将已暂存的更改拆分为每个 Go 包、模块或 DIR 一个提交
+// 使用底层操作在 HEAD 之上构建提交，并在最后一次性移动分支
+package commitmate
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/tern/zerotern"
+	"github.com/yyle88/zaplog"
+	"golang.org/x/mod/modfile"
+)
+
+// Split modes accepted by --split-by
+// --split-by 接受的拆分模式
+const (
+	SplitByPackage = "package" // One commit per Go package DIR // 每个 Go 包 DIR 一个提交
+	SplitByModule  = "module"  // One commit per Go module // 每个 Go 模块一个提交
+	SplitByDir     = "dir"     // One commit per DIR // 每个 DIR 一个提交
+)
+
+// DefaultSplitMessage is the message template of split commits
+// Supports {group}, {message} and {count} placeholders, conventional messages put {group} in the scope
+//
+// DefaultSplitMessage 是拆分提交的消息模板
+// 支持 {group}、{message} 和 {count} 占位符，约定式消息将 {group} 放入范围
+const DefaultSplitMessage = "{group}: {message}"
+
+// splitOtherGroup collects files outside each Go package or module, committed last
+// splitOtherGroup 收集不属于任何 Go 包或模块的文件，最后提交
+const splitOtherGroup = "other"
+
+// CommitGroup is one group of changed paths committed together
+// CommitGroup 是一起提交的一组已更改路径
+type CommitGroup struct {
+	Name    string        // Package DIR, module path or DIR // 包 DIR、模块路径或 DIR
+	Paths   []string      // Changed paths relative to the repo root // 相对于仓库根目录的已更改路径
+	Message string        // Commit message built from the template // 由模板构建的提交消息
+	Hash    plumbing.Hash // Commit hash once created // 创建后的提交哈希
+}
+
+// checkSplitMode returns an error when the mode is not supported
+// checkSplitMode 在模式不受支持时返回错误
+func checkSplitMode(mode string) error {
+	switch mode {
+	case SplitByPackage, SplitByModule, SplitByDir:
+		return nil
+	default:
+		return erero.Errorf("unknown split-by mode %q, use package, module or dir", mode)
+	}
+}
+
+// writeSplitCommit stores each split commit, tests replace it to fail the split at some commit
+// writeSplitCommit 存储每个拆分提交，测试替换它以使拆分在某个提交处失败
+var writeSplitCommit = writeCommit
+
+// SplitCommit commits the staged changes as a sequence of commits, one per group
+// Each commit uses the signature of the commit info and a message from the template
+// The branch moves only when each commit is built, else it stays at the original HEAD
+//
+// SplitCommit 将已暂存的更改提交为一系列提交，每组一个
+// 每个提交使用提交信息中的签名和由模板生成的消息
+// 只有在每个提交都构建完成后分支才会移动，否则保持在原始 HEAD
+func SplitCommit(projectRoot string, client *gogit.Client, commitInfo *gogit.CommitInfo, mode string, template string) ([]*CommitGroup, error) {
+	if err := checkSplitMode(mode); err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headReference, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	if headReference.Type() != plumbing.SymbolicReference {
+		return nil, erero.New("HEAD is detached, checkout a branch first")
+	}
+	branchReference, err := repo.Reference(headReference.Target(), true)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	headCommit, err := repo.CommitObject(branchReference.Hash())
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	files, err := commitFiles(headCommit)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedFiles, err := indexFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	groups := groupChangedPaths(projectRoot, listStagedChanges(files, stagedFiles), mode)
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	newHead := headCommit.Hash
+	for _, group := range groups {
+		for _, name := range group.Paths {
+			setTreeFile(files, name, stagedFiles[name])
+		}
+		treeHash, err := writeTree(repo, files)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		signature := commitInfo.GetObjectSignature()
+		group.Message = buildSplitMessage(template, group, commitInfo.Message)
+		newHead, err = writeSplitCommit(repo, &object.Commit{
+			Author:       *signature,
+			Committer:    *signature,
+			Message:      group.Message,
+			TreeHash:     treeHash,
+			ParentHashes: []plumbing.Hash{newHead},
+		})
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		group.Hash = newHead
+		zaplog.SUG.Debugln("split commit", group.Name, len(group.Paths), "files:", newHead)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName("ORIG_HEAD"), branchReference.Hash())); err != nil {
+		return nil, erero.Wro(err)
+	}
+	if err := repo.Storer.CheckAndSetReference(plumbing.NewHashReference(branchReference.Name(), newHead), branchReference); err != nil {
+		return nil, erero.Wro(err)
+	}
+	return groups, nil
+}
+
+// indexFiles maps each path of the index to its staged entry
+// indexFiles 将索引中的每个路径映射到其暂存条目
+func indexFiles(repo *git.Repository) (map[string]treeFile, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	files := make(map[string]treeFile, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		files[entry.Name] = treeFile{mode: entry.Mode, hash: entry.Hash}
+	}
+	return files, nil
+}
+
+// listStagedChanges returns the sorted paths whose staged entry differs from HEAD
+// listStagedChanges 返回暂存条目与 HEAD 不同的路径（已排序）
+func listStagedChanges(headFiles map[string]treeFile, stagedFiles map[string]treeFile) []string {
+	var paths []string
+	for name, file := range stagedFiles {
+		if headFiles[name] != file {
+			paths = append(paths, name)
+		}
+	}
+	for name := range headFiles {
+		if _, exists := stagedFiles[name]; !exists {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// groupChangedPaths groups the paths by the mode, sorted by name with the "other" group last
+// groupChangedPaths 按模式对路径分组，按名称排序且 "other" 组排在最后
+func groupChangedPaths(projectRoot string, paths []string, mode string) []*CommitGroup {
+	// Package DIRs are the DIRs with Go files on disk or among the changes
+	// 包 DIR 是磁盘上或更改中包含 Go 文件的 DIR
+	packageDIRs := map[string]bool{}
+	if mode == SplitByPackage {
+		for _, name := range paths {
+			dir := path.Dir(name)
+			if !packageDIRs[dir] && (path.Ext(name) == ".go" || hasGoFiles(filepath.Join(projectRoot, filepath.FromSlash(dir)))) {
+				packageDIRs[dir] = true
+			}
+		}
+	}
+
+	moduleNames := map[string]string{}
+	groupMap := map[string]*CommitGroup{}
+	for _, name := range paths {
+		var groupName string
+		switch mode {
+		case SplitByDir:
+			groupName = dirGroupName(projectRoot, path.Dir(name))
+		case SplitByPackage:
+			groupName = splitOtherGroup
+			if dir := path.Dir(name); packageDIRs[dir] {
+				groupName = dirGroupName(projectRoot, dir)
+			}
+		case SplitByModule:
+			groupName = zerotern.VV(findModuleName(projectRoot, path.Dir(name), moduleNames), splitOtherGroup)
+		}
+		group, exists := groupMap[groupName]
+		if !exists {
+			group = &CommitGroup{Name: groupName}
+			groupMap[groupName] = group
+		}
+		group.Paths = append(group.Paths, name)
+	}
+
+	groups := make([]*CommitGroup, 0, len(groupMap))
+	for _, group := range groupMap {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if isOther := groups[i].Name == splitOtherGroup; isOther != (groups[j].Name == splitOtherGroup) {
+			return !isOther
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// dirGroupName returns the DIR as the group name, the root DIR takes the project name
+// dirGroupName 返回 DIR 作为组名，根 DIR 使用项目名称
+func dirGroupName(projectRoot string, dir string) string {
+	if dir == "." {
+		return filepath.Base(projectRoot)
+	}
+	return dir
+}
+
+// hasGoFiles reports whether the DIR contains Go files
+// hasGoFiles 判断 DIR 中是否包含 Go 文件
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".go" {
+			return true
+		}
+	}
+	return false
+}
+
+// findModuleName returns the module path of the nearest go.mod at or above the DIR within the project
+// Returns blank when no go.mod is found, results are cached by DIR
+//
+// findModuleName 返回项目内 DIR 及其上级中最近的 go.mod 的模块路径
+// 找不到 go.mod 时返回空，结果按 DIR 缓存
+func findModuleName(projectRoot string, dir string, cache map[string]string) string {
+	if name, exists := cache[dir]; exists {
+		return name
+	}
+	var name string
+	if content, err := os.ReadFile(filepath.Join(projectRoot, filepath.FromSlash(dir), "go.mod")); err == nil {
+		name = modfile.ModulePath(content)
+	}
+	if name == "" && dir != "." {
+		name = findModuleName(projectRoot, path.Dir(dir), cache)
+	}
+	cache[dir] = name
+	return name
+}
+
+// buildSplitMessage fills the template with the group, the message and the file count
+// A conventional message takes the group as its scope, "{group}: {message}" becomes "type(group): description"
+//
+// buildSplitMessage 使用组名、消息和文件数量填充模板
+// 约定式消息将组名作为其范围，"{group}: {message}" 变为 "type(group): description"
+func buildSplitMessage(template string, group *CommitGroup, message string) string {
+	template = zerotern.VV(template, DefaultSplitMessage)
+	if scoped, ok := scopeConventionalMessage(message, group.Name); ok {
+		template = strings.Replace(template, DefaultSplitMessage, "{message}", 1)
+		message = scoped
+	}
+	return strings.NewReplacer(
+		"{group}", group.Name,
+		"{message}", zerotern.VV(message, "update"),
+		"{count}", strconv.Itoa(len(group.Paths)),
+	).Replace(template)
+}
+
+// scopeConventionalMessage puts the scope in the header of a conventional message without one
+// Returns false when the message is not conventional
+//
+// scopeConventionalMessage 将范围放入没有范围的约定式消息的标题中
+// 当消息不是约定式时返回 false
+func scopeConventionalMessage(message string, scope string) (string, bool) {
+	conventional, err := ParseConventionalMessage(message)
+	if err != nil {
+		return "", false
+	}
+	message = strings.TrimSpace(message)
+	if conventional.Scope != "" {
+		return message, true
+	}
+	header, rest, found := strings.Cut(message, "\n")
+	header = strings.TrimSpace(header)
+	header = header[:len(conventional.Type)] + "(" + scope + ")" + header[len(conventional.Type):]
+	if !found {
+		return header, true
+	}
+	return header + "\n" + rest, true
+}
+
+// splitMessages builds the messages the split commits of the staged changes would get
+// splitMessages 构建已暂存更改的拆分提交将会得到的消息
+func splitMessages(projectRoot string, repo *git.Repository, mode string, template string, message string) ([]string, error) {
+	if err := checkSplitMode(mode); err != nil {
+		return nil, erero.Wro(err)
+	}
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedFiles, err := indexFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	var messages []string
+	for _, group := range groupChangedPaths(projectRoot, listStagedChanges(headFiles, stagedFiles), mode) {
+		messages = append(messages, buildSplitMessage(template, group, message))
+	}
+	return messages, nil
+}
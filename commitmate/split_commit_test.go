@@ -0,0 +1,191 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-xlan/gogit"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/rese"
+)
+
+// writeSplitTestFiles writes the files with their content under the project root
+// writeSplitTestFiles 在项目根目录下写入文件及其内容
+func writeSplitTestFiles(t *testing.T, projectRoot string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(projectRoot, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// TestGitCommit_SplitByPackage validates one commit per Go package with the "other" group last
+// Tests that each commit only holds its group and the worktree ends clean
+//
+// TestGitCommit_SplitByPackage 验证每个 Go 包一个提交，且 "other" 组排在最后
+// 测试每个提交只包含其组的文件，且工作区最终是干净的
+func TestGitCommit_SplitByPackage(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"alpha/alpha.go":   "package alpha\n",
+		"alpha/data.json":  "{}\n",
+		"beta/beta.go":     "package beta\n",
+		"docs/guide.md":    "guide\n",
+		"beta/sub/sub.go":  "package sub\n",
+		"beta/sub/sub.txt": "sub\n",
+	})
+	commitFlags := &CommitFlags{
+		Username: "Test Username",
+		Mailbox:  "test@example.com",
+		Message:  "refactor",
+		SplitBy:  SplitByPackage,
+		SplitMsg: DefaultSplitMessage + " ({count})",
+	}
+	require.NoError(t, GitCommit(tempDIR, commitFlags))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	commitObject := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	var messages []string
+	for commitSubject(commitObject.Message) != "Initial commit" {
+		messages = append([]string{commitObject.Message}, messages...)
+		require.Equal(t, "Test Username", commitObject.Author.Name)
+		commitObject = rese.P1(commitObject.Parent(0))
+	}
+	require.Equal(t, []string{"alpha: refactor (2)", "beta: refactor (1)", "beta/sub: refactor (2)", "other: refactor (1)"}, messages)
+
+	betaCommit := rese.P1(repo.CommitObject(*rese.P1(repo.ResolveRevision("HEAD~2"))))
+	_, err := betaCommit.File("beta/beta.go")
+	require.NoError(t, err)
+	_, err = betaCommit.File("beta/sub/sub.go")
+	require.Error(t, err)
+
+	status := rese.V1(rese.P1(repo.Worktree()).Status())
+	require.True(t, status.IsClean())
+}
+
+// TestSplitCommit_FailureKeepsHead validates a split failing at a later commit, or on a detached HEAD, moves nothing
+// Tests that HEAD, ORIG_HEAD and the index stay as before the split
+//
+// TestSplitCommit_FailureKeepsHead 验证在后续提交处失败或在分离的 HEAD 上失败的拆分不会移动任何内容
+// 测试 HEAD、ORIG_HEAD 和索引保持拆分之前的状态
+func TestSplitCommit_FailureKeepsHead(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"alpha/alpha.go": "package alpha\n",
+		"beta/beta.go":   "package beta\n",
+		"gamma/gamma.go": "package gamma\n",
+	})
+	client := rese.P1(gogit.New(tempDIR))
+	require.NoError(t, client.AddAll())
+	repo := client.Repo()
+	headHash := rese.P1(repo.Head()).Hash()
+	origHead := plumbing.NewHashReference(plumbing.ReferenceName("ORIG_HEAD"), headHash)
+	require.NoError(t, repo.Storer.SetReference(origHead))
+	stagedFiles := rese.V1(indexFiles(repo))
+
+	// The second of three commits fails
+	// 三个提交中的第二个失败
+	count := 0
+	writeSplitCommit = func(repo *git.Repository, commitObject *object.Commit) (plumbing.Hash, error) {
+		if count++; count == 2 {
+			return plumbing.ZeroHash, erero.New("write failure")
+		}
+		return writeCommit(repo, commitObject)
+	}
+	t.Cleanup(func() { writeSplitCommit = writeCommit })
+
+	commitInfo := &gogit.CommitInfo{Name: "Test Username", Mailbox: "test@example.com", Message: "refactor"}
+	_, err := SplitCommit(tempDIR, client, commitInfo, SplitByPackage, DefaultSplitMessage)
+	require.ErrorContains(t, err, "write failure")
+	require.Equal(t, 2, count)
+
+	requireUnchanged := func() {
+		require.Equal(t, headHash, rese.P1(repo.Head()).Hash())
+		require.Equal(t, headHash, rese.P1(repo.Reference(plumbing.ReferenceName("ORIG_HEAD"), false)).Hash())
+		require.Equal(t, stagedFiles, rese.V1(indexFiles(repo)))
+	}
+	requireUnchanged()
+
+	// A detached HEAD is refused before any commit is written
+	// 分离的 HEAD 在写入任何提交之前即被拒绝
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, headHash)))
+	_, err = SplitCommit(tempDIR, client, commitInfo, SplitByPackage, DefaultSplitMessage)
+	require.ErrorContains(t, err, "detached")
+	require.Equal(t, 2, count)
+	requireUnchanged()
+}
+
+func TestGitCommit_SplitByUnknownMode(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	oldHead := rese.P1(repo.Head()).Hash()
+	writeSplitTestFiles(t, tempDIR, map[string]string{"a.txt": "a\n"})
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", SplitBy: "file"}
+	require.ErrorContains(t, GitCommit(tempDIR, commitFlags), "unknown split-by mode")
+	require.Equal(t, oldHead, rese.P1(repo.Head()).Hash())
+}
+
+func TestGroupChangedPaths_Module(t *testing.T) {
+	tempDIR := t.TempDir()
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"go.mod":            "module example.com/root\n",
+		"tools/go.mod":      "module example.com/tools\n",
+		"tools/cmd/main.go": "package main\n",
+	})
+	groups := groupChangedPaths(tempDIR, []string{"main.go", "tools/cmd/main.go", "tools/go.mod"}, SplitByModule)
+	require.Len(t, groups, 2)
+	require.Equal(t, "example.com/root", groups[0].Name)
+	require.Equal(t, []string{"main.go"}, groups[0].Paths)
+	require.Equal(t, "example.com/tools", groups[1].Name)
+	require.Equal(t, []string{"tools/cmd/main.go", "tools/go.mod"}, groups[1].Paths)
+
+	groups = groupChangedPaths(tempDIR, []string{"a.txt", "tools/go.mod"}, SplitByDir)
+	require.Equal(t, filepath.Base(tempDIR), groups[0].Name)
+	require.Equal(t, "tools", groups[1].Name)
+}
+
+func TestBuildSplitMessage(t *testing.T) {
+	group := &CommitGroup{Name: "commitmate", Paths: []string{"a.go", "b.go"}}
+	require.Equal(t, "commitmate: update", buildSplitMessage("", group, ""))
+	require.Equal(t, "[commitmate] fix (2 files)", buildSplitMessage("[{group}] {message} ({count} files)", group, "fix"))
+
+	// Conventional messages take the group as their scope
+	// 约定式消息将组名作为其范围
+	require.Equal(t, "feat(commitmate): add x", buildSplitMessage(DefaultSplitMessage, group, "feat: add x"))
+	require.Equal(t, "feat(commitmate)!: drop x\n\nBREAKING CHANGE: x is gone (2)", buildSplitMessage(DefaultSplitMessage+" ({count})", group, "feat!: drop x\n\nBREAKING CHANGE: x is gone"))
+	require.Equal(t, "fix(api): handle nil", buildSplitMessage("", group, "fix(api): handle nil"))
+}
+
+// TestGitCommit_SplitConventional validates --conventional checks the messages of the split commits
+// TestGitCommit_SplitConventional 验证 --conventional 检查拆分提交的消息
+func TestGitCommit_SplitConventional(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	writeSplitTestFiles(t, tempDIR, map[string]string{
+		"alpha/alpha.go": "package alpha\n",
+		"beta/beta.go":   "package beta\n",
+	})
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headHash := rese.P1(repo.Head()).Hash()
+	commitFlags := &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "feat: add x", SplitBy: SplitByPackage, SplitMsg: "[{group}] {message}", Conventional: true}
+	require.ErrorContains(t, GitCommit(tempDIR, commitFlags), "is not a conventional commit")
+	require.Equal(t, headHash, rese.P1(repo.Head()).Hash())
+
+	commitFlags.SplitMsg = DefaultSplitMessage
+	require.NoError(t, GitCommit(tempDIR, commitFlags))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "feat(beta): add x", headCommit.Message)
+	require.Equal(t, "feat(alpha): add x", rese.P1(headCommit.Parent(0)).Message)
+}
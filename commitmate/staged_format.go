@@ -0,0 +1,198 @@
+// Package commitmate formats the staged content of Go files in the index
+// Keeps unstaged edits out of the commit while the working tree copy follows the formatting when safe
+//
+// commitmate 包格式化索引中 Go 文件的已暂存内容
+// 使未暂存的编辑不进入提交，同时在安全时让工作树副本跟随格式化
+package commitmate
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-xlan/gogit"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// What happened to the working tree copy of a formatted staged file
+// 已格式化的暂存文件在工作树中的副本的处理结果
+const (
+	WorktreeSynced  = "synced"  // Same as the staged content, replaced with the formatted content // 与暂存内容相同，已替换为格式化后的内容
+	WorktreeMerged  = "merged"  // Formatting applied onto the unstaged edits // 格式化已应用到未暂存的编辑上
+	WorktreeKept    = "kept"    // Formatting conflicts with the unstaged edits, left unchanged // 格式化与未暂存的编辑冲突，保持不变
+	WorktreeMissing = "missing" // Deleted in the working tree // 已在工作树中删除
+)
+
+// StagedFormat is one staged Go file rewritten with the formatted content
+// StagedFormat 是一个以格式化内容重写的已暂存 Go 文件
+type StagedFormat struct {
+	Path     string // Path relative to the repo root // 相对于仓库根目录的路径
+	Worktree string // Result of the working tree copy // 工作树副本的处理结果
+}
+
+// FormatStagedGoFiles formats the staged blobs of the changed Go files and writes them back to the index
+// The working tree copy takes the same formatting through a three-way merge, and is kept when it conflicts
+// Unlike FormatChangedGoFiles, unstaged edits stay out of the index
+// Staged go.mod and go.work files are canonicalized the same way
+//
+// FormatStagedGoFiles 格式化已更改 Go 文件的暂存数据对象并写回索引
+// 工作树副本通过三方合并应用相同的格式化，冲突时保持不变
+// 与 FormatChangedGoFiles 不同，未暂存的编辑不会进入索引
+// 已暂存的 go.mod 和 go.work 文件也以相同方式规范化
+func FormatStagedGoFiles(projectRoot string, client *gogit.Client, options *FormatOptions) ([]*StagedFormat, error) {
+	if err := checkFormatScope(options.Scope); err != nil {
+		return nil, erero.Wro(err)
+	}
+	repo := client.Repo()
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedIndex, err := repo.Storer.Index()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	// Read the staged and HEAD content of each candidate, jobs then just format text
+	// 读取每个候选文件的暂存内容和 HEAD 内容，任务只需格式化文本
+	var entries []*index.Entry
+	var names []string
+	var staged []string
+	var bases []*string
+	for _, entry := range stagedIndex.Entries {
+		// Skip conflict stages, unchanged files and files the filter rejects
+		// 跳过冲突阶段、未更改的文件以及过滤器拒绝的文件
+		headFile, exists := headFiles[entry.Name]
+		if entry.Stage != 0 || !isFormatTarget(entry.Name) || headFile == (treeFile{mode: entry.Mode, hash: entry.Hash}) {
+			continue
+		}
+		if !options.AllowFormat(filepath.Join(projectRoot, filepath.FromSlash(entry.Name))) {
+			zaplog.SUG.Debugln("skip:", entry.Name)
+			continue
+		}
+		content, err := readBlob(repo, entry.Hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		var base *string
+		if exists {
+			baseContent, err := readBlob(repo, headFile.hash)
+			if err != nil {
+				return nil, erero.Wro(err)
+			}
+			base = &baseContent
+		}
+		entries = append(entries, entry)
+		names = append(names, entry.Name)
+		staged = append(staged, content)
+		bases = append(bases, base)
+	}
+
+	formatted := make([]string, len(entries))
+	err = runFormatJobs(names, options.Jobs, func(idx int) (bool, error) {
+		content, err := formatFileSource(names[idx], staged[idx], bases[idx], options.Scope)
+		if err != nil {
+			return false, erero.Wro(err)
+		}
+		formatted[idx] = content
+		return content != staged[idx], nil
+	})
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	// Write the formatted blobs and working tree copies in order
+	// 按顺序写入格式化后的数据对象和工作树副本
+	var results []*StagedFormat
+	for idx, entry := range entries {
+		if formatted[idx] == staged[idx] {
+			continue
+		}
+		hash, err := writeBlob(repo, formatted[idx])
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		entry.Hash = hash
+		entry.Size = uint32(len(formatted[idx]))
+
+		result, err := formatWorktreeCopy(filepath.Join(projectRoot, filepath.FromSlash(entry.Name)), staged[idx], formatted[idx])
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		results = append(results, &StagedFormat{Path: entry.Name, Worktree: result})
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	if err := repo.Storer.SetIndex(stagedIndex); err != nil {
+		return nil, erero.Wro(err)
+	}
+	return results, nil
+}
+
+// formatWorktreeCopy applies the formatting from the staged content onto the working tree file
+// formatWorktreeCopy 将暂存内容的格式化应用到工作树文件上
+func formatWorktreeCopy(absPath string, staged string, formatted string) (string, error) {
+	info, err := os.Stat(absPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return WorktreeMissing, nil
+	}
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", erero.Wro(err)
+	}
+
+	current, result := string(content), WorktreeSynced
+	switch current {
+	case formatted:
+		return WorktreeSynced, nil
+	case staged:
+		current = formatted
+	default:
+		merged, ok := mergeText(staged, formatted, current)
+		if !ok {
+			zaplog.SUG.Warnln("formatting conflicts with unstaged edits, working tree kept:", absPath)
+			return WorktreeKept, nil
+		}
+		current, result = merged, WorktreeMerged
+	}
+	if err := os.WriteFile(absPath, []byte(current), info.Mode().Perm()); err != nil {
+		return "", erero.Wro(err)
+	}
+	return result, nil
+}
+
+// hasStagedChanges reports whether some file differs between HEAD and the index
+// hasStagedChanges 判断是否有文件在 HEAD 与索引之间存在差异
+func hasStagedChanges(status git.Status) bool {
+	for _, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified && fileStatus.Staging != git.Untracked {
+			return true
+		}
+	}
+	return false
+}
+
+// commitStaged commits the index as staged, unlike CommitAll it does not add modified tracked files
+// commitStaged 按暂存状态提交索引，与 CommitAll 不同，它不会添加已修改的跟踪文件
+func commitStaged(client *gogit.Client, commitInfo *gogit.CommitInfo) (string, error) {
+	message := commitInfo.BuildCommitMessage()
+	commitHash, err := client.Tree().Commit(message, &git.CommitOptions{
+		Author: commitInfo.GetObjectSignature(),
+	})
+	if err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			return "", nil
+		}
+		return "", erero.Wro(err)
+	}
+	zaplog.SUG.Infoln("commit-staged-success:", commitHash.String(), message)
+	return commitHash.String(), nil
+}
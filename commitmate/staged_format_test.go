@@ -0,0 +1,107 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-xlan/gogit"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+const (
+	stagedFormatBefore   = "package demo\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	stagedFormatStaged   = "package demo\n\nfunc A() int {\n\treturn   10\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	stagedFormatWorktree = "package demo\n\nfunc A() int {\n\treturn   10\n}\n\nfunc B() int {\n\treturn 20\n}\n"
+)
+
+// stageFormatTestFile writes the staged content, adds it and writes the working tree content
+// stageFormatTestFile 写入暂存内容并添加，然后写入工作树内容
+func stageFormatTestFile(t *testing.T, projectRoot string, name string, staged string, worktree string) {
+	path := filepath.Join(projectRoot, name)
+	require.NoError(t, os.WriteFile(path, []byte(staged), 0644))
+	rese.V1(rese.P1(rese.P1(git.PlainOpen(projectRoot)).Worktree()).Add(name))
+	require.NoError(t, os.WriteFile(path, []byte(worktree), 0644))
+}
+
+// TestGitCommit_StagedOnly validates committing formatted staged content with unstaged edits left out
+// Tests the working tree copy takes the formatting through the merge and keeps the unstaged edit
+//
+// TestGitCommit_StagedOnly 验证提交格式化后的暂存内容而不包含未暂存的编辑
+// 测试工作树副本通过合并应用格式化并保留未暂存的编辑
+func TestGitCommit_StagedOnly(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "demo.go", stagedFormatBefore, &CommitFlags{Message: "add demo"})
+	commitTestFile(t, tempDIR, "notes.txt", "committed\n", &CommitFlags{Message: "add notes"})
+	stageFormatTestFile(t, tempDIR, "demo.go", stagedFormatStaged, stagedFormatWorktree)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "notes.txt"), []byte("unstaged\n"), 0644))
+
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "change A", StagedOnly: true, FormatGo: true}))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "change A", headCommit.Message)
+	require.Equal(t, "package demo\n\nfunc A() int {\n\treturn 10\n}\n\nfunc B() int {\n\treturn 2\n}\n", readTestFile(t, headCommit, "demo.go"))
+
+	content := string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go"))))
+	require.Equal(t, "package demo\n\nfunc A() int {\n\treturn 10\n}\n\nfunc B() int {\n\treturn 20\n}\n", content)
+	status := rese.V1(rese.P1(repo.Worktree()).Status())
+	require.Equal(t, git.Modified, status.File("demo.go").Worktree)
+	require.Equal(t, git.Unmodified, status.File("demo.go").Staging)
+	require.Equal(t, "committed\n", readTestFile(t, headCommit, "notes.txt"))
+}
+
+func TestFormatStagedGoFiles_Conflict(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "demo.go", stagedFormatBefore, &CommitFlags{Message: "add demo"})
+	worktree := "package demo\n\nfunc A() int {\n\treturn   100\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	stageFormatTestFile(t, tempDIR, "demo.go", stagedFormatStaged, worktree)
+	stageFormatTestFile(t, tempDIR, "same.go", "package demo\n\nvar  X = 1\n", "package demo\n\nvar  X = 1\n")
+
+	results, err := FormatStagedGoFiles(tempDIR, rese.P1(gogit.New(tempDIR)), NewFormatOptions())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, &StagedFormat{Path: "demo.go", Worktree: WorktreeKept}, results[0])
+	require.Equal(t, &StagedFormat{Path: "same.go", Worktree: WorktreeSynced}, results[1])
+	require.Equal(t, worktree, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "demo.go")))))
+	require.Equal(t, "package demo\n\nvar X = 1\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "same.go")))))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	stagedFiles := rese.V1(indexFiles(repo))
+	require.Equal(t, "package demo\n\nvar X = 1\n", rese.V1(readBlob(repo, stagedFiles["same.go"].hash)))
+}
+
+// TestFormatStagedGoFiles_MergeChunks validates how the working tree copies take the formatting
+// Tests an edit next to the formatted line conflicts, an edit apart from it merges, and repeated lines get the formatting once
+//
+// TestFormatStagedGoFiles_MergeChunks 验证工作树副本如何应用格式化
+// 测试紧邻格式化行的编辑产生冲突，与其分开的编辑可以合并，重复的行只应用一次格式化
+func TestFormatStagedGoFiles_MergeChunks(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	staged := "package demo\n\nfunc A() int {\n\treturn   1\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	adjacent := "package demo\n\nfunc A() int64 {\n\treturn   1\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	apart := "package demo\n\nfunc A() int {\n\treturn   1\n}\n\nfunc B() int64 {\n\treturn 2\n}\n"
+	repeated := "package demo\n\nfunc Z() int {\n\treturn   1\n}\n\nfunc A() int {\n\treturn   1\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+	stageFormatTestFile(t, tempDIR, "adjacent.go", staged, adjacent)
+	stageFormatTestFile(t, tempDIR, "apart.go", staged, apart)
+	stageFormatTestFile(t, tempDIR, "repeated.go", staged, repeated)
+
+	results, err := FormatStagedGoFiles(tempDIR, rese.P1(gogit.New(tempDIR)), NewFormatOptions())
+	require.NoError(t, err)
+	require.Equal(t, []*StagedFormat{
+		{Path: "adjacent.go", Worktree: WorktreeKept},
+		{Path: "apart.go", Worktree: WorktreeMerged},
+		{Path: "repeated.go", Worktree: WorktreeMerged},
+	}, results)
+	require.Equal(t, adjacent, string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "adjacent.go")))))
+	require.Equal(t, "package demo\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int64 {\n\treturn 2\n}\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "apart.go")))))
+	require.Equal(t, "package demo\n\nfunc Z() int {\n\treturn   1\n}\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int {\n\treturn 2\n}\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "repeated.go")))))
+}
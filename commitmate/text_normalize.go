@@ -0,0 +1,568 @@
+// Package commitmate normalizes whitespace, line endings and BOMs of the changed text files
+// Follows the .editorconfig and .gitattributes files of the repo, and leaves binary content alone
+//
+// commitmate 包规范化已改变文本文件的空白、行尾和 BOM
+// 遵循仓库中的 .editorconfig 和 .gitattributes 文件，并且不改动二进制内容
+package commitmate
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-mate/go-commit/internal/utils"
+	"github.com/go-xlan/gogit"
+	"github.com/go-xlan/gogit/gogitchange"
+	"github.com/yyle88/erero"
+	"github.com/yyle88/zaplog"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files
+// utf8BOM 是某些编辑器放在 UTF-8 文件开头的字节顺序标记
+const utf8BOM = "\xEF\xBB\xBF"
+
+// binarySniffSize is the count of leading bytes searched for NUL, the same as git
+// binarySniffSize 是搜索 NUL 的开头字节数，与 git 相同
+const binarySniffSize = 8000
+
+// textRule is how one changed text file gets normalized
+// textRule 表示一个已改变文本文件的规范化方式
+type textRule struct {
+	skip         bool   // Leave the file alone: binary, -text, -diff or not UTF-8 // 不改动该文件：binary、-text、-diff 或非 UTF-8
+	trimSpace    bool   // Trim trailing spaces and tabs of each line // 去除每行末尾的空格和制表符
+	finalNewline bool   // End a non-blank file with a line ending // 非空文件以行尾结束
+	eol          string // Line ending of the working tree, lf or crlf, blank keeps each line's own // 工作树的行尾，lf 或 crlf，空表示保留每行原有的行尾
+	storeLF      bool   // Store LF in the blob, as git does for the text and eol attributes // 在数据对象中存储 LF，与 git 对 text 和 eol 属性的处理相同
+	keepBOM      bool   // Keep the UTF-8 BOM // 保留 UTF-8 BOM
+}
+
+// blobRule returns the rule of the content stored in the blob, the eol becomes LF when storeLF is set
+// blobRule 返回存储在数据对象中的内容的规则，设置 storeLF 时行尾变为 LF
+func (r *textRule) blobRule() *textRule {
+	if !r.storeLF {
+		return r
+	}
+	rule := *r
+	rule.eol = "lf"
+	return &rule
+}
+
+// editorSection is one [glob] section of an .editorconfig file
+// editorSection 是 .editorconfig 文件中的一个 [glob] 小节
+type editorSection struct {
+	glob   *utils.PathGlob   // Files of the section, relative to the DIR of the file // 小节对应的文件，相对于该文件所在的 DIR
+	values map[string]string // Lowercase properties // 小写的属性
+}
+
+// editorConfig is one parsed .editorconfig file
+// editorConfig 是一个已解析的 .editorconfig 文件
+type editorConfig struct {
+	root     bool             // Stops the search of the parent DIRs // 停止搜索上级 DIR
+	sections []*editorSection // Sections in file order // 按文件顺序排列的小节
+}
+
+// attributeLine is one pattern line of a .gitattributes file
+// Values hold "set", "unset", "!" for unspecified, or the assigned value
+//
+// attributeLine 是 .gitattributes 文件中的一个模式行
+// 值为 "set"、"unset"、表示未指定的 "!"，或赋予的值
+type attributeLine struct {
+	glob   *utils.PathGlob   // Files of the line, relative to the DIR of the file // 该行对应的文件，相对于该文件所在的 DIR
+	values map[string]string // Attribute states // 属性状态
+}
+
+// textRules resolves the rules of the repo files and caches the config files of each DIR
+// textRules 解析仓库文件的规则并缓存每个 DIR 的配置文件
+type textRules struct {
+	projectRoot   string                      // Repo root DIR // 仓库根目录
+	editorConfigs map[string]*editorConfig    // .editorconfig by slash DIR, nil when missing // 按斜杠 DIR 存放的 .editorconfig，缺失时为 nil
+	attributes    map[string][]*attributeLine // .gitattributes lines by slash DIR // 按斜杠 DIR 存放的 .gitattributes 行
+}
+
+// newTextRules creates the resolver of the repo
+// newTextRules 创建仓库的规则解析器
+func newTextRules(projectRoot string) *textRules {
+	return &textRules{
+		projectRoot:   projectRoot,
+		editorConfigs: map[string]*editorConfig{},
+		attributes:    map[string][]*attributeLine{},
+	}
+}
+
+// ruleOf resolves the rule of the slash path relative to the repo root
+// Defaults trim, add the final newline, use LF and strip the BOM, .gitattributes wins over .editorconfig
+// Markdown keeps trailing whitespace by default, two trailing spaces there are a hard line break
+//
+// ruleOf 解析相对于仓库根目录的斜杠路径的规则
+// 默认去除行尾空白、补充末尾换行、使用 LF 并去除 BOM，.gitattributes 优先于 .editorconfig
+// Markdown 默认保留行尾空白，其中两个行尾空格表示硬换行
+func (r *textRules) ruleOf(name string) (*textRule, error) {
+	rule := &textRule{trimSpace: !isMarkdown(name), finalNewline: true, eol: "lf"}
+
+	// DIRs from the nearest to the repo root
+	// 从最近的 DIR 到仓库根目录
+	var dirs []string
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		dirs = append(dirs, strings.TrimPrefix(dir, "."))
+		if dir == "." {
+			break
+		}
+	}
+
+	// Nearer .editorconfig files win, the search stops at root = true
+	// 越近的 .editorconfig 文件优先，搜索在 root = true 处停止
+	var configs []*editorConfig
+	var configDIRs []string
+	for _, dir := range dirs {
+		config, err := r.loadEditorConfig(dir)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if config == nil {
+			continue
+		}
+		configs, configDIRs = append(configs, config), append(configDIRs, dir)
+		if config.root {
+			break
+		}
+	}
+	values := map[string]string{}
+	for idx := len(configs) - 1; idx >= 0; idx-- {
+		relPath := relativeTo(configDIRs[idx], name)
+		for _, section := range configs[idx].sections {
+			if section.glob.Match(relPath) {
+				for key, value := range section.values {
+					values[key] = value
+				}
+			}
+		}
+	}
+	switch values["trim_trailing_whitespace"] {
+	case "true":
+		rule.trimSpace = true
+	case "false":
+		rule.trimSpace = false
+	}
+	switch values["insert_final_newline"] {
+	case "true":
+		rule.finalNewline = true
+	case "false":
+		rule.finalNewline = false
+	}
+	switch values["end_of_line"] {
+	case "lf", "crlf":
+		rule.eol = values["end_of_line"]
+	case "cr":
+		rule.eol = ""
+	}
+	switch values["charset"] {
+	case "utf-8-bom":
+		rule.keepBOM = true
+	case "latin1", "utf-16be", "utf-16le":
+		rule.skip = true
+	}
+
+	// Deeper .gitattributes files and later lines win
+	// 越深的 .gitattributes 文件以及越靠后的行优先
+	attributes := map[string]string{}
+	for idx := len(dirs) - 1; idx >= 0; idx-- {
+		lines, err := r.loadAttributes(dirs[idx])
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		relPath := relativeTo(dirs[idx], name)
+		for _, line := range lines {
+			if !line.glob.Match(relPath) {
+				continue
+			}
+			for key, value := range line.values {
+				if value == "!" {
+					delete(attributes, key)
+				} else {
+					attributes[key] = value
+				}
+			}
+		}
+	}
+	if attributes["text"] == "unset" || attributes["diff"] == "unset" {
+		rule.skip = true
+	}
+	// Git stores LF for text files and uses the eol in the working tree only
+	// Git 为文本文件存储 LF，eol 仅用于工作树
+	switch attributes["eol"] {
+	case "lf", "crlf":
+		rule.eol, rule.storeLF = attributes["eol"], true
+	}
+	if attributes["text"] == "set" || attributes["text"] == "auto" {
+		rule.storeLF = true
+	}
+	return rule, nil
+}
+
+// isMarkdown reports whether the path names a Markdown file
+// isMarkdown 判断路径是否为 Markdown 文件
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// relativeTo returns the slash path relative to the DIR, a blank DIR is the repo root
+// relativeTo 返回相对于 DIR 的斜杠路径，空 DIR 表示仓库根目录
+func relativeTo(dir string, name string) string {
+	if dir == "" {
+		return name
+	}
+	return strings.TrimPrefix(name, dir+"/")
+}
+
+// loadEditorConfig reads and parses the .editorconfig of the DIR once, nil when missing
+// Sections with invalid globs are skipped with a warning
+//
+// loadEditorConfig 读取并解析 DIR 中的 .editorconfig 一次，缺失时返回 nil
+// 带有无效 glob 的小节会被跳过并发出警告
+func (r *textRules) loadEditorConfig(dir string) (*editorConfig, error) {
+	if config, exists := r.editorConfigs[dir]; exists {
+		return config, nil
+	}
+	content, err := os.ReadFile(filepath.Join(r.projectRoot, filepath.FromSlash(dir), ".editorconfig"))
+	if errors.Is(err, fs.ErrNotExist) {
+		r.editorConfigs[dir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	config := &editorConfig{}
+	var section *editorSection
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			glob, err := utils.CompilePathGlob(line[1 : len(line)-1])
+			if err != nil {
+				zaplog.SUG.Warnln("skip .editorconfig section:", line, err)
+				section = &editorSection{values: map[string]string{}}
+				continue
+			}
+			section = &editorSection{glob: glob, values: map[string]string{}}
+			config.sections = append(config.sections, section)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value))
+		if section == nil {
+			config.root = config.root || (key == "root" && value == "true")
+			continue
+		}
+		section.values[key] = value
+	}
+	r.editorConfigs[dir] = config
+	return config, nil
+}
+
+// loadAttributes reads and parses the .gitattributes of the DIR once
+// The binary macro expands to -text -diff, macro definitions and DIR patterns are skipped
+//
+// loadAttributes 读取并解析 DIR 中的 .gitattributes 一次
+// binary 宏展开为 -text -diff，跳过宏定义和 DIR 模式
+func (r *textRules) loadAttributes(dir string) ([]*attributeLine, error) {
+	if lines, exists := r.attributes[dir]; exists {
+		return lines, nil
+	}
+	content, err := os.ReadFile(filepath.Join(r.projectRoot, filepath.FromSlash(dir), ".gitattributes"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, erero.Wro(err)
+	}
+
+	var lines []*attributeLine
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[attr]") || strings.HasSuffix(fields[0], "/") {
+			continue
+		}
+		glob, err := utils.CompilePathGlob(fields[0])
+		if err != nil {
+			zaplog.SUG.Warnln("skip .gitattributes line:", line, err)
+			continue
+		}
+		values := map[string]string{}
+		for _, field := range fields[1:] {
+			switch {
+			case field == "binary":
+				values["text"], values["diff"] = "unset", "unset"
+			case strings.HasPrefix(field, "-"):
+				values[field[1:]] = "unset"
+			case strings.HasPrefix(field, "!"):
+				values[field[1:]] = "!"
+			case strings.Contains(field, "="):
+				key, value, _ := strings.Cut(field, "=")
+				values[key] = value
+			default:
+				values[field] = "set"
+			}
+		}
+		lines = append(lines, &attributeLine{glob: glob, values: values})
+	}
+	r.attributes[dir] = lines
+	return lines, nil
+}
+
+// looksBinary reports whether the content is binary, by a NUL in the leading bytes as git does, or not UTF-8
+// looksBinary 判断内容是否为二进制：与 git 一样检查开头字节中的 NUL，或内容不是 UTF-8
+func looksBinary(content string) bool {
+	return strings.IndexByte(content[:min(len(content), binarySniffSize)], 0) >= 0 || !utf8.ValidString(content)
+}
+
+// normalizeText applies the rule to the text content
+// normalizeText 将规则应用到文本内容
+func normalizeText(content string, rule *textRule) string {
+	bom := ""
+	if strings.HasPrefix(content, utf8BOM) {
+		content = strings.TrimPrefix(content, utf8BOM)
+		if rule.keepBOM {
+			bom = utf8BOM
+		}
+	}
+	if content == "" {
+		return bom
+	}
+
+	var result strings.Builder
+	result.WriteString(bom)
+	lines := strings.SplitAfter(content, "\n")
+	for idx, line := range lines {
+		if line == "" {
+			continue
+		}
+		body, ending := line, ""
+		switch {
+		case strings.HasSuffix(line, "\r\n"):
+			body, ending = strings.TrimSuffix(line, "\r\n"), "\r\n"
+		case strings.HasSuffix(line, "\n"):
+			body, ending = strings.TrimSuffix(line, "\n"), "\n"
+		}
+		if rule.trimSpace {
+			body = strings.TrimRight(body, " \t")
+		}
+		if ending == "" && rule.finalNewline && idx == len(lines)-1 {
+			ending = "\n"
+		}
+		if ending != "" {
+			switch rule.eol {
+			case "lf":
+				ending = "\n"
+			case "crlf":
+				ending = "\r\n"
+			}
+		}
+		result.WriteString(body)
+		result.WriteString(ending)
+	}
+	return result.String()
+}
+
+// NormalizeChangedTextFiles normalizes the changed text files in the working tree, Go files are left to formatting
+// Returns the normalized paths relative to the repo root
+//
+// NormalizeChangedTextFiles 规范化工作树中已改变的文本文件，Go 文件留给格式化处理
+// 返回已规范化的文件路径，相对于仓库根目录
+func NormalizeChangedTextFiles(projectRoot string, client *gogit.Client) ([]string, error) {
+	matchOptions := gogitchange.NewMatchOptions().MatchPath(func(path string) bool {
+		return filepath.Ext(path) != ".go"
+	})
+	changedPaths, err := gogitchange.NewChangedFileManager(projectRoot, client.Tree()).ListChangedFilePaths(matchOptions)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	slices.Sort(changedPaths)
+
+	rules := newTextRules(projectRoot)
+	var results []string
+	for _, absPath := range changedPaths {
+		relPath, err := filepath.Rel(projectRoot, absPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		name := filepath.ToSlash(relPath)
+		rule, err := rules.ruleOf(name)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		// Symlinks are skipped so writing never goes through them
+		// 跳过符号链接，写入永远不会经过它们
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if rule.skip || !info.Mode().IsRegular() {
+			zaplog.SUG.Debugln("skip:", name)
+			continue
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if looksBinary(string(content)) {
+			zaplog.SUG.Debugln("skip binary:", name)
+			continue
+		}
+		normalized := normalizeText(string(content), rule)
+		if normalized == string(content) {
+			continue
+		}
+		if err := os.WriteFile(absPath, []byte(normalized), info.Mode().Perm()); err != nil {
+			return nil, erero.Wro(err)
+		}
+		zaplog.SUG.Infoln("normalized:", name)
+		results = append(results, name)
+	}
+	return results, nil
+}
+
+// NormalizeStagedTextFiles normalizes the staged blobs of the changed text files and writes them back to the index
+// Blobs get LF when storeLF is set, the working tree copy takes the edits with its own eol as in FormatStagedGoFiles
+//
+// NormalizeStagedTextFiles 规范化已改变文本文件的暂存数据对象并写回索引
+// 设置 storeLF 时数据对象使用 LF，工作树副本按与 FormatStagedGoFiles 相同的方式以其自身的行尾应用这些编辑
+func NormalizeStagedTextFiles(projectRoot string, client *gogit.Client) ([]*StagedFormat, error) {
+	repo := client.Repo()
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	stagedIndex, err := repo.Storer.Index()
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+
+	rules := newTextRules(projectRoot)
+	var results []*StagedFormat
+	for _, entry := range stagedIndex.Entries {
+		if !isNormalizeCandidate(entry, headFiles) {
+			continue
+		}
+		rule, err := rules.ruleOf(entry.Name)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if rule.skip {
+			continue
+		}
+		content, err := readBlob(repo, entry.Hash)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		if looksBinary(content) {
+			continue
+		}
+		normalized := normalizeText(content, rule.blobRule())
+		if normalized == content {
+			continue
+		}
+		hash, err := writeBlob(repo, normalized)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		entry.Hash = hash
+		entry.Size = uint32(len(normalized))
+
+		result, err := formatWorktreeCopy(filepath.Join(projectRoot, filepath.FromSlash(entry.Name)), content, normalizeText(content, rule))
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		results = append(results, &StagedFormat{Path: entry.Name, Worktree: result})
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	if err := repo.Storer.SetIndex(stagedIndex); err != nil {
+		return nil, erero.Wro(err)
+	}
+	return results, nil
+}
+
+// cleanStagedLineEndings stores LF in the changed staged blobs of the files git keeps as LF, as the git clean filter does
+// go-git adds the working tree bytes as they are, so CRLF copies of LF blobs would be staged back on each run
+//
+// cleanStagedLineEndings 与 git 的 clean 过滤器一样，为 git 以 LF 保存的文件在已改变的暂存数据对象中存储 LF
+// go-git 按原样添加工作树字节，否则每次运行都会把 LF 数据对象的 CRLF 副本重新暂存
+func cleanStagedLineEndings(projectRoot string, repo *git.Repository) error {
+	headFiles, err := headCommitFiles(repo)
+	if err != nil {
+		return erero.Wro(err)
+	}
+	stagedIndex, err := repo.Storer.Index()
+	if err != nil {
+		return erero.Wro(err)
+	}
+
+	rules := newTextRules(projectRoot)
+	var changed bool
+	for _, entry := range stagedIndex.Entries {
+		if entry.Stage != 0 || (entry.Mode != filemode.Regular && entry.Mode != filemode.Executable) {
+			continue
+		}
+		if headFiles[entry.Name] == (treeFile{mode: entry.Mode, hash: entry.Hash}) {
+			continue
+		}
+		rule, err := rules.ruleOf(entry.Name)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		if rule.skip || !rule.storeLF {
+			continue
+		}
+		content, err := readBlob(repo, entry.Hash)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		if looksBinary(content) || !strings.Contains(content, "\r\n") {
+			continue
+		}
+		cleaned := strings.ReplaceAll(content, "\r\n", "\n")
+		hash, err := writeBlob(repo, cleaned)
+		if err != nil {
+			return erero.Wro(err)
+		}
+		entry.Hash = hash
+		entry.Size = uint32(len(cleaned))
+		changed = true
+		zaplog.SUG.Debugln("stored LF:", entry.Name)
+	}
+	if !changed {
+		return nil
+	}
+	if err := repo.Storer.SetIndex(stagedIndex); err != nil {
+		return erero.Wro(err)
+	}
+	return nil
+}
+
+// isNormalizeCandidate reports whether the index entry is a changed regular non-Go file
+// isNormalizeCandidate 判断索引条目是否为已改变的普通非 Go 文件
+func isNormalizeCandidate(entry *index.Entry, headFiles map[string]treeFile) bool {
+	if entry.Stage != 0 || path.Ext(entry.Name) == ".go" {
+		return false
+	}
+	if entry.Mode != filemode.Regular && entry.Mode != filemode.Executable {
+		return false
+	}
+	return headFiles[entry.Name] != (treeFile{mode: entry.Mode, hash: entry.Hash})
+}
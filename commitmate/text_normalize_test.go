@@ -0,0 +1,167 @@
+package commitmate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/rese"
+)
+
+func TestNormalizeText(t *testing.T) {
+	rule := &textRule{trimSpace: true, finalNewline: true, eol: "lf"}
+	require.Equal(t, "a\nb\n\nc\n", normalizeText(utf8BOM+"a  \r\nb\t\r\n\r\nc", rule))
+	require.Equal(t, "", normalizeText("", rule))
+
+	rule = &textRule{finalNewline: false, eol: "crlf", keepBOM: true}
+	require.Equal(t, utf8BOM+"a  \r\nb", normalizeText(utf8BOM+"a  \nb", rule))
+
+	rule = &textRule{trimSpace: true, finalNewline: true}
+	require.Equal(t, "a\r\nb\n", normalizeText("a \r\nb ", rule))
+
+	require.True(t, looksBinary("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	require.True(t, looksBinary("caf\xe9\n"))
+	require.False(t, looksBinary("café\n"))
+}
+
+// TestTextRules validates resolving the rule from nested .editorconfig and .gitattributes files
+// TestTextRules 验证从嵌套的 .editorconfig 和 .gitattributes 文件解析规则
+func TestTextRules(t *testing.T) {
+	tempDIR := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDIR, "docs", "win"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, ".editorconfig"), []byte("root = true\n\n[*]\ninsert_final_newline = true\n\n[*.md]\ntrim_trailing_whitespace = false\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "docs", ".editorconfig"), []byte("[win/*.bat]\nend_of_line = crlf\n[legacy.txt]\ncharset = latin1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, ".gitattributes"), []byte("*.svg binary\n*.csv -diff\ndocs/*.sh eol=lf\n*.lock -text\nkeep.lock !text\n"), 0644))
+
+	rules := newTextRules(tempDIR)
+	cases := map[string]*textRule{
+		"README.md":           {finalNewline: true, eol: "lf"},
+		"main.txt":            {trimSpace: true, finalNewline: true, eol: "lf"},
+		"docs/win/run.bat":    {trimSpace: true, finalNewline: true, eol: "crlf"},
+		"docs/legacy.txt":     {skip: true, trimSpace: true, finalNewline: true, eol: "lf"},
+		"assets/logo.svg":     {skip: true, trimSpace: true, finalNewline: true, eol: "lf"},
+		"data/report.csv":     {skip: true, trimSpace: true, finalNewline: true, eol: "lf"},
+		"package.lock":        {skip: true, trimSpace: true, finalNewline: true, eol: "lf"},
+		"keep.lock":           {trimSpace: true, finalNewline: true, eol: "lf"},
+		"docs/guide.MARKDOWN": {finalNewline: true, eol: "lf"},
+		"docs/run.sh":         {trimSpace: true, finalNewline: true, eol: "lf", storeLF: true},
+	}
+	for name, expected := range cases {
+		rule, err := rules.ruleOf(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, rule, name)
+	}
+}
+
+// TestGitCommit_NormalizeText validates normalizing changed text files while binary and Go files stay as they are
+// TestGitCommit_NormalizeText 验证规范化已改变的文本文件，同时二进制文件和 Go 文件保持不变
+func TestGitCommit_NormalizeText(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR  \r\n"
+	goSource := "package demo\n\nvar Text = `line  \r\n`\n"
+	files := map[string]string{
+		"notes.txt": utf8BOM + "first  \r\nsecond",
+		"logo.png":  image,
+		"demo.go":   goSource,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDIR, name), []byte(content), 0644))
+	}
+	commitTestFile(t, tempDIR, "script.sh", "#!/bin/sh  \n", &CommitFlags{Message: "add files", NormalizeText: true})
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "first\nsecond\n", readTestFile(t, headCommit, "notes.txt"))
+	require.Equal(t, "#!/bin/sh\n", readTestFile(t, headCommit, "script.sh"))
+	require.Equal(t, image, readTestFile(t, headCommit, "logo.png"))
+	require.Equal(t, goSource, readTestFile(t, headCommit, "demo.go"))
+	require.Equal(t, "first\nsecond\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "notes.txt")))))
+}
+
+// TestNormalizeStagedTextFiles validates normalizing the staged blob while the unstaged edit stays out
+// TestNormalizeStagedTextFiles 验证规范化已暂存的数据对象，同时未暂存的编辑不进入提交
+func TestNormalizeStagedTextFiles(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "notes.txt", "one\ntwo\nthree\n", &CommitFlags{Message: "add notes"})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "notes.txt"), []byte("zero  \none\ntwo\nthree\n"), 0644))
+	worktree := rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree())
+	rese.V1(worktree.Add("notes.txt"))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "notes.txt"), []byte("zero  \none\ntwo\nthree\nfour\n"), 0644))
+
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "trim", StagedOnly: true, NormalizeText: true}))
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "trim", headCommit.Message)
+	require.Equal(t, "zero\none\ntwo\nthree\n", readTestFile(t, headCommit, "notes.txt"))
+	require.Equal(t, "zero\none\ntwo\nthree\nfour\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "notes.txt")))))
+}
+
+// TestGitCommit_NormalizeMarkdown validates the trailing spaces of Markdown hard line breaks are kept by default
+// TestGitCommit_NormalizeMarkdown 验证默认保留 Markdown 硬换行的行尾空格
+func TestGitCommit_NormalizeMarkdown(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, "NOTES.md", "first line  \r\nsecond line", &CommitFlags{Message: "add notes", NormalizeText: true})
+
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "first line  \nsecond line\n", readTestFile(t, headCommit, "NOTES.md"))
+}
+
+// TestGitCommit_NormalizeCRLF validates "text eol=crlf" stores LF in the blob while the working tree keeps CRLF
+// TestGitCommit_NormalizeCRLF 验证 "text eol=crlf" 在数据对象中存储 LF，而工作树保留 CRLF
+func TestGitCommit_NormalizeCRLF(t *testing.T) {
+	for _, stagedOnly := range []bool{false, true} {
+		tempDIR, cleanup := setupTestRepo()
+		t.Cleanup(cleanup)
+
+		commitTestFile(t, tempDIR, ".gitattributes", "*.bat text eol=crlf\n", &CommitFlags{Message: "add attributes"})
+		require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "run.bat"), []byte("@echo off  \r\necho hi\n"), 0644))
+		if stagedOnly {
+			rese.V1(rese.P1(rese.P1(git.PlainOpen(tempDIR)).Worktree()).Add("run.bat"))
+		}
+		require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add script", StagedOnly: stagedOnly, NormalizeText: true}))
+
+		repo := rese.P1(git.PlainOpen(tempDIR))
+		headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+		require.Equal(t, "add script", headCommit.Message)
+		require.Equal(t, "@echo off\necho hi\n", readTestFile(t, headCommit, "run.bat"))
+		require.Equal(t, "@echo off\r\necho hi\r\n", string(rese.V1(os.ReadFile(filepath.Join(tempDIR, "run.bat")))))
+	}
+}
+
+// TestGitCommit_NormalizeCRLFTwice validates a later run without --normalize-text keeps the LF blob of "text eol=crlf"
+// Tests that the CRLF working tree copy is not staged back, and that edits to it are committed as LF
+//
+// TestGitCommit_NormalizeCRLFTwice 验证之后不带 --normalize-text 的运行保持 "text eol=crlf" 的 LF 数据对象
+// 测试 CRLF 工作树副本不会被重新暂存，且对它的编辑以 LF 提交
+func TestGitCommit_NormalizeCRLFTwice(t *testing.T) {
+	tempDIR, cleanup := setupTestRepo()
+	t.Cleanup(cleanup)
+
+	commitTestFile(t, tempDIR, ".gitattributes", "*.bat text eol=crlf\n", &CommitFlags{Message: "add attributes"})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "run.bat"), []byte("@echo off\r\necho hi\r\n"), 0644))
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "add script", NormalizeText: true}))
+	repo := rese.P1(git.PlainOpen(tempDIR))
+	head := rese.P1(repo.Head()).Hash()
+
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "again"}))
+	require.Equal(t, head, rese.P1(repo.Head()).Hash())
+	stagedIndex := rese.P1(repo.Storer.Index())
+	entry := rese.P1(stagedIndex.Entry("run.bat"))
+	require.Equal(t, rese.P1(rese.P1(repo.CommitObject(head)).File("run.bat")).Hash, entry.Hash)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDIR, "run.bat"), []byte("@echo off\r\necho bye\r\n"), 0644))
+	require.NoError(t, GitCommit(tempDIR, &CommitFlags{Username: "Test Username", Mailbox: "test@example.com", Message: "edit script"}))
+	headCommit := rese.P1(repo.CommitObject(rese.P1(repo.Head()).Hash()))
+	require.Equal(t, "edit script", headCommit.Message)
+	require.Equal(t, "@echo off\necho bye\n", readTestFile(t, headCommit, "run.bat"))
+}
@@ -6,6 +6,7 @@ require (
 	github.com/go-git/go-git/v5 v5.16.4
 	github.com/go-xlan/gitgo v0.0.23
 	github.com/go-xlan/gogit v0.0.20
+	github.com/sergi/go-diff v1.4.0
 	github.com/spf13/cobra v1.10.2
 	github.com/stretchr/testify v1.11.1
 	github.com/yyle88/erero v1.0.24
@@ -20,6 +21,8 @@ require (
 	github.com/yyle88/tern v0.0.10
 	github.com/yyle88/zaplog v0.0.28
 	go.uber.org/zap v1.27.1
+	golang.org/x/mod v0.31.0
+	golang.org/x/sync v0.19.0
 )
 
 require (
@@ -40,7 +43,6 @@ require (
 	github.com/pjbgf/sha1cd v0.5.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
-	github.com/sergi/go-diff v1.4.0 // indirect
 	github.com/skeema/knownhosts v1.3.2 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
@@ -52,9 +54,7 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/crypto v0.46.0 // indirect
 	golang.org/x/exp v0.0.0-20251209150349-8475f28825e9 // indirect
-	golang.org/x/mod v0.31.0 // indirect
 	golang.org/x/net v0.48.0 // indirect
-	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/tools v0.40.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
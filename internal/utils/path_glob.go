@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yyle88/erero"
+)
+
+// PathGlob is a glob on slash paths as .editorconfig sections and .gitattributes lines write them
+// "*" and "?" stop at "/", "**" crosses it, "[...]" and "{a,b}" work as in CompilePattern
+//
+// PathGlob 是斜杠路径上的 glob，与 .editorconfig 小节和 .gitattributes 行的写法相同
+// "*" 和 "?" 不跨越 "/"，"**" 可跨越，"[...]" 和 "{a,b}" 与 CompilePattern 中相同
+type PathGlob struct {
+	regexes  []*regexp.Regexp // Anchored expression of each brace expansion // 每个花括号展开的锚定表达式
+	baseName bool             // Pattern without "/" matches the base name at any depth // 不含 "/" 的模式匹配任意深度的文件名
+}
+
+// CompilePathGlob compiles the glob, a leading "/" anchors it to the DIR holding the config
+// CompilePathGlob 编译 glob，开头的 "/" 将其锚定到配置所在的 DIR
+func CompilePathGlob(pattern string) (*PathGlob, error) {
+	glob := &PathGlob{baseName: !strings.Contains(pattern, "/")}
+	expansions, err := expandBraces(strings.TrimPrefix(pattern, "/"))
+	if err != nil {
+		return nil, erero.Wro(err)
+	}
+	for _, expansion := range expansions {
+		// "**/" also matches no DIR at all
+		// "**/" 也可以不匹配任何 DIR
+		variants := []string{expansion}
+		if rest, ok := strings.CutPrefix(expansion, "**/"); ok {
+			variants = append(variants, rest)
+		}
+		for _, variant := range variants {
+			expr, _, err := translateGlob(variant, true)
+			if err != nil {
+				return nil, erero.Wro(err)
+			}
+			regex, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, erero.Wro(err)
+			}
+			glob.regexes = append(glob.regexes, regex)
+		}
+	}
+	return glob, nil
+}
+
+// Match reports whether the slash path relative to the DIR holding the config matches
+// Match 判断相对于配置所在 DIR 的斜杠路径是否匹配
+func (g *PathGlob) Match(name string) bool {
+	if g.baseName {
+		name = path.Base(name)
+	}
+	for _, regex := range g.regexes {
+		if regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
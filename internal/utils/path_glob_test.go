@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePathGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide/intro.md", true},
+		{"*.md", "docs/intro.txt", false},
+		{"*.{yml,yaml}", "ci/build.yaml", true},
+		{"docs/*.txt", "docs/a.txt", true},
+		{"docs/*.txt", "docs/sub/a.txt", false},
+		{"/docs/**", "docs/sub/a.txt", true},
+		{"**/testdata/*", "testdata/a.golden", true},
+		{"**/testdata/*", "pkg/testdata/a.golden", true},
+		{"Makefile", "build/Makefile", true},
+		{"file?.[ch]", "src/file1.c", true},
+		{"file?.[ch]", "src/file12.c", false},
+	}
+	for _, tc := range cases {
+		glob, err := CompilePathGlob(tc.pattern)
+		require.NoError(t, err)
+		require.Equal(t, tc.matched, glob.Match(tc.name), "%s ~ %s", tc.pattern, tc.name)
+	}
+
+	_, err := CompilePathGlob("*.{md")
+	require.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// adversarialPattern has many "*" wildcards and a final literal the URL lacks,
+// which made the recursive matcher try each split of the URL
+//
+// adversarialPattern 含有大量 "*" 通配符以及 URL 中不存在的末尾字面内容，
+// 这会使递归匹配器尝试 URL 的每一种拆分方式
+var adversarialPattern = strings.Repeat("*a", 24) + "*b"
+
+// adversarialURL is a long run of "a" characters without the final "b"
+// adversarialURL 是一长串不含末尾 "b" 的 "a" 字符
+var adversarialURL = "git@github.com:" + strings.Repeat("a", 4096)
+
+// TestMatchRemotePattern_Adversarial tests patterns with many wildcards, the benchmarks below track their time
+// Validates the result against the same URL ending with the missing literal
+//
+// TestMatchRemotePattern_Adversarial 测试含大量通配符的模式，其耗时由下方的基准测试跟踪
+// 与以缺失字面内容结尾的同一 URL 对比验证结果
+func TestMatchRemotePattern_Adversarial(t *testing.T) {
+	require.Equal(t, -1, MatchRemotePattern(adversarialPattern, adversarialURL))
+	require.Equal(t, 25, MatchRemotePattern(adversarialPattern, adversarialURL+"b"))
+}
+
+func TestCompilePattern_SegmentEdges(t *testing.T) {
+	for _, item := range []struct {
+		pattern   string
+		remoteURL string
+		matched   bool
+	}{
+		{"a*a", "a", false},
+		{"a*a", "aa", true},
+		{"ab*ba", "aba", false},
+		{"*ab*ab*", "abab", true},
+		{"*ab*ab*", "aab", false},
+		{"***", "", true},
+		{"x**y", "xy", true},
+		{"", "", true},
+		{"", "x", false},
+	} {
+		compiled, err := CompilePattern(item.pattern)
+		require.NoError(t, err)
+		require.Equal(t, item.matched, compiled.Match(item.remoteURL) >= 0, item.pattern+" ~ "+item.remoteURL)
+	}
+}
+
+func BenchmarkMatchRemotePattern_Adversarial(b *testing.B) {
+	for b.Loop() {
+		MatchRemotePattern(adversarialPattern, adversarialURL)
+	}
+}
+
+func BenchmarkCompiledPattern_Adversarial(b *testing.B) {
+	compiled, err := CompilePattern(adversarialPattern)
+	require.NoError(b, err)
+	for b.Loop() {
+		compiled.Match(adversarialURL)
+	}
+}
+
+func BenchmarkCompiledPattern_Braces(b *testing.B) {
+	compiled, err := CompilePattern("git@{github,gitlab,bitbucket}.{corp,home}.com:**/*.git")
+	require.NoError(b, err)
+	for b.Loop() {
+		compiled.Match("git@bitbucket.home.com:team/group/project.git")
+	}
+}
+
+func BenchmarkCompiledPattern_Regex(b *testing.B) {
+	compiled, err := CompilePattern(`re:git@github\.com:(a+)+b`)
+	require.NoError(b, err)
+	for b.Loop() {
+		compiled.Match(adversarialURL)
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/yyle88/erero"
+)
+
+// ValidatePattern checks whether the pattern is usable as a remote pattern
+// Rejects blank patterns, patterns containing whitespace or control characters, and extended patterns that do not compile
+// Returns nil when the pattern is accepted
+//
+// ValidatePattern 检查模式是否可以作为远程模式使用
+// 拒绝空白模式、包含空白或控制字符的模式，以及无法编译的扩展模式
+// 当模式可用时返回 nil
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return erero.New("pattern is blank")
+	}
+	for idx, char := range pattern {
+		if unicode.IsSpace(char) || unicode.IsControl(char) {
+			return erero.Errorf("pattern contains invalid character %q at offset %d", char, idx)
+		}
+	}
+	if !IsSimplePattern(pattern) {
+		if _, err := CompilePattern(pattern); err != nil {
+			return erero.Errorf("pattern does not compile: %v", err)
+		}
+	}
+	return nil
+}
+
+// CoversPattern reports whether the general pattern matches every URL that the specific pattern matches
+// Treats each "*" in the specific pattern as a symbol that only a "*" in the general pattern can consume
+// Returns true when the language of specific is a subset of the language of general
+// Extended patterns are not analyzed, so the result is false when either pattern is not simple
+//
+// CoversPattern 判断通用模式是否匹配具体模式能匹配的所有 URL
+// 将具体模式中的每个 "*" 视为只能被通用模式中的 "*" 消费的符号
+// 当具体模式的语言是通用模式语言的子集时返回 true
+// 扩展模式不做分析，因此任一模式不是简单模式时结果为 false
+func CoversPattern(general, specific string) bool {
+	if !IsSimplePattern(general) || !IsSimplePattern(specific) {
+		return false
+	}
+	generalRunes := []rune(collapseWildcards(general))
+	specificRunes := []rune(collapseWildcards(specific))
+
+	// covered[i][j] tells whether general[i:] covers specific[j:]
+	// covered[i][j] 表示 general[i:] 是否覆盖 specific[j:]
+	covered := make([][]bool, len(generalRunes)+1)
+	for i := range covered {
+		covered[i] = make([]bool, len(specificRunes)+1)
+	}
+	covered[len(generalRunes)][len(specificRunes)] = true
+
+	for i := len(generalRunes); i >= 0; i-- {
+		for j := len(specificRunes); j >= 0; j-- {
+			if i == len(generalRunes) {
+				continue
+			}
+			if generalRunes[i] == '*' {
+				// Wildcard consumes nothing, or one more symbol of the specific pattern
+				// 通配符不消费，或者消费具体模式中的一个符号
+				covered[i][j] = covered[i+1][j] || (j < len(specificRunes) && covered[i][j+1])
+				continue
+			}
+			covered[i][j] = j < len(specificRunes) &&
+				specificRunes[j] != '*' &&
+				specificRunes[j] == generalRunes[i] &&
+				covered[i+1][j+1]
+		}
+	}
+	return covered[0][0]
+}
+
+// collapseWildcards merges runs of "*" into a single "*" since they match the same strings
+// collapseWildcards 将连续的 "*" 合并为一个，因为它们匹配相同的字符串
+func collapseWildcards(pattern string) string {
+	for strings.Contains(pattern, "**") {
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+	}
+	return pattern
+}
+
+// CountPatternScore exposes the specificity score of the pattern without matching
+// Equals the score MatchRemotePattern returns when the pattern matches, brace patterns give their lowest alternative
+//
+// CountPatternScore 在不匹配的情况下给出模式的特异性分数
+// 等于模式匹配时 MatchRemotePattern 返回的分数，花括号模式给出其最低的备选分数
+func CountPatternScore(pattern string) int {
+	if IsSimplePattern(pattern) {
+		return countNonWildcardChars(pattern)
+	}
+	compiled, err := CompilePattern(pattern)
+	if err != nil {
+		return 0
+	}
+	return compiled.Score()
+}
+
+// IntersectPatterns reports whether some URL is matched by both wildcard patterns
+// Explores the product of both patterns as automata, each "*" consumes any character
+// Extended patterns are not analyzed, so the result is false when either pattern is not simple
+//
+// IntersectPatterns 判断是否存在同时被两个通配符模式匹配的 URL
+// 将两个模式视为自动机并探索其乘积，每个 "*" 可以消费任意字符
+// 扩展模式不做分析，因此任一模式不是简单模式时结果为 false
+func IntersectPatterns(patternA, patternB string) bool {
+	if !IsSimplePattern(patternA) || !IsSimplePattern(patternB) {
+		return false
+	}
+	runesA := []rune(collapseWildcards(patternA))
+	runesB := []rune(collapseWildcards(patternB))
+
+	type state struct{ a, b int }
+	visited := map[state]bool{}
+	queue := []state{{0, 0}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		if current.a == len(runesA) && current.b == len(runesB) {
+			return true
+		}
+		starA := current.a < len(runesA) && runesA[current.a] == '*'
+		starB := current.b < len(runesB) && runesB[current.b] == '*'
+
+		// Wildcards may match the empty sequence
+		// 通配符可以匹配空序列
+		if starA {
+			queue = append(queue, state{current.a + 1, current.b})
+		}
+		if starB {
+			queue = append(queue, state{current.a, current.b + 1})
+		}
+
+		// Consume one character on both sides
+		// 两边同时消费一个字符
+		switch {
+		case starA && current.b < len(runesB) && !starB:
+			queue = append(queue, state{current.a, current.b + 1})
+		case starB && current.a < len(runesA) && !starA:
+			queue = append(queue, state{current.a + 1, current.b})
+		case !starA && !starB && current.a < len(runesA) && current.b < len(runesB) && runesA[current.a] == runesB[current.b]:
+			queue = append(queue, state{current.a + 1, current.b + 1})
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePattern(t *testing.T) {
+	require.NoError(t, ValidatePattern("git@github.com:*"))
+	require.NoError(t, ValidatePattern("*"))
+
+	require.Error(t, ValidatePattern(""))
+	require.Error(t, ValidatePattern("git@github.com: *"))
+	require.Error(t, ValidatePattern("git@github.com:*\n"))
+}
+
+// TestCoversPattern validates language inclusion between wildcard patterns
+// Tests that general patterns cover specific ones and never the reverse
+//
+// TestCoversPattern 验证通配符模式之间的语言包含关系
+// 测试通用模式覆盖具体模式，而反之不成立
+func TestCoversPattern(t *testing.T) {
+	require.True(t, CoversPattern("*", "git@github.com:user/*"))
+	require.True(t, CoversPattern("git@github.com:*", "git@github.com:user/*"))
+	require.True(t, CoversPattern("git@*:*", "git@github.com:user/repo.git"))
+	require.True(t, CoversPattern("git@github.com:*", "git@github.com:*"))
+	require.True(t, CoversPattern("a*b", "a*x*b"))
+
+	require.False(t, CoversPattern("git@github.com:user/*", "git@github.com:*"))
+	require.False(t, CoversPattern("git@github.com:*", "https://github.com/*"))
+	require.False(t, CoversPattern("git@github.com:user/repo.git", "git@github.com:user/*"))
+	require.False(t, CoversPattern("*.git", "git@github.com:*"))
+
+	// Extended patterns are not analyzed
+	// 扩展模式不做分析
+	require.False(t, CoversPattern("a**b", "a*b"))
+	require.False(t, CoversPattern("*", "re:git@.*"))
+}
+
+func TestCountPatternScore(t *testing.T) {
+	require.Equal(t, 15, CountPatternScore("git@github.com:*"))
+	require.Equal(t, MatchRemotePattern("git@github.com:*", "git@github.com:user/repo.git"), CountPatternScore("git@github.com:*"))
+	require.Equal(t, 0, CountPatternScore("*"))
+}
+
+func TestIntersectPatterns(t *testing.T) {
+	require.True(t, IntersectPatterns("git@github.com:*", "*:user/*"))
+	require.True(t, IntersectPatterns("git@*.company.com:*", "git@gitlab.*:*"))
+	require.True(t, IntersectPatterns("*", "exact"))
+	require.True(t, IntersectPatterns("a*c", "*b*"))
+
+	require.False(t, IntersectPatterns("git@github.com:*", "https://*"))
+	require.False(t, IntersectPatterns("*.git", "*.zip"))
+	require.False(t, IntersectPatterns("exact", "other"))
+}
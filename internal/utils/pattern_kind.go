@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/yyle88/erero"
+)
+
+// RegexPrefix marks a remote pattern written as a regular expression
+// RegexPrefix 标记以正则表达式书写的远程模式
+const RegexPrefix = "re:"
+
+// IsSimplePattern reports whether the pattern uses nothing but "*" wildcards
+// Simple patterns keep the original matching: "*" matches any sequence, including "/"
+//
+// IsSimplePattern 判断模式是否只使用 "*" 通配符
+// 简单模式保持原有的匹配方式："*" 匹配任意序列，包括 "/"
+func IsSimplePattern(pattern string) bool {
+	return !strings.HasPrefix(pattern, RegexPrefix) &&
+		!strings.ContainsAny(pattern, "?[{}") &&
+		!strings.Contains(pattern, "**")
+}
+
+// CompiledPattern is a remote pattern prepared once to match many URLs
+// Supports simple "*" globs, extended globs and "re:" regular expressions
+//
+// CompiledPattern 是预先准备好以便匹配多个 URL 的远程模式
+// 支持简单的 "*" glob、扩展 glob 和 "re:" 正则表达式
+type CompiledPattern struct {
+	source       string              // Pattern as written // 书写的模式
+	simple       *starGlob           // Segments of simple patterns, nil on extended kinds // 简单模式的片段，扩展种类时为 nil
+	score        int                 // Static score, the lowest among alternatives // 静态分数，备选中取最低者
+	maxScore     int                 // Highest score any match can give // 任何匹配能给出的最高分数
+	alternatives []*patternAlternate // Brace expansions of extended globs, or the regex // 扩展 glob 的花括号展开结果，或正则表达式
+}
+
+// patternAlternate is one compiled expansion of a pattern with its score
+// patternAlternate 是模式的一个已编译展开及其分数
+type patternAlternate struct {
+	regex *regexp.Regexp // Anchored expression // 锚定的表达式
+	score int            // Score when this expansion matches // 该展开匹配时的分数
+}
+
+// CompilePattern parses the pattern and prepares the matcher
+// Pattern kinds:
+// - "re:<expr>": Go regular expression matching the whole URL, each literal character scores 1
+// - Extended glob with "?", "[...]", "{a,b}" or "**": "?" matches one character, "[...]" one character in the class,
+// "{a,b}" alternates and scores by the matched alternative, patterns containing "**" make "*" and "?" stop at "/"
+// - Simple glob with "*" alone: unchanged, "*" matches any sequence and each other character scores 1
+//
+// CompilePattern 解析模式并准备匹配器
+// 模式种类：
+// - "re:<expr>"：匹配整个 URL 的 Go 正则表达式，每个字面字符计 1 分
+// - 含 "?"、"[...]"、"{a,b}" 或 "**" 的扩展 glob："?" 匹配一个字符，"[...]" 匹配类中的一个字符，
+// "{a,b}" 表示备选并按匹配到的备选计分，包含 "**" 的模式中 "*" 和 "?" 不跨越 "/"
+// - 只含 "*" 的简单 glob：保持不变，"*" 匹配任意序列，其他每个字符计 1 分
+func CompilePattern(pattern string) (*CompiledPattern, error) {
+	compiled := &CompiledPattern{source: pattern}
+	switch {
+	case strings.HasPrefix(pattern, RegexPrefix):
+		expr := strings.TrimPrefix(pattern, RegexPrefix)
+		tree, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		regex, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err != nil {
+			return nil, erero.Wro(err)
+		}
+		compiled.score = countRegexLiterals(tree.Simplify())
+		compiled.alternatives = []*patternAlternate{{regex: regex, score: compiled.score}}
+	case IsSimplePattern(pattern):
+		compiled.simple = compileStarGlob(pattern)
+		compiled.score = countNonWildcardChars(pattern)
+	default:
+		expansions, err := expandBraces(pattern)
+		if err != nil {
+			return nil, err
+		}
+		pathAware := strings.Contains(pattern, "**")
+		for idx, expansion := range expansions {
+			expr, score, err := translateGlob(expansion, pathAware)
+			if err != nil {
+				return nil, err
+			}
+			regex, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, erero.Wro(err)
+			}
+			compiled.alternatives = append(compiled.alternatives, &patternAlternate{regex: regex, score: score})
+			if idx == 0 || score < compiled.score {
+				compiled.score = score
+			}
+			compiled.maxScore = max(compiled.maxScore, score)
+		}
+	}
+	compiled.maxScore = max(compiled.maxScore, compiled.score)
+	return compiled, nil
+}
+
+// Source returns the pattern as written
+// Source 返回书写的模式
+func (p *CompiledPattern) Source() string {
+	return p.source
+}
+
+// IsSimple reports whether the pattern uses "*" wildcards alone
+// IsSimple 判断模式是否只使用 "*" 通配符
+func (p *CompiledPattern) IsSimple() bool {
+	return p.simple != nil
+}
+
+// Score returns the static score, the lowest among brace alternatives
+// Score 返回静态分数，花括号备选中取最低者
+func (p *CompiledPattern) Score() int {
+	return p.score
+}
+
+// MaxScore returns the highest score Match can return, the highest among brace alternatives
+// MaxScore 返回 Match 可能返回的最高分数，花括号备选中取最高者
+func (p *CompiledPattern) MaxScore() int {
+	return p.maxScore
+}
+
+// Match returns the score on the remote URL, -1 when not matched
+// Brace alternatives score by the highest matching alternative
+//
+// Match 返回在远程 URL 上的分数，不匹配时返回 -1
+// 花括号备选按匹配到的最高分备选计分
+func (p *CompiledPattern) Match(remoteURL string) int {
+	if p.simple != nil {
+		if !p.simple.match(remoteURL) {
+			return -1
+		}
+		return p.score
+	}
+	best := -1
+	for _, alternative := range p.alternatives {
+		if alternative.score > best && alternative.regex.MatchString(remoteURL) {
+			best = alternative.score
+		}
+	}
+	return best
+}
+
+// expandBraces expands "{a,b}" alternations, nested braces included
+// expandBraces 展开 "{a,b}" 备选，包括嵌套的花括号
+func expandBraces(pattern string) ([]string, error) {
+	open := strings.IndexByte(pattern, '{')
+	if open < 0 {
+		if strings.IndexByte(pattern, '}') >= 0 {
+			return nil, erero.Errorf("unbalanced '}' in pattern %q", pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	// Find the matching close brace and the top-level commas
+	// 查找匹配的右花括号以及顶层逗号
+	depth, start := 0, open+1
+	var options []string
+	for idx := open; idx < len(pattern); idx++ {
+		switch pattern[idx] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				options = append(options, pattern[start:idx])
+				start = idx + 1
+			}
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			options = append(options, pattern[start:idx])
+			prefix := pattern[:open]
+			if strings.IndexByte(prefix, '}') >= 0 {
+				return nil, erero.Errorf("unbalanced '}' in pattern %q", pattern)
+			}
+			var expansions []string
+			for _, option := range options {
+				rest, err := expandBraces(option + pattern[idx+1:])
+				if err != nil {
+					return nil, err
+				}
+				for _, tail := range rest {
+					expansions = append(expansions, prefix+tail)
+				}
+			}
+			return expansions, nil
+		}
+	}
+	return nil, erero.Errorf("unbalanced '{' in pattern %q", pattern)
+}
+
+// translateGlob converts one brace-free glob into an anchored regular expression and its score
+// translateGlob 将一个不含花括号的 glob 转换为锚定的正则表达式及其分数
+func translateGlob(glob string, pathAware bool) (string, int, error) {
+	anyRun, anyOne := ".*", "."
+	if pathAware {
+		anyRun, anyOne = "[^/]*", "[^/]"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("^")
+	score := 0
+	runes := []rune(glob)
+	for idx := 0; idx < len(runes); idx++ {
+		switch char := runes[idx]; char {
+		case '*':
+			if idx+1 < len(runes) && runes[idx+1] == '*' {
+				for idx+1 < len(runes) && runes[idx+1] == '*' {
+					idx++
+				}
+				builder.WriteString(".*")
+			} else {
+				builder.WriteString(anyRun)
+			}
+		case '?':
+			builder.WriteString(anyOne)
+		case '[':
+			end := idx + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return "", 0, erero.Errorf("unclosed '[' in pattern %q", glob)
+			}
+			class := string(runes[idx+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			score++
+			idx = end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+			score++
+		}
+	}
+	builder.WriteString("$")
+	return builder.String(), score, nil
+}
+
+// countRegexLiterals counts the characters the expression requires literally
+// Alternations count their lowest branch, optional and repeated parts count their minimum
+//
+// countRegexLiterals 统计表达式中必须按字面出现的字符数量
+// 备选分支取最低者，可选和重复部分按最少次数计算
+func countRegexLiterals(tree *syntax.Regexp) int {
+	switch tree.Op {
+	case syntax.OpLiteral:
+		return len(tree.Rune)
+	case syntax.OpCharClass:
+		return 1
+	case syntax.OpCapture:
+		return countRegexLiterals(tree.Sub[0])
+	case syntax.OpConcat:
+		count := 0
+		for _, sub := range tree.Sub {
+			count += countRegexLiterals(sub)
+		}
+		return count
+	case syntax.OpAlternate:
+		lowest := -1
+		for _, sub := range tree.Sub {
+			if count := countRegexLiterals(sub); lowest < 0 || count < lowest {
+				lowest = count
+			}
+		}
+		return max(lowest, 0)
+	case syntax.OpPlus:
+		return countRegexLiterals(tree.Sub[0])
+	case syntax.OpRepeat:
+		return countRegexLiterals(tree.Sub[0]) * tree.Min
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchRemotePattern_Regex tests "re:" patterns and their literal scores
+// Validates that the expression must match the whole URL
+//
+// TestMatchRemotePattern_Regex 测试 "re:" 模式及其字面字符分数
+// 验证表达式必须匹配整个 URL
+func TestMatchRemotePattern_Regex(t *testing.T) {
+	pattern := `re:git@github\.com:(acme|acme-labs)/.+`
+	require.Equal(t, 20, MatchRemotePattern(pattern, "git@github.com:acme/repo.git"))
+	require.Equal(t, 20, MatchRemotePattern(pattern, "git@github.com:acme-labs/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern(pattern, "git@github.com:other/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern(`re:github\.com`, "git@github.com:acme/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern(`re:(`, "git@github.com:acme/repo.git"))
+}
+
+func TestMatchRemotePattern_QuestionAndClass(t *testing.T) {
+	require.Equal(t, 20, MatchRemotePattern("git@github.com:team?/*", "git@github.com:team1/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("git@github.com:team?/*", "git@github.com:team/repo.git"))
+	require.Equal(t, 21, MatchRemotePattern("git@github.com:team[0-9]/*", "git@github.com:team7/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("git@github.com:team[!0-9]/*", "git@github.com:team7/repo.git"))
+	require.Equal(t, 21, MatchRemotePattern("git@github.com:team[!0-9]/*", "git@github.com:teamx/repo.git"))
+}
+
+func TestMatchRemotePattern_Braces(t *testing.T) {
+	pattern := "git@{github,gitlab}.corp.com:*"
+	require.Equal(t, 20, MatchRemotePattern(pattern, "git@github.corp.com:team/repo.git"))
+	require.Equal(t, 20, MatchRemotePattern(pattern, "git@gitlab.corp.com:team/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern(pattern, "git@bitbucket.corp.com:team/repo.git"))
+
+	// Scores follow the matched alternative
+	// 分数取决于匹配到的备选
+	require.Equal(t, 15, MatchRemotePattern("git@{gh,github}.com:*", "git@github.com:team/repo.git"))
+	require.Equal(t, 11, MatchRemotePattern("git@{gh,github}.com:*", "git@gh.com:team/repo.git"))
+	require.Equal(t, 11, CountPatternScore("git@{gh,github}.com:*"))
+
+	// Nested braces expand as well
+	// 嵌套的花括号同样会展开
+	require.Equal(t, 19, MatchRemotePattern("https://{git{hub,lab}.com,bitbucket.org}/*", "https://gitlab.com/team/repo"))
+}
+
+func TestMatchRemotePattern_DoubleStar(t *testing.T) {
+	// With "**" in the pattern, "*" stops at "/"
+	// 模式中含有 "**" 时，"*" 在 "/" 处停止
+	require.Equal(t, 20, MatchRemotePattern("https://github.com/*/**", "https://github.com/acme/team/repo"))
+	require.Equal(t, -1, MatchRemotePattern("https://github.com/**/*/x", "https://github.com/acme/repo"))
+	require.Equal(t, 28, MatchRemotePattern("https://github.com/acme/*.git**", "https://github.com/acme/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("https://github.com/acme/*.git**", "https://github.com/acme/team/repo.git"))
+
+	// Without "**" the simple "*" keeps crossing "/"
+	// 没有 "**" 时，简单的 "*" 仍然跨越 "/"
+	require.Equal(t, 28, MatchRemotePattern("https://github.com/acme/*.git", "https://github.com/acme/team/repo.git"))
+
+	// Without "**" extended globs keep "*" and "?" crossing "/", adding "?", "[...]" or braces keeps what "*" means
+	// 没有 "**" 时扩展 glob 中的 "*" 和 "?" 仍然跨越 "/"，添加 "?"、"[...]" 或花括号不会改变 "*" 的含义
+	require.Equal(t, 20, MatchRemotePattern("git@github.com:org-?/*", "git@github.com:org-a/team/repo.git"))
+	require.Equal(t, 21, MatchRemotePattern("git@github.com:team[0-9]/*", "git@github.com:team7/sub/repo.git"))
+	require.Equal(t, 2, MatchRemotePattern("{a,b}/*", "a/team/repo"))
+	require.Equal(t, 19, MatchRemotePattern("git@github.com:team?", "git@github.com:team/"))
+
+	// With "**" each of them stops at "/"
+	// 含有 "**" 时它们都在 "/" 处停止
+	require.Equal(t, -1, MatchRemotePattern("git@github.com:org-?/*.git**", "git@github.com:org-a/team/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("git@github.com:team[0-9]/*.git**", "git@github.com:team7/sub/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("{a,b}/*.git**", "a/team/repo.git"))
+	require.Equal(t, 6, MatchRemotePattern("{a,b}/*.git**", "a/repo.git"))
+	require.Equal(t, -1, MatchRemotePattern("git@github.com:team?**", "git@github.com:team/"))
+}
+
+func TestCompilePattern(t *testing.T) {
+	compiled, err := CompilePattern("git@github.com:*")
+	require.NoError(t, err)
+	require.True(t, compiled.IsSimple())
+	require.Equal(t, 15, compiled.Score())
+	require.Equal(t, 15, compiled.Match("git@github.com:user/repo.git"))
+
+	for _, pattern := range []string{"git@{github,gitlab.com:*", "git@github}.com:*", "git@github.com:[abc", "re:[a-"} {
+		_, err := CompilePattern(pattern)
+		require.Error(t, err, pattern)
+		require.Error(t, ValidatePattern(pattern), pattern)
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RemoteURL is a Git remote URL split into host, owner and repo
+// Owner keeps nested groups such as "company/team-alpha"
+//
+// RemoteURL 是拆分为主机、所有者和仓库的 Git 远程 URL
+// 所有者保留嵌套分组，例如 "company/team-alpha"
+type RemoteURL struct {
+	Host  string // Lowercase host without user and port // 不含用户和端口的小写主机名
+	Owner string // Path segments before the repo // 仓库之前的路径段
+	Repo  string // Last path segment without ".git" // 去掉 ".git" 的最后一个路径段
+}
+
+// ParseRemoteURL normalizes the remote URL into host/owner/repo form
+// Supports scp-like "git@host:owner/repo.git" and URLs such as "ssh://", "https://" and "git://"
+// Returns false on local paths and URLs without owner and repo
+//
+// ParseRemoteURL 将远程 URL 规范化为 host/owner/repo 形式
+// 支持类 scp 的 "git@host:owner/repo.git" 以及 "ssh://"、"https://"、"git://" 等 URL
+// 对本地路径以及缺少所有者和仓库的 URL 返回 false
+func ParseRemoteURL(remoteURL string) (*RemoteURL, bool) {
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		parsed, err := url.Parse(remoteURL)
+		if err != nil || parsed.Scheme == "file" {
+			return nil, false
+		}
+		host, path = parsed.Hostname(), parsed.Path
+	} else {
+		// The scp-like syntax needs a colon before the first slash
+		// 类 scp 语法要求冒号出现在第一个斜杠之前
+		colon := strings.Index(remoteURL, ":")
+		if colon <= 0 || strings.Contains(remoteURL[:colon], "/") {
+			return nil, false
+		}
+		host, path = remoteURL[:colon], remoteURL[colon+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	slash := strings.LastIndex(path, "/")
+	if host == "" || slash <= 0 || slash == len(path)-1 {
+		return nil, false
+	}
+	return &RemoteURL{
+		Host:  strings.ToLower(host),
+		Owner: strings.Trim(path[:slash], "/"),
+		Repo:  path[slash+1:],
+	}, true
+}
+
+// Canonical returns the "host/owner/repo" form, the same across protocols
+// Canonical 返回 "host/owner/repo" 形式，在各种协议间保持一致
+func (r *RemoteURL) Canonical() string {
+	return r.Host + "/" + r.Owner + "/" + r.Repo
+}
+
+// RewriteRemoteURL applies Git "url.<base>.insteadOf" rules to the remote URL
+// Rules map each insteadOf prefix to its base, the longest matching prefix wins as in Git
+//
+// RewriteRemoteURL 将 Git 的 "url.<base>.insteadOf" 规则应用到远程 URL
+// 规则将每个 insteadOf 前缀映射到其 base，与 Git 一致由最长匹配前缀胜出
+func RewriteRemoteURL(remoteURL string, insteadOfRules map[string]string) string {
+	var longest string
+	for prefix := range insteadOfRules {
+		if prefix != "" && strings.HasPrefix(remoteURL, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return remoteURL
+	}
+	return insteadOfRules[longest] + remoteURL[len(longest):]
+}
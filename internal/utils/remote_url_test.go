@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRemoteURL_SameRepo tests that each protocol normalizes to the same form
+// Validates scp-like, ssh, https with user and git URLs of one repo
+//
+// TestParseRemoteURL_SameRepo 测试各种协议规范化为相同形式
+// 验证同一仓库的类 scp、ssh、带用户的 https 和 git URL
+func TestParseRemoteURL_SameRepo(t *testing.T) {
+	for _, remoteURL := range []string{
+		"git@github.com:org/repo.git",
+		"ssh://git@github.com/org/repo",
+		"ssh://git@github.com:22/org/repo.git",
+		"https://github.com/org/repo",
+		"https://user@github.com/org/repo.git",
+		"https://GitHub.com/org/repo/",
+		"git://github.com/org/repo.git",
+	} {
+		remote, ok := ParseRemoteURL(remoteURL)
+		require.True(t, ok, remoteURL)
+		require.Equal(t, "github.com/org/repo", remote.Canonical(), remoteURL)
+	}
+}
+
+func TestParseRemoteURL_NestedOwner(t *testing.T) {
+	remote, ok := ParseRemoteURL("git@gitlab.com:company/team-alpha/backend.git")
+	require.True(t, ok)
+	require.Equal(t, "gitlab.com", remote.Host)
+	require.Equal(t, "company/team-alpha", remote.Owner)
+	require.Equal(t, "backend", remote.Repo)
+}
+
+func TestParseRemoteURL_Unsupported(t *testing.T) {
+	for _, remoteURL := range []string{
+		"/srv/git/repo.git",
+		"./repo",
+		"file:///srv/git/org/repo.git",
+		"https://github.com/repo",
+		"git@github.com:repo.git",
+		"",
+	} {
+		_, ok := ParseRemoteURL(remoteURL)
+		require.False(t, ok, remoteURL)
+	}
+}
+
+func TestRewriteRemoteURL(t *testing.T) {
+	rules := map[string]string{
+		"gh:":                      "git@github.com:",
+		"https://github.com/":      "git@github.com:",
+		"https://github.com/work/": "git@github-work:work/",
+	}
+	require.Equal(t, "git@github.com:org/repo.git", RewriteRemoteURL("gh:org/repo.git", rules))
+	require.Equal(t, "git@github.com:org/repo.git", RewriteRemoteURL("https://github.com/org/repo.git", rules))
+	require.Equal(t, "git@github-work:work/repo.git", RewriteRemoteURL("https://github.com/work/repo.git", rules))
+	require.Equal(t, "git@gitlab.com:org/repo.git", RewriteRemoteURL("git@gitlab.com:org/repo.git", rules))
+}
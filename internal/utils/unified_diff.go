@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffLine is one line of a line diff, kind is ' ', '-' or '+'
+// diffLine 是逐行差异中的一行，kind 为 ' '、'-' 或 '+'
+type diffLine struct {
+	kind byte   // Line kind // 行类型
+	text string // Line text with its line ending // 包含行尾的行文本
+}
+
+// UnifiedDiff returns the unified diff of the two texts with the context lines, blank when equal
+// The names show in the "---" and "+++" headers, like "a/main.go" and "b/main.go"
+//
+// UnifiedDiff 返回两个文本带上下文行的统一差异格式，相同时返回空
+// 名称显示在 "---" 和 "+++" 标题中，例如 "a/main.go" 和 "b/main.go"
+func UnifiedDiff(oldName string, newName string, before string, after string, context int) string {
+	if before == after {
+		return ""
+	}
+	lines := splitDiffLines(before, after)
+
+	// Line numbers before each diff line, in the old and the new text
+	// 每个差异行之前在旧文本和新文本中的行号
+	oldLines := make([]int, len(lines)+1)
+	newLines := make([]int, len(lines)+1)
+	for idx, line := range lines {
+		oldLines[idx+1], newLines[idx+1] = oldLines[idx], newLines[idx]
+		if line.kind != '+' {
+			oldLines[idx+1]++
+		}
+		if line.kind != '-' {
+			newLines[idx+1]++
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+	for idx := 0; idx < len(lines); idx++ {
+		if lines[idx].kind == ' ' {
+			continue
+		}
+		// Changes at most twice the context apart share one hunk, as their contexts touch
+		// 间隔不超过两倍上下文的更改共用一个块，因为它们的上下文相接
+		end := idx + 1
+		for next := idx + 1; next < len(lines) && next-end <= 2*context; next++ {
+			if lines[next].kind != ' ' {
+				end = next + 1
+			}
+		}
+		start, stop := max(idx-context, 0), min(end+context, len(lines))
+
+		oldCount, newCount := oldLines[stop]-oldLines[start], newLines[stop]-newLines[start]
+		result.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldLines[start], oldCount), hunkRange(newLines[start], newCount)))
+		for _, line := range lines[start:stop] {
+			result.WriteByte(line.kind)
+			result.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				result.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		idx = end - 1
+	}
+	return result.String()
+}
+
+// hunkRange formats the "start,count" of a hunk header as git does
+// A single line omits the count, an empty range starts at the line before it
+//
+// hunkRange 按 git 的方式格式化块标题中的 "start,count"
+// 单行省略数量，空范围从其前一行开始
+func hunkRange(before int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// splitDiffLines diffs the two texts line by line and splits the result into single lines
+// splitDiffLines 逐行比较两个文本并将结果拆分为单独的行
+func splitDiffLines(before string, after string) []*diffLine {
+	dmp := diffmatchpatch.New()
+	chars1, chars2, lineArray := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(chars1, chars2, false), lineArray)
+
+	var lines []*diffLine
+	for _, diff := range diffs {
+		kind := byte(' ')
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			kind = '-'
+		case diffmatchpatch.DiffInsert:
+			kind = '+'
+		}
+		for _, text := range strings.SplitAfter(diff.Text, "\n") {
+			if text != "" {
+				lines = append(lines, &diffLine{kind: kind, text: text})
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestUnifiedDiff tests hunks with context lines in git's format
+// Validates nearby changes sharing one hunk and distant changes in separate hunks
+//
+// TestUnifiedDiff 测试 git 格式的带上下文行的块
+// 验证相近的更改共用一个块，相距较远的更改位于不同的块
+func TestUnifiedDiff(t *testing.T) {
+	before := "package demo\n\nfunc A()  int {\n\treturn 1\n}\n"
+	after := "package demo\n\nfunc A() int {\n\treturn 1\n}\n"
+	require.Equal(t, "--- a/demo.go\n+++ b/demo.go\n@@ -2,3 +2,3 @@\n \n-func A()  int {\n+func A() int {\n \treturn 1\n", UnifiedDiff("a/demo.go", "b/demo.go", before, after, 1))
+	require.Empty(t, UnifiedDiff("a", "b", before, before, 3))
+
+	var lines []string
+	for idx := 0; idx < 20; idx++ {
+		lines = append(lines, fmt.Sprintf("line%d", idx+1))
+	}
+	before = strings.Join(lines, "\n") + "\n"
+	lines[1], lines[17] = "first", "second"
+	after = strings.Join(lines, "\n") + "\n"
+	diff := UnifiedDiff("a/x", "b/x", before, after, 3)
+	require.Equal(t, 2, strings.Count(diff, "@@ -"))
+	require.Contains(t, diff, "@@ -1,5 +1,5 @@\n line1\n-line2\n+first\n line3\n")
+	require.Contains(t, diff, "@@ -15,6 +15,6 @@\n")
+
+	// Changes exactly twice the context apart share one hunk, one line more splits them
+	// 恰好相隔两倍上下文的更改共用一个块，再多一行则拆分它们
+	lines[1], lines[17] = "line2", "line18"
+	lines[2], lines[9] = "first", "second"
+	after = strings.Join(lines, "\n") + "\n"
+	diff = UnifiedDiff("a/x", "b/x", before, after, 3)
+	require.Equal(t, 1, strings.Count(diff, "@@ -"))
+	require.Contains(t, diff, "@@ -1,13 +1,13 @@\n")
+
+	lines[9], lines[10] = "line10", "second"
+	after = strings.Join(lines, "\n") + "\n"
+	diff = UnifiedDiff("a/x", "b/x", before, after, 3)
+	require.Equal(t, 2, strings.Count(diff, "@@ -"))
+}
+
+func TestUnifiedDiff_Edges(t *testing.T) {
+	require.Equal(t, "--- a/x\n+++ b/x\n@@ -0,0 +1 @@\n+new\n", UnifiedDiff("a/x", "b/x", "", "new\n", 3))
+	require.Equal(t, "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+old\n", UnifiedDiff("a/x", "b/x", "old", "old\n", 3))
+}
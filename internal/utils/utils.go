@@ -21,6 +21,7 @@ import "strings"
 // - "git@github.com:*" matches "git@github.com:user/repo.git" - domain-specific
 // - "*://*.com/*" matches "https://example.com/path" - agnostic to protocol type
 // - "git@*.company.com:team/*" - subdomain and path-specific matching
+// - "re:", "?", "[...]", "{a,b}" and "**" select the extended kinds described in CompilePattern
 //
 // Intelligent Scoring Algorithm:
 // - Score equals count of non-wildcard characters in pattern (used in specificity ranking)
@@ -38,6 +39,7 @@ import "strings"
 // - "git@github.com:*" 匹配 "git@github.com:user/repo.git" - 域名特定
 // - "*://*.com/*" 匹配 "https://example.com/path" - 协议类型无关
 // - "git@*.company.com:team/*" - 子域名和路径特定匹配
+// - "re:"、"?"、"[...]"、"{a,b}" 和 "**" 选择 CompilePattern 中描述的扩展种类
 //
 // 智能评分算法：
 // - 分数等于模式中非通配符字符的数量（用于特异性排名）
@@ -45,6 +47,16 @@ import "strings"
 // - 更具体的模式获得更高的优先分数
 // - 在多模式配置中实现最佳匹配选择
 func MatchRemotePattern(pattern, remoteURL string) int {
+	// Extended kinds compile into regular expressions
+	// 扩展种类编译为正则表达式
+	if !IsSimplePattern(pattern) {
+		compiled, err := CompilePattern(pattern)
+		if err != nil {
+			return -1
+		}
+		return compiled.Match(remoteURL)
+	}
+
 	// Use glob matching on patterns
 	// 对模式使用 glob 匹配
 	if !matchGlob(remoteURL, pattern) {
@@ -59,11 +71,11 @@ func MatchRemotePattern(pattern, remoteURL string) int {
 }
 
 // matchGlob performs sophisticated glob pattern matching with advanced wildcard support
-// Implements high-performance recursive matching algorithm against complex URL patterns
+// Compiles the pattern into literal segments and matches them in linear time
 // Returns true if remoteURL matches the specified wildcard pattern
 //
 // matchGlob 执行复杂的 glob 模式匹配，支持高级通配符
-// 针对复杂 URL 模式实现高性能递归匹配算法
+// 将模式编译为字面片段并以线性时间进行匹配
 // 如果 remoteURL 匹配指定的通配符模式则返回 true
 func matchGlob(remoteURL, pattern string) bool {
 	// Quick exact match check to improve performance
@@ -71,66 +83,59 @@ func matchGlob(remoteURL, pattern string) bool {
 	if remoteURL == pattern {
 		return true
 	}
-	// No wildcard in pattern, must be exact match
-	// 模式中没有通配符，必须精确匹配
-	if !strings.Contains(pattern, "*") {
-		return false
-	}
-	return matchGlobRecursive([]rune(remoteURL), []rune(pattern))
+	return compileStarGlob(pattern).match(remoteURL)
 }
 
-// matchGlobRecursive matches remoteURL against pattern through recursion
-// Uses slice recursion to consume characters from both strings
-//
-// Algorithm:
-// 1. When pattern is at end, check if remoteURL is at end as well
-// 2. When pattern starts with *, attempt matching 0 to N characters
-// 3. When remoteURL is at end but pattern has non-wildcard, no match occurs
-// 4. Otherwise, match single unit and recurse
-//
-// matchGlobRecursive 通过递归将 remoteURL 与模式匹配
-// 使用切片递归从两个字符串中消费字符
-//
-// 算法：
-// 1. 当模式到达末尾时，检查 remoteURL 是否也到达末尾
-// 2. 当模式以 * 开头时，尝试匹配 0 到 N 个字符
-// 3. 当 remoteURL 到达末尾但模式有非通配符时，则不匹配
-// 4. 否则，匹配单个单元并递归
-func matchGlobRecursive(remoteURL, pattern []rune) bool {
-	// End of pattern
-	// 模式结束
-	if len(pattern) == 0 {
-		return len(remoteURL) == 0
-	}
+// starGlob is a "*" glob split into the literal segments between wildcards
+// starGlob 是按通配符拆分为字面片段的 "*" glob
+type starGlob struct {
+	prefix  string   // Literal before the first "*" // 第一个 "*" 之前的字面内容
+	middles []string // Non-blank literals between "*" wildcards // "*" 之间的非空字面内容
+	suffix  string   // Literal after the last "*" // 最后一个 "*" 之后的字面内容
+	hasStar bool     // Whether the pattern has "*" at all // 模式中是否存在 "*"
+}
 
-	// When facing wildcard in pattern
-	// 当前字符是通配符
-	if pattern[0] == '*' {
-		// Attempt matching 0+ characters
-		// 尝试匹配 0 个或多个字符
-		for i := 0; i <= len(remoteURL); i++ {
-			if matchGlobRecursive(remoteURL[i:], pattern[1:]) {
-				return true
-			}
+// compileStarGlob splits the pattern on "*" once so that matching needs no backtracking
+// compileStarGlob 一次性按 "*" 拆分模式，使匹配无需回溯
+func compileStarGlob(pattern string) *starGlob {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return &starGlob{prefix: pattern}
+	}
+	glob := &starGlob{prefix: parts[0], suffix: parts[len(parts)-1], hasStar: true}
+	for _, part := range parts[1 : len(parts)-1] {
+		if part != "" {
+			glob.middles = append(glob.middles, part)
 		}
-		return false
 	}
+	return glob
+}
 
-	// remoteURL ends but pattern continues (non-wildcard)
-	// remoteURL 结束但模式继续（非通配符）
-	if len(remoteURL) == 0 {
+// match checks the anchored prefix and suffix, then finds each middle segment at its leftmost position
+// Taking the leftmost position is always safe since "*" can absorb whatever it skips,
+// so each segment is searched once and the time stays linear in the URL length
+//
+// match 检查锚定的前缀和后缀，然后在最左位置查找每个中间片段
+// 选择最左位置总是安全的，因为 "*" 可以吸收被跳过的内容，
+// 所以每个片段只搜索一次，耗时与 URL 长度保持线性关系
+func (g *starGlob) match(remoteURL string) bool {
+	if !g.hasStar {
+		return remoteURL == g.prefix
+	}
+	if len(remoteURL) < len(g.prefix)+len(g.suffix) ||
+		!strings.HasPrefix(remoteURL, g.prefix) ||
+		!strings.HasSuffix(remoteURL, g.suffix) {
 		return false
 	}
-
-	// Match single unit
-	// 匹配单个单元
-	if remoteURL[0] == pattern[0] {
-		return matchGlobRecursive(remoteURL[1:], pattern[1:])
+	rest := remoteURL[len(g.prefix) : len(remoteURL)-len(g.suffix)]
+	for _, middle := range g.middles {
+		idx := strings.Index(rest, middle)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(middle):]
 	}
-
-	// No match
-	// 无匹配
-	return false
+	return true
 }
 
 // countNonWildcardChars performs precise counting in patterns excluding wildcards